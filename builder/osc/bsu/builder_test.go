@@ -1,9 +1,15 @@
 package bsu
 
 import (
+	"context"
+	"reflect"
+	"strings"
 	"testing"
 
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
 )
 
 func testConfig() map[string]interface{} {
@@ -18,6 +24,29 @@ func testConfig() map[string]interface{} {
 	}
 }
 
+// testServerConfig returns the config of a build of the source OMI against the
+// fake OAPI server, with the given settings. A nil setting is removed.
+func testServerConfig(server *oapitest.Server, sourceOmi string, settings map[string]interface{}) map[string]interface{} {
+	config := map[string]interface{}{
+		"access_key":           "AK",
+		"secret_key":           "SK",
+		"region":               "eu-west-2",
+		"custom_endpoint_oapi": server.URL,
+		"source_omi":           sourceOmi,
+		"vm_type":              "tinav4.c1r1p2",
+		"communicator":         "none",
+		"omi_name":             "packer-test",
+	}
+	for key, value := range settings {
+		if value == nil {
+			delete(config, key)
+			continue
+		}
+		config[key] = value
+	}
+	return config
+}
+
 func TestBuilder_ImplementsBuilder(t *testing.T) {
 	var raw interface{}
 	raw = &Builder{}
@@ -79,6 +108,27 @@ func TestBuilderPrepare_OMIName(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_Force(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["packer_force"] = true
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.OMINameConflict != osccommon.OMINameConflictDeregister {
+		t.Fatalf("-force should deregister, got %s", b.config.OMINameConflict)
+	}
+
+	b = Builder{}
+	config["omi_name_conflict"] = "suffix"
+	if _, _, err := b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.OMINameConflict != osccommon.OMINameConflictSuffix || b.config.OMIForceDeregister {
+		t.Fatalf("-force should keep the omi_name_conflict policy, got %s", b.config.OMINameConflict)
+	}
+}
+
 func TestBuilderPrepare_InvalidKey(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -129,3 +179,354 @@ func TestBuilderPrepare_InvalidShutdownBehavior(t *testing.T) {
 		t.Fatal("should have error")
 	}
 }
+
+func TestBuilderPrepare_SpotPrice(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["spot_price"] = "0.05"
+	_, warnings, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("should warn that spot VMs are not available: %#v", warnings)
+	}
+}
+
+func TestBuilderPrepare_BlockDurationMinutes(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["block_duration_minutes"] = 60
+	_, warnings, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("should warn that block_duration_minutes is ignored: %#v", warnings)
+	}
+}
+
+func TestBuilder_Run(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	var b Builder
+	_, _, err := b.Prepare(testServerConfig(server, source.ImageId, map[string]interface{}{
+		"omi_regions":   []string{"us-east-2"},
+		"snapshot_tags": map[string]string{"Name": "packer-test"},
+	}))
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	artifact, err := b.Run(context.Background(), packersdk.TestUi(t), &packersdk.MockHook{})
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	omis := artifact.(*osccommon.Artifact).Omis
+	if len(omis) != 2 || server.Region(omis["us-east-2"]) != "us-east-2" {
+		t.Fatalf("bad omis: %#v", omis)
+	}
+
+	for _, vm := range server.Vms() {
+		if vm.State != "terminated" {
+			t.Fatalf("the source VM should be terminated, got %s", vm.State)
+		}
+	}
+	if volumes := server.Volumes(); len(volumes) != 0 {
+		t.Fatalf("the volumes should be deleted, got %#v", volumes)
+	}
+	if sgs := server.SecurityGroups(); len(sgs) != 0 {
+		t.Fatalf("the temporary security group should be deleted, got %#v", sgs)
+	}
+	if keypairs := server.Keypairs(); len(keypairs) != 0 {
+		t.Fatalf("the temporary keypair should be deleted, got %#v", keypairs)
+	}
+	for _, snapshot := range server.Snapshots() {
+		if server.Region(snapshot.SnapshotId) == "us-east-2" && len(snapshot.Tags) != 1 {
+			t.Fatalf("the copied snapshots should be tagged, got %#v", snapshot)
+		}
+	}
+}
+
+func TestBuilder_RunTemporaryNet(t *testing.T) {
+	for _, private := range []bool{false, true} {
+		server := oapitest.NewServer()
+		defer server.Close()
+		server.Transitions = 0
+		source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+		var b Builder
+		_, _, err := b.Prepare(testServerConfig(server, source.ImageId, map[string]interface{}{
+			"temporary_net": map[string]interface{}{
+				"private": private,
+				"tags":    map[string]string{"Name": "packer-{{ .BuildRegion }}"},
+			},
+		}))
+		if err != nil {
+			t.Fatalf("should not have error: %s", err)
+		}
+
+		if _, err := b.Run(context.Background(), packersdk.TestUi(t), &packersdk.MockHook{}); err != nil {
+			t.Fatalf("should not have error: %s", err)
+		}
+
+		if nets := server.Nets(); len(nets) != 0 {
+			t.Fatalf("the temporary Net should be deleted, got %#v", nets)
+		}
+		if subnets := server.Subnets(); len(subnets) != 0 {
+			t.Fatalf("the temporary Subnets should be deleted, got %#v", subnets)
+		}
+		if services := server.InternetServices(); len(services) != 0 {
+			t.Fatalf("the temporary internet service should be deleted, got %#v", services)
+		}
+		if tables := server.RouteTables(); len(tables) != 0 {
+			t.Fatalf("the temporary route tables should be deleted, got %#v", tables)
+		}
+		if ips := server.PublicIps(); len(ips) != 0 {
+			t.Fatalf("the public IPs should be deleted, got %#v", ips)
+		}
+
+		nats := server.NatServices()
+		if private != (len(nats) == 1) {
+			t.Fatalf("bad NAT services: %#v", nats)
+		}
+		for _, nat := range nats {
+			if nat.State != "deleted" {
+				t.Fatalf("the temporary NAT service should be deleted, got %s", nat.State)
+			}
+			if len(nat.Tags) != 1 || nat.Tags[0].Value != "packer-eu-west-2" {
+				t.Fatalf("the temporary NAT service should be tagged, got %#v", nat.Tags)
+			}
+		}
+	}
+}
+
+func TestBuilder_RunNics(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+	n := server.AddNet("eu-west-2", "10.0.0.0/16")
+	subnet := server.AddSubnet("eu-west-2", n.NetId, "10.0.0.0/24", "eu-west-2a")
+	other := server.AddSubnet("eu-west-2", n.NetId, "10.0.1.0/24", "eu-west-2a")
+
+	config := func(privateIp string) map[string]interface{} {
+		return testServerConfig(server, source.ImageId, map[string]interface{}{
+			"omi_name":                    "packer-test-" + privateIp,
+			"subnet_id":                   subnet.SubnetId,
+			"private_ip":                  privateIp,
+			"secondary_private_ips":       []string{"10.0.0.11"},
+			"associate_public_ip_address": true,
+			"nics": []map[string]interface{}{{
+				"subnet_id":                  other.SubnetId,
+				"private_ip":                 "10.0.1.20",
+				"secondary_private_ip_count": 1,
+			}},
+		})
+	}
+
+	var b Builder
+	if _, _, err := b.Prepare(config("10.0.1.10")); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if _, err := b.Run(context.Background(), packersdk.TestUi(t), &packersdk.MockHook{}); err == nil {
+		t.Fatal("should fail when the private IP is outside of the Subnet")
+	}
+	if calls := server.Calls("CreateVms"); calls != 0 {
+		t.Fatalf("no VM should be launched, got %d calls", calls)
+	}
+
+	b = Builder{}
+	if _, _, err := b.Prepare(config("10.0.0.10")); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if _, err := b.Run(context.Background(), packersdk.TestUi(t), &packersdk.MockHook{}); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	vms := server.Vms()
+	if len(vms) != 1 || vms[0].PrivateIp != "10.0.0.10" || len(vms[0].Nics) != 2 {
+		t.Fatalf("bad VM: %#v", vms)
+	}
+	var ips [][]string
+	for _, nic := range vms[0].Nics {
+		var nicIps []string
+		for _, ip := range nic.PrivateIps {
+			nicIps = append(nicIps, ip.PrivateIp)
+		}
+		ips = append(ips, nicIps)
+	}
+	if !reflect.DeepEqual(ips, [][]string{{"10.0.0.10", "10.0.0.11"}, {"10.0.1.20", "10.0.1.4"}}) {
+		t.Fatalf("bad private IPs: %v", ips)
+	}
+	if vms[0].Nics[1].SubnetId != other.SubnetId || !vms[0].Nics[1].LinkNic.DeleteOnVmDeletion {
+		t.Fatalf("bad NIC: %#v", vms[0].Nics[1])
+	}
+	// The fake server, like OAPI, rejects the VmId of a VM with several NICs.
+	if calls := server.Calls("LinkPublicIp"); calls != 1 {
+		t.Fatalf("the public IP should be linked to the first NIC, got %d calls", calls)
+	}
+}
+
+func TestBuilder_RunGeneratedData(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{
+		ImageName: "source",
+		Tags:      []osc.ResourceTag{{Key: "os", Value: "ubuntu"}},
+	})
+
+	var b Builder
+	generatedKeys, _, err := b.Prepare(testServerConfig(server, source.ImageId, nil))
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !reflect.DeepEqual(generatedKeys, osccommon.GetGeneratedDataList()) {
+		t.Fatalf("bad generated data keys: %v", generatedKeys)
+	}
+
+	hook := &packersdk.MockHook{}
+	artifact, err := b.Run(context.Background(), packersdk.TestUi(t), hook)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	vm := server.Vms()[0]
+	data := artifact.(*osccommon.Artifact).StateData["generated_data"].(map[string]interface{})
+	expected := map[string]interface{}{
+		"BuildRegion":   "eu-west-2",
+		"SourceOMI":     source.ImageId,
+		"SourceOMIName": "source",
+		"SourceOMITags": map[string]string{"os": "ubuntu"},
+		"VmId":          vm.VmId,
+		"VmType":        "tinav4.c1r1p2",
+		"SubregionName": vm.Placement.SubregionName,
+	}
+	for key, value := range expected {
+		if !reflect.DeepEqual(data[key], value) {
+			t.Fatalf("bad generated data %s: %#v", key, data[key])
+		}
+	}
+	if id, _ := data["RootVolumeId"].(string); !strings.HasPrefix(id, "vol-") {
+		t.Fatalf("bad generated data RootVolumeId: %#v", data["RootVolumeId"])
+	}
+	if hookData := hook.RunData.(map[string]interface{}); hookData["VmId"] != vm.VmId {
+		t.Fatalf("the provisioners should get the generated data, got %#v", hookData)
+	}
+}
+
+func TestBuilder_RunFallback(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+	n := server.AddNet("eu-west-2", "10.0.0.0/16")
+	subnetA := server.AddSubnet("eu-west-2", n.NetId, "10.0.0.0/24", "eu-west-2a")
+	subnetB := server.AddSubnet("eu-west-2", n.NetId, "10.0.1.0/24", "eu-west-2b")
+	server.RemoveCapacity("tinav5.c4r8p1", "")
+	server.RemoveCapacity("", "eu-west-2a")
+
+	run := func(name string, placement map[string]interface{}) map[string]interface{} {
+		config := testServerConfig(server, source.ImageId, map[string]interface{}{
+			"vm_type":  nil,
+			"vm_types": []string{"tinav5.c4r8p1", "tinav5.c2r4p2"},
+			"omi_name": name,
+		})
+		for key, value := range placement {
+			config[key] = value
+		}
+
+		var b Builder
+		if _, _, err := b.Prepare(config); err != nil {
+			t.Fatalf("should not have error: %s", err)
+		}
+		artifact, err := b.Run(context.Background(), packersdk.TestUi(t), &packersdk.MockHook{})
+		if err != nil {
+			t.Fatalf("should not have error: %s", err)
+		}
+		return artifact.(*osccommon.Artifact).StateData["generated_data"].(map[string]interface{})
+	}
+
+	// Both VM types are tried in the first Subregion, then in the second.
+	data := run("packer-subregions", map[string]interface{}{
+		"subregion_names": []string{"eu-west-2a", "eu-west-2b"},
+	})
+	if data["VmType"] != "tinav5.c2r4p2" || data["SubregionName"] != "eu-west-2b" {
+		t.Fatalf("should launch the second VM type in the second Subregion, got %v in %v", data["VmType"], data["SubregionName"])
+	}
+	if calls := server.Calls("CreateVms"); calls != 4 {
+		t.Fatalf("should try every combination, got %d calls", calls)
+	}
+
+	data = run("packer-subnets", map[string]interface{}{
+		"subnet_ids": []string{subnetA.SubnetId, subnetB.SubnetId},
+	})
+	if data["SubnetId"] != subnetB.SubnetId || data["SubregionName"] != "eu-west-2b" {
+		t.Fatalf("should launch the VM in the second Subnet, got %v in %v", data["SubnetId"], data["SubregionName"])
+	}
+}
+
+func TestBuilder_RunQuotaExceeded(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+	server.Fail("CreateVms", 400, "TooManyResources (QuotaExceded)")
+
+	var b Builder
+	_, _, err := b.Prepare(testServerConfig(server, source.ImageId, map[string]interface{}{
+		"vm_type":         nil,
+		"vm_types":        []string{"tinav5.c4r8p1", "tinav5.c2r4p2"},
+		"subregion_names": []string{"eu-west-2a", "eu-west-2b"},
+	}))
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if _, err := b.Run(context.Background(), packersdk.TestUi(t), &packersdk.MockHook{}); err == nil {
+		t.Fatal("should fail when the quota is exceeded")
+	}
+	if calls := server.Calls("CreateVms"); calls != 1 {
+		t.Fatalf("a quota error should not be retried on the other candidates, got %d calls", calls)
+	}
+}
+
+func TestBuilder_RunNameConflictSuffix(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+	server.AddImage("eu-west-2", osc.Image{ImageName: "packer-test"})
+	server.AddImage("us-east-2", osc.Image{ImageName: "packer-test-1"})
+
+	var b Builder
+	_, _, err := b.Prepare(testServerConfig(server, source.ImageId, map[string]interface{}{
+		"omi_name_conflict": "suffix",
+		"omi_regions":       []string{"us-east-2"},
+	}))
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	artifact, err := b.Run(context.Background(), packersdk.TestUi(t), &packersdk.MockHook{})
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	omis := artifact.(*osccommon.Artifact).Omis
+	if len(omis) != 2 {
+		t.Fatalf("the OMI should be copied, got %v", omis)
+	}
+	for region, id := range omis {
+		for _, image := range server.Images() {
+			if image.ImageId == id && image.ImageName != "packer-test-2" {
+				t.Fatalf("the OMI in %s should be suffixed, got %s", region, image.ImageName)
+			}
+		}
+	}
+}
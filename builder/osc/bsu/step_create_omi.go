@@ -13,8 +13,9 @@ import (
 )
 
 type stepCreateOMI struct {
-	image     *osc.Image
-	RawRegion string
+	image         *osc.Image
+	RawRegion     string
+	PollingConfig *osccommon.PollingConfig
 }
 
 func (s *stepCreateOMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -24,7 +25,7 @@ func (s *stepCreateOMI) Run(ctx context.Context, state multistep.StateBag) multi
 	ui := state.Get("ui").(packersdk.Ui)
 
 	// Create the image
-	omiName := config.OMIName
+	omiName := osccommon.OMIName(state, config.OMIName)
 
 	ui.Say(fmt.Sprintf("Creating OMI %s from vm %s", omiName, vm.VmId))
 	createOpts := osc.CreateImageRequest{
@@ -40,7 +41,7 @@ func (s *stepCreateOMI) Run(ctx context.Context, state multistep.StateBag) multi
 		CreateImageRequest: optional.NewInterface(createOpts),
 	})
 	if err != nil || resp.Image.ImageId == "" {
-		err := fmt.Errorf("Error creating OMI: %s", err)
+		err := fmt.Errorf("Error creating OMI: %s", osccommon.DecodeError(err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
@@ -56,7 +57,7 @@ func (s *stepCreateOMI) Run(ctx context.Context, state multistep.StateBag) multi
 
 	// Wait for the image to become ready
 	ui.Say("Waiting for OMI to become ready...")
-	if err := osccommon.WaitUntilOscImageAvailable(oscconn, image.ImageId); err != nil {
+	if err := s.PollingConfig.WaitUntilOscImageAvailable(ctx, oscconn, image.ImageId); err != nil {
 		log.Printf("Error waiting for OMI: %s", err)
 		imagesResp, _, err := oscconn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
 			ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
@@ -86,7 +87,7 @@ func (s *stepCreateOMI) Run(ctx context.Context, state multistep.StateBag) multi
 		}),
 	})
 	if err != nil {
-		err := fmt.Errorf("Error searching for OMI: %s", err)
+		err := fmt.Errorf("Error searching for OMI: %s", osccommon.DecodeError(err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
@@ -123,7 +124,7 @@ func (s *stepCreateOMI) Cleanup(state multistep.StateBag) {
 	if _, _, err := oscconn.ImageApi.DeleteImage(context.Background(), &osc.DeleteImageOpts{
 		DeleteImageRequest: optional.NewInterface(DeleteOpts),
 	}); err != nil {
-		ui.Error(fmt.Sprintf("Error Deleting OMI, may still be around: %s", err))
+		ui.Error(fmt.Sprintf("Error Deleting OMI, may still be around: %s", osccommon.DecodeError(err)))
 		return
 	}
 }
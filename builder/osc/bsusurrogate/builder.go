@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/outscale/osc-sdk-go/osc"
@@ -30,8 +31,9 @@ type Config struct {
 	osccommon.BlockDevices `mapstructure:",squash"`
 	osccommon.OMIConfig    `mapstructure:",squash"`
 
-	RootDevice    RootBlockDevice  `mapstructure:"omi_root_device"`
-	VolumeRunTags osccommon.TagMap `mapstructure:"run_volume_tags"`
+	RootDevice    RootBlockDevice         `mapstructure:"omi_root_device"`
+	VolumeRunTags osccommon.TagMap        `mapstructure:"run_volume_tags"`
+	PollingConfig osccommon.PollingConfig `mapstructure:"osc_polling"`
 
 	ctx interpolate.Context
 }
@@ -54,11 +56,14 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"omi_description",
+				"omi_retention",
 				"run_tags",
 				"run_volume_tags",
 				"snapshot_tags",
 				"spot_tags",
 				"tags",
+				"temporary_net",
+				"temporary_security_group_tags",
 			},
 		},
 	}, raws...)
@@ -66,13 +71,15 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 		return nil, nil, err
 	}
 
-	if b.config.PackerConfig.PackerForce {
+	// -force deregisters the existing OMIs, unless another policy is set.
+	if b.config.PackerConfig.PackerForce && b.config.OMINameConflict == "" {
 		b.config.OMIForceDeregister = true
 	}
 
 	// Accumulate any errors
 	var errs *packersdk.MultiError
 	errs = packersdk.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(&b.config.ctx)...)
+	errs = packersdk.MultiErrorAppend(errs, b.config.PollingConfig.Prepare()...)
 	errs = packersdk.MultiErrorAppend(errs, b.config.RunConfig.Prepare(&b.config.ctx)...)
 	errs = packersdk.MultiErrorAppend(errs,
 		b.config.OMIConfig.Prepare(&b.config.AccessConfig, &b.config.ctx)...)
@@ -93,12 +100,17 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("no volume with name '%s' is found", b.config.RootDevice.SourceDeviceName))
 	}
 
+	var warns []string
+	if b.config.HasSpotOptions() {
+		warns = append(warns, osccommon.SpotVmWarning)
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
-		return nil, nil, errs
+		return nil, warns, errs
 	}
 
 	packersdk.LogSecretFilter.Set(b.config.AccessKey, b.config.SecretKey, b.config.Token)
-	return nil, nil, nil
+	return osccommon.GetGeneratedDataList(), warns, nil
 
 }
 
@@ -117,6 +129,7 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	state.Put("accessConfig", &b.config.AccessConfig)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
+	generatedData := &packerbuilderdata.GeneratedData{State: state}
 
 	//VMStep
 
@@ -125,13 +138,34 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 
 	steps := []multistep.Step{
 		&osccommon.StepPreValidate{
-			DestOmiName:     b.config.OMIName,
-			ForceDeregister: b.config.OMIForceDeregister,
+			DestOmiName:              b.config.OMIName,
+			NameConflict:             b.config.OMINameConflict,
+			VmType:                   b.config.VmType,
+			VmTypes:                  b.config.VmTypes,
+			SubregionName:            b.config.Subregion,
+			SubregionNames:           b.config.SubregionNames,
+			SourceOmi:                b.config.SourceOmi,
+			SourceOmiFilter:          b.config.SourceOmiFilter,
+			ExpectedRootDevice:       osccommon.RunSourceVmBSUExpectedRootDevice,
+			BlockDevices:             b.config.BlockDevices,
+			SubnetId:                 b.config.SubnetId,
+			SubnetIds:                b.config.SubnetIds,
+			PrivateIp:                b.config.PrivateIp,
+			SecondaryPrivateIps:      b.config.SecondaryPrivateIps,
+			SecondaryPrivateIpCount:  b.config.SecondaryPrivateIpCount,
+			Nics:                     b.config.Nics,
+			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
+			PublicIpId:               b.config.PublicIpId,
+			PublicIpFilter:           b.config.PublicIpFilter,
 		},
 		&osccommon.StepSourceOMIInfo{
 			SourceOmi:  b.config.SourceOmi,
 			OmiFilters: b.config.SourceOmiFilter,
 		},
+		&osccommon.StepSetGeneratedData{
+			GeneratedData: generatedData,
+			RawRegion:     b.config.RawRegion,
+		},
 		&osccommon.StepNetworkInfo{
 			NetId:               b.config.NetId,
 			NetFilter:           b.config.NetFilter,
@@ -140,6 +174,11 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 			SubnetId:            b.config.SubnetId,
 			SubnetFilter:        b.config.SubnetFilter,
 			SubregionName:       b.config.Subregion,
+			TemporaryNet:        b.config.TemporaryNet,
+			RequiredIps:         1 + len(b.config.SecondaryPrivateIps) + b.config.SecondaryPrivateIpCount,
+			PollingConfig:       &b.config.PollingConfig,
+			Ctx:                 b.config.ctx,
+			RawRegion:           b.config.RawRegion,
 		},
 		&osccommon.StepKeyPair{
 			Debug:        b.config.PackerDebug,
@@ -148,13 +187,22 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		},
 		&osccommon.StepPublicIp{
 			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
+			PublicIpId:               b.config.PublicIpId,
+			PublicIpFilter:           b.config.PublicIpFilter,
 			Debug:                    b.config.PackerDebug,
 		},
 		&osccommon.StepSecurityGroup{
-			SecurityGroupFilter:   b.config.SecurityGroupFilter,
-			SecurityGroupIds:      b.config.SecurityGroupIds,
-			CommConfig:            &b.config.RunConfig.Comm,
-			TemporarySGSourceCidr: b.config.TemporarySGSourceCidr,
+			SecurityGroupFilter:       b.config.SecurityGroupFilter,
+			SecurityGroupIds:          b.config.SecurityGroupIds,
+			CommConfig:                &b.config.RunConfig.Comm,
+			TemporarySGSourceCidrs:    b.config.TemporarySGSourceCidrs,
+			TemporarySGSourcePublicIp: b.config.TemporarySGSourcePublicIp,
+			PublicIpURL:               b.config.TemporarySGSourcePublicIpURL,
+			TemporarySGRules:          b.config.TemporarySGRules,
+			TemporarySGOutboundRules:  b.config.TemporarySGOutboundRules,
+			Tags:                      b.config.TemporarySGTags,
+			Ctx:                       b.config.ctx,
+			RawRegion:                 b.config.RawRegion,
 		},
 		&osccommon.StepCleanupVolumes{
 			BlockDevices: b.config.BlockDevices,
@@ -176,6 +224,15 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 			UserData:                    b.config.UserData,
 			UserDataFile:                b.config.UserDataFile,
 			VolumeTags:                  b.config.VolumeRunTags,
+			PollingConfig:               &b.config.PollingConfig,
+			PrivateIp:                   b.config.PrivateIp,
+			SecondaryPrivateIps:         b.config.SecondaryPrivateIps,
+			SecondaryPrivateIpCount:     b.config.SecondaryPrivateIpCount,
+			Nics:                        b.config.Nics,
+			VmTypes:                     b.config.VmTypes,
+			SubregionNames:              b.config.SubregionNames,
+			SubnetIds:                   b.config.SubnetIds,
+			GeneratedData:               generatedData,
 		},
 		&osccommon.StepGetPassword{
 			Debug:     b.config.PackerDebug,
@@ -197,13 +254,16 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		&osccommon.StepStopBSUBackedVm{
 			Skip:          false,
 			DisableStopVm: b.config.DisableStopVm,
+			PollingConfig: &b.config.PollingConfig,
 		},
 		&StepSnapshotVolumes{
 			LaunchDevices: launchOSCDevices,
+			PollingConfig: &b.config.PollingConfig,
 		},
 		&osccommon.StepDeregisterOMI{
 			AccessConfig:        &b.config.AccessConfig,
-			ForceDeregister:     b.config.OMIForceDeregister,
+			PollingConfig:       &b.config.PollingConfig,
+			NameConflict:        b.config.OMINameConflict,
 			ForceDeleteSnapshot: b.config.OMIForceDeleteSnapshot,
 			OMIName:             b.config.OMIName,
 			Regions:             b.config.OMIRegions,
@@ -213,10 +273,20 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 			OMIDevices:    omiDevices,
 			LaunchDevices: launchOSCDevices,
 			RawRegion:     b.config.RawRegion,
+			PollingConfig: &b.config.PollingConfig,
+		},
+		&osccommon.StepCopyOMI{
+			AccessConfig:  &b.config.AccessConfig,
+			Regions:       b.config.OMIRegions,
+			Name:          b.config.OMIName,
+			PollingConfig: &b.config.PollingConfig,
 		},
 		&osccommon.StepUpdateOMIAttributes{
 			AccountIds:         b.config.OMIAccountIDs,
 			SnapshotAccountIds: b.config.SnapshotAccountIDs,
+			Groups:             b.config.OMIGroups,
+			SnapshotGroups:     b.config.SnapshotGroups,
+			ProductCodes:       b.config.OMIProductCodes,
 			GlobalPermission:   b.config.GlobalPermission,
 			Ctx:                b.config.ctx,
 		},
@@ -225,6 +295,11 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 			SnapshotTags: b.config.SnapshotTags,
 			Ctx:          b.config.ctx,
 		},
+		&osccommon.StepOMIRetention{
+			AccessConfig: &b.config.AccessConfig,
+			Retention:    b.config.OMIRetention,
+			Ctx:          b.config.ctx,
+		},
 	}
 
 	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
@@ -238,8 +313,12 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	//Build the artifact
 	if omis, ok := state.GetOk("omis"); ok {
 		// Build the artifact and return it
+		snapshots, _ := state.Get("snapshots").(map[string][]string)
 		artifact := &osccommon.Artifact{
 			Omis:           omis.(map[string]string),
+			Snapshots:      snapshots,
+			Endpoint:       b.config.CustomEndpointOAPI,
+			AccessConfig:   &b.config.AccessConfig,
 			BuilderIdValue: BuilderId,
 			StateData:      map[string]interface{}{"generated_data": state.Get("generated_data")},
 		}
@@ -6,12 +6,13 @@ import (
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/acctest"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
 )
 
 func TestAccBuilder_basic(t *testing.T) {
 	testCase := &acctest.PluginTestCase{
 		Name:     "bsusurrogate_basic_test",
-		Template: testBuilderAccBasic,
+		Template: oapitest.UseCassette(t, "bsusurrogate_basic_test", testBuilderAccBasic),
 		Check: func(buildCommand *exec.Cmd, logfile string) error {
 			if buildCommand.ProcessState != nil {
 				if buildCommand.ProcessState.ExitCode() != 0 {
@@ -26,7 +27,7 @@ func TestAccBuilder_basic(t *testing.T) {
 func TestAccBuilder_VmTerminate(t *testing.T) {
 	testCase := &acctest.PluginTestCase{
 		Name:     "bsusurrogate_vm_terminate_test",
-		Template: testBuilderAccVmTerminate,
+		Template: oapitest.UseCassette(t, "bsusurrogate_vm_terminate_test", testBuilderAccVmTerminate),
 		Check: func(buildCommand *exec.Cmd, logfile string) error {
 			if buildCommand.ProcessState != nil {
 				if buildCommand.ProcessState.ExitCode() != 0 {
@@ -1,9 +1,12 @@
 package bsusurrogate
 
 import (
+	"context"
 	"testing"
 
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
 )
 
 func testConfig() map[string]interface{} {
@@ -79,3 +82,65 @@ func TestBuilder_ShutdownBehavior_BsuDeletion(t *testing.T) {
 	}
 
 }
+
+func TestBuilder_Run(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	var b Builder
+	_, _, err := b.Prepare(map[string]interface{}{
+		"access_key":           "AK",
+		"secret_key":           "SK",
+		"region":               "eu-west-2",
+		"custom_endpoint_oapi": server.URL,
+		"source_omi":           source.ImageId,
+		"vm_type":              "tinav4.c1r1p2",
+		"communicator":         "none",
+		"omi_name":             "packer-test",
+		"omi_root_device": map[string]interface{}{
+			"device_name":           "/dev/sda1",
+			"source_device_name":    "/dev/xvdf",
+			"delete_on_vm_deletion": true,
+			"volume_size":           10,
+		},
+		"launch_block_device_mappings": []map[string]interface{}{{
+			"device_name":           "/dev/xvdf",
+			"delete_on_vm_deletion": true,
+			"volume_size":           10,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	artifact, err := b.Run(context.Background(), packersdk.TestUi(t), &packersdk.MockHook{})
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if artifact == nil {
+		t.Fatal("should have an artifact")
+	}
+
+	var registered *osc.Image
+	for _, image := range server.Images() {
+		if image.ImageName == "packer-test" {
+			registered = &image
+		}
+	}
+	if registered == nil || registered.RootDeviceName != "/dev/sda1" || len(registered.BlockDeviceMappings) != 1 {
+		t.Fatalf("bad registered OMI: %#v", registered)
+	}
+	for _, vm := range server.Vms() {
+		if vm.State != "terminated" {
+			t.Fatalf("the source VM should be terminated, got %s", vm.State)
+		}
+	}
+	if volumes := server.Volumes(); len(volumes) != 0 {
+		t.Fatalf("the volumes should be deleted, got %#v", volumes)
+	}
+	if sgs := server.SecurityGroups(); len(sgs) != 0 {
+		t.Fatalf("the temporary security group should be deleted, got %#v", sgs)
+	}
+}
@@ -18,6 +18,7 @@ type StepRegisterOMI struct {
 	LaunchDevices []osc.BlockDeviceMappingVmCreation
 	image         *osc.Image
 	RawRegion     string
+	PollingConfig *osccommon.PollingConfig
 }
 
 func (s *StepRegisterOMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -31,7 +32,7 @@ func (s *StepRegisterOMI) Run(ctx context.Context, state multistep.StateBag) mul
 	blockDevices := s.combineDevices(snapshotIds)
 
 	registerOpts := osc.CreateImageRequest{
-		ImageName:           config.OMIName,
+		ImageName:           osccommon.OMIName(state, config.OMIName),
 		Architecture:        "x86_64",
 		RootDeviceName:      s.RootDevice.DeviceName,
 		BlockDeviceMappings: blockDevices,
@@ -44,7 +45,7 @@ func (s *StepRegisterOMI) Run(ctx context.Context, state multistep.StateBag) mul
 		CreateImageRequest: optional.NewInterface(registerOpts),
 	})
 	if err != nil {
-		state.Put("error", fmt.Errorf("Error registering OMI: %s", err))
+		state.Put("error", fmt.Errorf("Error registering OMI: %s", osccommon.DecodeError(err)))
 		ui.Error(state.Get("error").(error).Error())
 		return multistep.ActionHalt
 	}
@@ -57,7 +58,7 @@ func (s *StepRegisterOMI) Run(ctx context.Context, state multistep.StateBag) mul
 
 	// Wait for the image to become ready
 	ui.Say("Waiting for OMI to become ready...")
-	if err := osccommon.WaitUntilOscImageAvailable(oscconn, registerResp.Image.ImageId); err != nil {
+	if err := s.PollingConfig.WaitUntilOscImageAvailable(ctx, oscconn, registerResp.Image.ImageId); err != nil {
 		err := fmt.Errorf("Error waiting for OMI: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -73,7 +74,7 @@ func (s *StepRegisterOMI) Run(ctx context.Context, state multistep.StateBag) mul
 	})
 
 	if err != nil {
-		err := fmt.Errorf("Error searching for OMI: %s", err)
+		err := fmt.Errorf("Error searching for OMI: %s", osccommon.DecodeError(err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
@@ -112,7 +113,7 @@ func (s *StepRegisterOMI) Cleanup(state multistep.StateBag) {
 	})
 
 	if err != nil {
-		ui.Error(fmt.Sprintf("Error deregistering OMI, may still be around: %s", err))
+		ui.Error(fmt.Sprintf("Error deregistering OMI, may still be around: %s", osccommon.DecodeError(err)))
 		return
 	}
 }
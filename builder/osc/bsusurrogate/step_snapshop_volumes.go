@@ -21,6 +21,7 @@ import (
 //	snapshot_ids map[string]string - IDs of the created snapshots
 type StepSnapshotVolumes struct {
 	LaunchDevices []osc.BlockDeviceMappingVmCreation
+	PollingConfig *osccommon.PollingConfig
 	snapshotIds   map[string]string
 }
 
@@ -56,7 +57,7 @@ func (s *StepSnapshotVolumes) snapshotVolume(ctx context.Context, deviceName str
 	s.snapshotIds[deviceName] = createSnapResp.Snapshot.SnapshotId
 
 	// Wait for snapshot to be created
-	err = osccommon.WaitUntilOscSnapshotCompleted(oscconn, createSnapResp.Snapshot.SnapshotId)
+	err = s.PollingConfig.WaitUntilOscSnapshotCompleted(ctx, oscconn, createSnapResp.Snapshot.SnapshotId)
 	return err
 }
 
@@ -106,7 +107,7 @@ func (s *StepSnapshotVolumes) Cleanup(state multistep.StateBag) {
 				DeleteSnapshotRequest: optional.NewInterface(osc.DeleteSnapshotRequest{SnapshotId: snapshotID}),
 			})
 			if err != nil {
-				ui.Error(fmt.Sprintf("Error: %s", err))
+				ui.Error(fmt.Sprintf("Error: %s", osccommon.DecodeError(err)))
 			}
 		}
 	}
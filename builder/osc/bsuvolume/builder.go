@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/outscale/osc-sdk-go/osc"
@@ -28,7 +29,8 @@ type Config struct {
 	osccommon.AccessConfig `mapstructure:",squash"`
 	osccommon.RunConfig    `mapstructure:",squash"`
 
-	VolumeMappings []BlockDevice `mapstructure:"bsu_volumes"`
+	VolumeMappings []BlockDevice           `mapstructure:"bsu_volumes"`
+	PollingConfig  osccommon.PollingConfig `mapstructure:"osc_polling"`
 
 	launchBlockDevices osccommon.BlockDevices
 	ctx                interpolate.Context
@@ -66,6 +68,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 	// Accumulate any errors
 	var errs *packersdk.MultiError
 	errs = packersdk.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(&b.config.ctx)...)
+	errs = packersdk.MultiErrorAppend(errs, b.config.PollingConfig.Prepare()...)
 	errs = packersdk.MultiErrorAppend(errs, b.config.RunConfig.Prepare(&b.config.ctx)...)
 	errs = packersdk.MultiErrorAppend(errs, b.config.launchBlockDevices.Prepare(&b.config.ctx)...)
 
@@ -80,12 +83,17 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 		errs = packersdk.MultiErrorAppend(errs, err)
 	}
 
+	var warns []string
+	if b.config.HasSpotOptions() {
+		warns = append(warns, osccommon.SpotVmWarning)
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
-		return nil, nil, errs
+		return nil, warns, errs
 	}
 
 	packersdk.LogSecretFilter.Set(b.config.AccessKey, b.config.SecretKey, b.config.Token)
-	return nil, nil, nil
+	return osccommon.GetGeneratedDataList(), warns, nil
 }
 
 func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
@@ -111,6 +119,7 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	state.Put("osc", oscConn)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
+	generatedData := &packerbuilderdata.GeneratedData{State: state}
 
 	log.Printf("[DEBUG] launch block devices %#v", b.config.launchBlockDevices)
 
@@ -129,14 +138,46 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		Tags:                        b.config.RunTags,
 		UserData:                    b.config.UserData,
 		UserDataFile:                b.config.UserDataFile,
+		PollingConfig:               &b.config.PollingConfig,
+		PrivateIp:                   b.config.PrivateIp,
+		SecondaryPrivateIps:         b.config.SecondaryPrivateIps,
+		SecondaryPrivateIpCount:     b.config.SecondaryPrivateIpCount,
+		Nics:                        b.config.Nics,
+		VmTypes:                     b.config.VmTypes,
+		SubregionNames:              b.config.SubregionNames,
+		SubnetIds:                   b.config.SubnetIds,
+		GeneratedData:               generatedData,
 	}
 
 	// Build the steps
 	steps := []multistep.Step{
+		&osccommon.StepPreValidate{
+			VmType:                   b.config.VmType,
+			VmTypes:                  b.config.VmTypes,
+			SubregionName:            b.config.Subregion,
+			SubregionNames:           b.config.SubregionNames,
+			SourceOmi:                b.config.SourceOmi,
+			SourceOmiFilter:          b.config.SourceOmiFilter,
+			ExpectedRootDevice:       "bsu",
+			BlockDevices:             b.config.launchBlockDevices,
+			SubnetId:                 b.config.SubnetId,
+			SubnetIds:                b.config.SubnetIds,
+			PrivateIp:                b.config.PrivateIp,
+			SecondaryPrivateIps:      b.config.SecondaryPrivateIps,
+			SecondaryPrivateIpCount:  b.config.SecondaryPrivateIpCount,
+			Nics:                     b.config.Nics,
+			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
+			PublicIpId:               b.config.PublicIpId,
+			PublicIpFilter:           b.config.PublicIpFilter,
+		},
 		&osccommon.StepSourceOMIInfo{
 			SourceOmi:  b.config.SourceOmi,
 			OmiFilters: b.config.SourceOmiFilter,
 		},
+		&osccommon.StepSetGeneratedData{
+			GeneratedData: generatedData,
+			RawRegion:     b.config.RawRegion,
+		},
 		&osccommon.StepNetworkInfo{
 			NetId:               b.config.NetId,
 			NetFilter:           b.config.NetFilter,
@@ -145,6 +186,11 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 			SubnetId:            b.config.SubnetId,
 			SubnetFilter:        b.config.SubnetFilter,
 			SubregionName:       b.config.Subregion,
+			TemporaryNet:        b.config.TemporaryNet,
+			RequiredIps:         1 + len(b.config.SecondaryPrivateIps) + b.config.SecondaryPrivateIpCount,
+			PollingConfig:       &b.config.PollingConfig,
+			Ctx:                 b.config.ctx,
+			RawRegion:           b.config.RawRegion,
 		},
 		&osccommon.StepKeyPair{
 			Debug:        b.config.PackerDebug,
@@ -153,17 +199,27 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		},
 		&osccommon.StepPublicIp{
 			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
+			PublicIpId:               b.config.PublicIpId,
+			PublicIpFilter:           b.config.PublicIpFilter,
 			Debug:                    b.config.PackerDebug,
 		},
 		&osccommon.StepSecurityGroup{
-			SecurityGroupFilter:   b.config.SecurityGroupFilter,
-			SecurityGroupIds:      b.config.SecurityGroupIds,
-			CommConfig:            &b.config.RunConfig.Comm,
-			TemporarySGSourceCidr: b.config.TemporarySGSourceCidr,
+			SecurityGroupFilter:       b.config.SecurityGroupFilter,
+			SecurityGroupIds:          b.config.SecurityGroupIds,
+			CommConfig:                &b.config.RunConfig.Comm,
+			TemporarySGSourceCidrs:    b.config.TemporarySGSourceCidrs,
+			TemporarySGSourcePublicIp: b.config.TemporarySGSourcePublicIp,
+			PublicIpURL:               b.config.TemporarySGSourcePublicIpURL,
+			TemporarySGRules:          b.config.TemporarySGRules,
+			TemporarySGOutboundRules:  b.config.TemporarySGOutboundRules,
+			Tags:                      b.config.TemporarySGTags,
+			Ctx:                       b.config.ctx,
+			RawRegion:                 b.config.RawRegion,
 		},
 		instanceStep,
 		&stepTagBSUVolumes{
 			VolumeMapping: b.config.VolumeMappings,
+			RawRegion:     b.config.RawRegion,
 			Ctx:           b.config.ctx,
 		},
 		&osccommon.StepGetPassword{
@@ -184,8 +240,8 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 			Comm: &b.config.RunConfig.Comm,
 		},
 		&osccommon.StepStopBSUBackedVm{
-			Skip:          b.config.IsSpotVm(),
 			DisableStopVm: b.config.DisableStopVm,
+			PollingConfig: &b.config.PollingConfig,
 		},
 	}
 
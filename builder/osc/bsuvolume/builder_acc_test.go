@@ -7,12 +7,13 @@ import (
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/acctest"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
 )
 
 func TestAccBuilder_basic(t *testing.T) {
 	testCase := &acctest.PluginTestCase{
 		Name:     "bsuvolume_basic_test",
-		Template: testBuilderAccBasic,
+		Template: oapitest.UseCassette(t, "bsuvolume_basic_test", testBuilderAccBasic),
 		Check: func(buildCommand *exec.Cmd, logfile string) error {
 			if buildCommand.ProcessState != nil {
 				if buildCommand.ProcessState.ExitCode() != 0 {
@@ -1,9 +1,12 @@
 package bsuvolume
 
 import (
+	"context"
 	"testing"
 
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
 )
 
 func testConfig() map[string]interface{} {
@@ -90,3 +93,49 @@ func TestBuilderPrepare_InvalidShutdownBehavior(t *testing.T) {
 		t.Fatal("should have error")
 	}
 }
+
+func TestBuilder_Run(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	var b Builder
+	_, _, err := b.Prepare(map[string]interface{}{
+		"access_key":           "AK",
+		"secret_key":           "SK",
+		"region":               "eu-west-2",
+		"custom_endpoint_oapi": server.URL,
+		"source_omi":           source.ImageId,
+		"vm_type":              "tinav4.c1r1p2",
+		"communicator":         "none",
+		"bsu_volumes": []map[string]interface{}{{
+			"device_name":           "/dev/xvdf",
+			"delete_on_vm_deletion": false,
+			"volume_size":           5,
+			"tags":                  map[string]string{"Name": "packer-test"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	artifact, err := b.Run(context.Background(), packersdk.TestUi(t), &packersdk.MockHook{})
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if volumes := artifact.(*Artifact).Volumes["eu-west-2"]; len(volumes) != 1 {
+		t.Fatalf("bad artifact volumes: %#v", artifact.(*Artifact).Volumes)
+	}
+
+	// Only the volume to keep outlives the source VM, unlinked and tagged.
+	volumes := server.Volumes()
+	if len(volumes) != 1 || volumes[0].State != "available" || volumes[0].Size != 5 || len(volumes[0].Tags) != 1 {
+		t.Fatalf("bad remaining volumes: %#v", volumes)
+	}
+	for _, vm := range server.Vms() {
+		if vm.State != "terminated" {
+			t.Fatalf("the source VM should be terminated, got %s", vm.State)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
 )
 
 type stepTagBSUVolumes struct {
@@ -68,7 +69,7 @@ func (s *stepTagBSUVolumes) Run(_ context.Context, state multistep.StateBag) mul
 				}),
 			})
 			if err != nil {
-				err := fmt.Errorf("Error tagging BSU Volume %s on %s: %s", volumeId, vm.VmId, err)
+				err := fmt.Errorf("Error tagging BSU Volume %s on %s: %s", volumeId, vm.VmId, osccommon.DecodeError(err))
 				state.Put("error", err)
 				ui.Error(err.Error())
 				return multistep.ActionHalt
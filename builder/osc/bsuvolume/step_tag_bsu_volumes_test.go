@@ -0,0 +1,51 @@
+package bsuvolume
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antihax/optional"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+func TestStepTagBSUVolumes_region(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	conn := (&osccommon.AccessConfig{AccessKey: "AK", SecretKey: "SK", RawRegion: "eu-west-2", CustomEndpointOAPI: server.URL}).NewOSCClientByRegion("eu-west-2")
+	resp, _, err := conn.VolumeApi.CreateVolume(context.Background(), &osc.CreateVolumeOpts{
+		CreateVolumeRequest: optional.NewInterface(osc.CreateVolumeRequest{SubregionName: "eu-west-2a", Size: 5}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	step := &stepTagBSUVolumes{
+		VolumeMapping: []BlockDevice{{
+			BlockDevice: osccommon.BlockDevice{DeviceName: "/dev/xvdf"},
+			Tags:        osccommon.TagMap{"Region": "{{ .BuildRegion }}"},
+		}},
+		RawRegion: "eu-west-2",
+	}
+	state := new(multistep.BasicStateBag)
+	state.Put("osc", conn)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("vm", osc.Vm{VmId: "i-00000001", BlockDeviceMappings: []osc.BlockDeviceMappingCreated{{
+		DeviceName: "/dev/xvdf",
+		Bsu:        osc.BsuCreated{VolumeId: resp.Volume.VolumeId},
+	}}})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+	// The volumes are listed and their tags interpolated in the build region.
+	if volumes := state.Get("bsuvolumes").(BsuVolumes); len(volumes["eu-west-2"]) != 1 {
+		t.Fatalf("the volume should be listed in eu-west-2, got %#v", volumes)
+	}
+	if tags := server.Volumes()[0].Tags; len(tags) != 1 || tags[0].Value != "eu-west-2" {
+		t.Fatalf("the volume should be tagged with the build region, got %#v", tags)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/outscale/osc-sdk-go/osc"
@@ -50,6 +51,7 @@ type Config struct {
 	SourceOMI         string                     `mapstructure:"source_omi"`
 	SourceOMIFilter   osccommon.OmiFilterOptions `mapstructure:"source_omi_filter"`
 	RootVolumeTags    osccommon.TagMap           `mapstructure:"root_volume_tags"`
+	PollingConfig     osccommon.PollingConfig    `mapstructure:"osc_polling"`
 
 	ctx interpolate.Context
 }
@@ -74,6 +76,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"omi_description",
+				"omi_retention",
 				"snapshot_tags",
 				"tags",
 				"root_volume_tags",
@@ -88,7 +91,8 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 		return nil, nil, err
 	}
 
-	if b.config.PackerConfig.PackerForce {
+	// -force deregisters the existing OMIs, unless another policy is set.
+	if b.config.PackerConfig.PackerForce && b.config.OMINameConflict == "" {
 		b.config.OMIForceDeregister = true
 	}
 
@@ -132,6 +136,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 	var warns []string
 
 	errs = packersdk.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(&b.config.ctx)...)
+	errs = packersdk.MultiErrorAppend(errs, b.config.PollingConfig.Prepare()...)
 	errs = packersdk.MultiErrorAppend(errs,
 		b.config.OMIConfig.Prepare(&b.config.AccessConfig, &b.config.ctx)...)
 
@@ -181,7 +186,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 	}
 
 	packersdk.LogSecretFilter.Set(b.config.AccessKey, b.config.SecretKey, b.config.Token)
-	return nil, warns, nil
+	return osccommon.GetSourceOMIGeneratedDataList(), warns, nil
 }
 
 func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
@@ -207,12 +212,13 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 	state.Put("wrappedCommand", CommandWrapper(wrappedCommand))
+	generatedData := &packerbuilderdata.GeneratedData{State: state}
 
 	// Build the steps
 	steps := []multistep.Step{
 		&osccommon.StepPreValidate{
-			DestOmiName:     b.config.OMIName,
-			ForceDeregister: b.config.OMIForceDeregister,
+			DestOmiName:  b.config.OMIName,
+			NameConflict: b.config.OMINameConflict,
 		},
 		&StepVmInfo{},
 	}
@@ -228,6 +234,10 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	}
 
 	steps = append(steps,
+		&osccommon.StepSetGeneratedData{
+			GeneratedData: generatedData,
+			RawRegion:     b.config.RawRegion,
+		},
 		&StepFlock{},
 		&StepPrepareDevice{},
 		&StepCreateVolume{
@@ -235,8 +245,11 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 			RootVolumeSize: b.config.RootVolumeSize,
 			RootVolumeTags: b.config.RootVolumeTags,
 			Ctx:            b.config.ctx,
+			PollingConfig:  &b.config.PollingConfig,
+		},
+		&StepLinkVolume{
+			PollingConfig: &b.config.PollingConfig,
 		},
-		&StepLinkVolume{},
 		&StepEarlyUnflock{},
 		&StepPreMountCommands{
 			Commands: b.config.PreMountCommands,
@@ -253,11 +266,13 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		&StepChrootProvision{},
 		&StepEarlyCleanup{},
 		&StepSnapshot{
-			RawRegion: b.config.RawRegion,
+			RawRegion:     b.config.RawRegion,
+			PollingConfig: &b.config.PollingConfig,
 		},
 		&osccommon.StepDeregisterOMI{
 			AccessConfig:        &b.config.AccessConfig,
-			ForceDeregister:     b.config.OMIForceDeregister,
+			PollingConfig:       &b.config.PollingConfig,
+			NameConflict:        b.config.OMINameConflict,
 			ForceDeleteSnapshot: b.config.OMIForceDeleteSnapshot,
 			OMIName:             b.config.OMIName,
 			Regions:             b.config.OMIRegions,
@@ -265,10 +280,20 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		&StepCreateOMI{
 			RootVolumeSize: b.config.RootVolumeSize,
 			RawRegion:      b.config.RawRegion,
+			PollingConfig:  &b.config.PollingConfig,
+		},
+		&osccommon.StepCopyOMI{
+			AccessConfig:  &b.config.AccessConfig,
+			Regions:       b.config.OMIRegions,
+			Name:          b.config.OMIName,
+			PollingConfig: &b.config.PollingConfig,
 		},
 		&osccommon.StepUpdateOMIAttributes{
 			AccountIds:         b.config.OMIAccountIDs,
 			SnapshotAccountIds: b.config.SnapshotAccountIDs,
+			Groups:             b.config.OMIGroups,
+			SnapshotGroups:     b.config.SnapshotGroups,
+			ProductCodes:       b.config.OMIProductCodes,
 			GlobalPermission:   b.config.GlobalPermission,
 			Ctx:                b.config.ctx,
 		},
@@ -277,6 +302,11 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 			SnapshotTags: b.config.SnapshotTags,
 			Ctx:          b.config.ctx,
 		},
+		&osccommon.StepOMIRetention{
+			AccessConfig: &b.config.AccessConfig,
+			Retention:    b.config.OMIRetention,
+			Ctx:          b.config.ctx,
+		},
 	)
 
 	// Run!
@@ -294,8 +324,12 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	}
 
 	// Build the artifact and return it
+	snapshots, _ := state.Get("snapshots").(map[string][]string)
 	artifact := &osccommon.Artifact{
 		Omis:           state.Get("omis").(map[string]string),
+		Snapshots:      snapshots,
+		Endpoint:       b.config.CustomEndpointOAPI,
+		AccessConfig:   &b.config.AccessConfig,
 		BuilderIdValue: BuilderId,
 		StateData:      map[string]interface{}{"generated_data": state.Get("generated_data")},
 	}
@@ -11,57 +11,64 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName         *string                      `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType       *string                      `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion       *string                      `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug             *bool                        `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce             *bool                        `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError           *string                      `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars          map[string]string            `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars     []string                     `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	OMIMappings             []common.FlatBlockDevice     `mapstructure:"omi_block_device_mappings" cty:"omi_block_device_mappings" hcl:"omi_block_device_mappings"`
-	OMIName                 *string                      `mapstructure:"omi_name" cty:"omi_name" hcl:"omi_name"`
-	OMIDescription          *string                      `mapstructure:"omi_description" cty:"omi_description" hcl:"omi_description"`
-	OMIAccountIDs           []string                     `mapstructure:"omi_account_ids" cty:"omi_account_ids" hcl:"omi_account_ids"`
-	OMIGroups               []string                     `mapstructure:"omi_groups" cty:"omi_groups" hcl:"omi_groups"`
-	OMIProductCodes         []string                     `mapstructure:"omi_product_codes" cty:"omi_product_codes" hcl:"omi_product_codes"`
-	OMIRegions              []string                     `mapstructure:"omi_regions" cty:"omi_regions" hcl:"omi_regions"`
-	OMISkipRegionValidation *bool                        `mapstructure:"skip_region_validation" cty:"skip_region_validation" hcl:"skip_region_validation"`
-	OMITags                 common.TagMap                `mapstructure:"tags" cty:"tags" hcl:"tags"`
-	OMIForceDeregister      *bool                        `mapstructure:"force_deregister" cty:"force_deregister" hcl:"force_deregister"`
-	OMIForceDeleteSnapshot  *bool                        `mapstructure:"force_delete_snapshot" cty:"force_delete_snapshot" hcl:"force_delete_snapshot"`
-	SnapshotTags            common.TagMap                `mapstructure:"snapshot_tags" cty:"snapshot_tags" hcl:"snapshot_tags"`
-	SnapshotAccountIDs      []string                     `mapstructure:"snapshot_account_ids" cty:"snapshot_account_ids" hcl:"snapshot_account_ids"`
-	SnapshotGroups          []string                     `mapstructure:"snapshot_groups" cty:"snapshot_groups" hcl:"snapshot_groups"`
-	GlobalPermission        *bool                        `mapstructure:"global_permission" cty:"global_permission" hcl:"global_permission"`
-	AccessKey               *string                      `mapstructure:"access_key" cty:"access_key" hcl:"access_key"`
-	CustomEndpointOAPI      *string                      `mapstructure:"custom_endpoint_oapi" cty:"custom_endpoint_oapi" hcl:"custom_endpoint_oapi"`
-	InsecureSkipTLSVerify   *bool                        `mapstructure:"insecure_skip_tls_verify" cty:"insecure_skip_tls_verify" hcl:"insecure_skip_tls_verify"`
-	MFACode                 *string                      `mapstructure:"mfa_code" cty:"mfa_code" hcl:"mfa_code"`
-	ProfileName             *string                      `mapstructure:"profile" cty:"profile" hcl:"profile"`
-	RawRegion               *string                      `mapstructure:"region" cty:"region" hcl:"region"`
-	SecretKey               *string                      `mapstructure:"secret_key" cty:"secret_key" hcl:"secret_key"`
-	SkipMetadataApiCheck    *bool                        `mapstructure:"skip_metadata_api_check" cty:"skip_metadata_api_check" hcl:"skip_metadata_api_check"`
-	Token                   *string                      `mapstructure:"token" cty:"token" hcl:"token"`
-	X509certPath            *string                      `mapstructure:"x509_cert_path" cty:"x509_cert_path" hcl:"x509_cert_path"`
-	X509keyPath             *string                      `mapstructure:"x509_key_path" cty:"x509_key_path" hcl:"x509_key_path"`
-	ChrootMounts            [][]string                   `mapstructure:"chroot_mounts" cty:"chroot_mounts" hcl:"chroot_mounts"`
-	CommandWrapper          *string                      `mapstructure:"command_wrapper" cty:"command_wrapper" hcl:"command_wrapper"`
-	CopyFiles               []string                     `mapstructure:"copy_files" cty:"copy_files" hcl:"copy_files"`
-	DevicePath              *string                      `mapstructure:"device_path" cty:"device_path" hcl:"device_path"`
-	NVMEDevicePath          *string                      `mapstructure:"nvme_device_path" cty:"nvme_device_path" hcl:"nvme_device_path"`
-	FromScratch             *bool                        `mapstructure:"from_scratch" cty:"from_scratch" hcl:"from_scratch"`
-	MountOptions            []string                     `mapstructure:"mount_options" cty:"mount_options" hcl:"mount_options"`
-	MountPartition          *string                      `mapstructure:"mount_partition" cty:"mount_partition" hcl:"mount_partition"`
-	MountPath               *string                      `mapstructure:"mount_path" cty:"mount_path" hcl:"mount_path"`
-	PostMountCommands       []string                     `mapstructure:"post_mount_commands" cty:"post_mount_commands" hcl:"post_mount_commands"`
-	PreMountCommands        []string                     `mapstructure:"pre_mount_commands" cty:"pre_mount_commands" hcl:"pre_mount_commands"`
-	RootDeviceName          *string                      `mapstructure:"root_device_name" cty:"root_device_name" hcl:"root_device_name"`
-	RootVolumeSize          *int64                       `mapstructure:"root_volume_size" cty:"root_volume_size" hcl:"root_volume_size"`
-	RootVolumeType          *string                      `mapstructure:"root_volume_type" cty:"root_volume_type" hcl:"root_volume_type"`
-	SourceOMI               *string                      `mapstructure:"source_omi" cty:"source_omi" hcl:"source_omi"`
-	SourceOMIFilter         *common.FlatOmiFilterOptions `mapstructure:"source_omi_filter" cty:"source_omi_filter" hcl:"source_omi_filter"`
-	RootVolumeTags          common.TagMap                `mapstructure:"root_volume_tags" cty:"root_volume_tags" hcl:"root_volume_tags"`
+	PackerBuildName         *string                        `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType       *string                        `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion       *string                        `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug             *bool                          `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce             *bool                          `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError           *string                        `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars          map[string]string              `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars     []string                       `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	OMIMappings             []common.FlatBlockDevice       `mapstructure:"omi_block_device_mappings" cty:"omi_block_device_mappings" hcl:"omi_block_device_mappings"`
+	OMIName                 *string                        `mapstructure:"omi_name" cty:"omi_name" hcl:"omi_name"`
+	OMIDescription          *string                        `mapstructure:"omi_description" cty:"omi_description" hcl:"omi_description"`
+	OMIAccountIDs           []string                       `mapstructure:"omi_account_ids" cty:"omi_account_ids" hcl:"omi_account_ids"`
+	OMIGroups               []string                       `mapstructure:"omi_groups" cty:"omi_groups" hcl:"omi_groups"`
+	OMIProductCodes         []string                       `mapstructure:"omi_product_codes" cty:"omi_product_codes" hcl:"omi_product_codes"`
+	OMIRegions              []string                       `mapstructure:"omi_regions" cty:"omi_regions" hcl:"omi_regions"`
+	OMISkipRegionValidation *bool                          `mapstructure:"skip_region_validation" cty:"skip_region_validation" hcl:"skip_region_validation"`
+	OMITags                 common.TagMap                  `mapstructure:"tags" cty:"tags" hcl:"tags"`
+	OMIForceDeregister      *bool                          `mapstructure:"force_deregister" cty:"force_deregister" hcl:"force_deregister"`
+	OMIForceDeleteSnapshot  *bool                          `mapstructure:"force_delete_snapshot" cty:"force_delete_snapshot" hcl:"force_delete_snapshot"`
+	OMINameConflict         *string                        `mapstructure:"omi_name_conflict" cty:"omi_name_conflict" hcl:"omi_name_conflict"`
+	OMIRetention            *common.FlatOMIRetentionConfig `mapstructure:"omi_retention" cty:"omi_retention" hcl:"omi_retention"`
+	SnapshotTags            common.TagMap                  `mapstructure:"snapshot_tags" cty:"snapshot_tags" hcl:"snapshot_tags"`
+	SnapshotAccountIDs      []string                       `mapstructure:"snapshot_account_ids" cty:"snapshot_account_ids" hcl:"snapshot_account_ids"`
+	SnapshotGroups          []string                       `mapstructure:"snapshot_groups" cty:"snapshot_groups" hcl:"snapshot_groups"`
+	GlobalPermission        *bool                          `mapstructure:"global_permission" cty:"global_permission" hcl:"global_permission"`
+	AccessKey               *string                        `mapstructure:"access_key" cty:"access_key" hcl:"access_key"`
+	CustomEndpointOAPI      *string                        `mapstructure:"custom_endpoint_oapi" cty:"custom_endpoint_oapi" hcl:"custom_endpoint_oapi"`
+	InsecureSkipTLSVerify   *bool                          `mapstructure:"insecure_skip_tls_verify" cty:"insecure_skip_tls_verify" hcl:"insecure_skip_tls_verify"`
+	MaxRetries              *int                           `mapstructure:"max_retries" cty:"max_retries" hcl:"max_retries"`
+	MFACode                 *string                        `mapstructure:"mfa_code" cty:"mfa_code" hcl:"mfa_code"`
+	MFASerial               *string                        `mapstructure:"mfa_serial" cty:"mfa_serial" hcl:"mfa_serial"`
+	ProfileName             *string                        `mapstructure:"profile" cty:"profile" hcl:"profile"`
+	RawRegion               *string                        `mapstructure:"region" cty:"region" hcl:"region"`
+	RequestBurst            *int                           `mapstructure:"request_burst" cty:"request_burst" hcl:"request_burst"`
+	RequestsPerSecond       *float64                       `mapstructure:"requests_per_second" cty:"requests_per_second" hcl:"requests_per_second"`
+	SecretKey               *string                        `mapstructure:"secret_key" cty:"secret_key" hcl:"secret_key"`
+	SkipMetadataApiCheck    *bool                          `mapstructure:"skip_metadata_api_check" cty:"skip_metadata_api_check" hcl:"skip_metadata_api_check"`
+	Token                   *string                        `mapstructure:"token" cty:"token" hcl:"token"`
+	X509certPath            *string                        `mapstructure:"x509_cert_path" cty:"x509_cert_path" hcl:"x509_cert_path"`
+	X509keyPath             *string                        `mapstructure:"x509_key_path" cty:"x509_key_path" hcl:"x509_key_path"`
+	ChrootMounts            [][]string                     `mapstructure:"chroot_mounts" cty:"chroot_mounts" hcl:"chroot_mounts"`
+	CommandWrapper          *string                        `mapstructure:"command_wrapper" cty:"command_wrapper" hcl:"command_wrapper"`
+	CopyFiles               []string                       `mapstructure:"copy_files" cty:"copy_files" hcl:"copy_files"`
+	DevicePath              *string                        `mapstructure:"device_path" cty:"device_path" hcl:"device_path"`
+	NVMEDevicePath          *string                        `mapstructure:"nvme_device_path" cty:"nvme_device_path" hcl:"nvme_device_path"`
+	FromScratch             *bool                          `mapstructure:"from_scratch" cty:"from_scratch" hcl:"from_scratch"`
+	MountOptions            []string                       `mapstructure:"mount_options" cty:"mount_options" hcl:"mount_options"`
+	MountPartition          *string                        `mapstructure:"mount_partition" cty:"mount_partition" hcl:"mount_partition"`
+	MountPath               *string                        `mapstructure:"mount_path" cty:"mount_path" hcl:"mount_path"`
+	PostMountCommands       []string                       `mapstructure:"post_mount_commands" cty:"post_mount_commands" hcl:"post_mount_commands"`
+	PreMountCommands        []string                       `mapstructure:"pre_mount_commands" cty:"pre_mount_commands" hcl:"pre_mount_commands"`
+	RootDeviceName          *string                        `mapstructure:"root_device_name" cty:"root_device_name" hcl:"root_device_name"`
+	RootVolumeSize          *int64                         `mapstructure:"root_volume_size" cty:"root_volume_size" hcl:"root_volume_size"`
+	RootVolumeType          *string                        `mapstructure:"root_volume_type" cty:"root_volume_type" hcl:"root_volume_type"`
+	SourceOMI               *string                        `mapstructure:"source_omi" cty:"source_omi" hcl:"source_omi"`
+	SourceOMIFilter         *common.FlatOmiFilterOptions   `mapstructure:"source_omi_filter" cty:"source_omi_filter" hcl:"source_omi_filter"`
+	RootVolumeTags          common.TagMap                  `mapstructure:"root_volume_tags" cty:"root_volume_tags" hcl:"root_volume_tags"`
+	PollingConfig           *common.FlatPollingConfig      `mapstructure:"osc_polling" cty:"osc_polling" hcl:"osc_polling"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -95,6 +102,8 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"tags":                       &hcldec.AttrSpec{Name: "tags", Type: cty.Map(cty.String), Required: false},
 		"force_deregister":           &hcldec.AttrSpec{Name: "force_deregister", Type: cty.Bool, Required: false},
 		"force_delete_snapshot":      &hcldec.AttrSpec{Name: "force_delete_snapshot", Type: cty.Bool, Required: false},
+		"omi_name_conflict":          &hcldec.AttrSpec{Name: "omi_name_conflict", Type: cty.String, Required: false},
+		"omi_retention":              &hcldec.BlockSpec{TypeName: "omi_retention", Nested: hcldec.ObjectSpec((*common.FlatOMIRetentionConfig)(nil).HCL2Spec())},
 		"snapshot_tags":              &hcldec.AttrSpec{Name: "snapshot_tags", Type: cty.Map(cty.String), Required: false},
 		"snapshot_account_ids":       &hcldec.AttrSpec{Name: "snapshot_account_ids", Type: cty.List(cty.String), Required: false},
 		"snapshot_groups":            &hcldec.AttrSpec{Name: "snapshot_groups", Type: cty.List(cty.String), Required: false},
@@ -102,9 +111,13 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"access_key":                 &hcldec.AttrSpec{Name: "access_key", Type: cty.String, Required: false},
 		"custom_endpoint_oapi":       &hcldec.AttrSpec{Name: "custom_endpoint_oapi", Type: cty.String, Required: false},
 		"insecure_skip_tls_verify":   &hcldec.AttrSpec{Name: "insecure_skip_tls_verify", Type: cty.Bool, Required: false},
+		"max_retries":                &hcldec.AttrSpec{Name: "max_retries", Type: cty.Number, Required: false},
 		"mfa_code":                   &hcldec.AttrSpec{Name: "mfa_code", Type: cty.String, Required: false},
+		"mfa_serial":                 &hcldec.AttrSpec{Name: "mfa_serial", Type: cty.String, Required: false},
 		"profile":                    &hcldec.AttrSpec{Name: "profile", Type: cty.String, Required: false},
 		"region":                     &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
+		"request_burst":              &hcldec.AttrSpec{Name: "request_burst", Type: cty.Number, Required: false},
+		"requests_per_second":        &hcldec.AttrSpec{Name: "requests_per_second", Type: cty.Number, Required: false},
 		"secret_key":                 &hcldec.AttrSpec{Name: "secret_key", Type: cty.String, Required: false},
 		"skip_metadata_api_check":    &hcldec.AttrSpec{Name: "skip_metadata_api_check", Type: cty.Bool, Required: false},
 		"token":                      &hcldec.AttrSpec{Name: "token", Type: cty.String, Required: false},
@@ -127,6 +140,7 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"source_omi":                 &hcldec.AttrSpec{Name: "source_omi", Type: cty.String, Required: false},
 		"source_omi_filter":          &hcldec.BlockSpec{TypeName: "source_omi_filter", Nested: hcldec.ObjectSpec((*common.FlatOmiFilterOptions)(nil).HCL2Spec())},
 		"root_volume_tags":           &hcldec.AttrSpec{Name: "root_volume_tags", Type: cty.Map(cty.String), Required: false},
+		"osc_polling":                &hcldec.BlockSpec{TypeName: "osc_polling", Nested: hcldec.ObjectSpec((*common.FlatPollingConfig)(nil).HCL2Spec())},
 	}
 	return s
 }
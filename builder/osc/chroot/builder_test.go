@@ -0,0 +1,119 @@
+package chroot
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/antihax/optional"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+// stepUnlinkVolume unlinks the root volume, as StepEarlyCleanup does once the
+// device is unmounted.
+type stepUnlinkVolume struct{}
+
+func (s *stepUnlinkVolume) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	if err := state.Get("attach_cleanup").(Cleanup).CleanupFunc(state); err != nil {
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	return multistep.ActionContinue
+}
+
+func (s *stepUnlinkVolume) Cleanup(multistep.StateBag) {}
+
+// runVolumeSteps runs the steps building an OMI from the root volume of the
+// source OMI, from the VM Packer runs on.
+func runVolumeSteps(t *testing.T, server *oapitest.Server) multistep.StateBag {
+	access := &osccommon.AccessConfig{
+		AccessKey:          "AK",
+		SecretKey:          "SK",
+		RawRegion:          "eu-west-2",
+		CustomEndpointOAPI: server.URL,
+	}
+	conn := access.NewOSCClientByRegion("eu-west-2")
+
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+	resp, _, err := conn.VmApi.CreateVms(context.Background(), &osc.CreateVmsOpts{
+		CreateVmsRequest: optional.NewInterface(osc.CreateVmsRequest{ImageId: source.ImageId}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", osccommon.DecodeError(err))
+	}
+
+	polling := &osccommon.PollingConfig{DelaySeconds: 1}
+	config := &Config{AccessConfig: *access}
+	config.OMIName = "packer-test"
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", config)
+	state.Put("osc", conn)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("vm", resp.Vms[0])
+	state.Put("source_image", source)
+	state.Put("device", "/dev/xvdf")
+
+	runner := &multistep.BasicRunner{Steps: []multistep.Step{
+		&StepCreateVolume{RawRegion: "eu-west-2", PollingConfig: polling},
+		&StepLinkVolume{PollingConfig: polling},
+		&stepUnlinkVolume{},
+		&StepSnapshot{RawRegion: "eu-west-2", PollingConfig: polling},
+		&StepCreateOMI{RootVolumeSize: 10, RawRegion: "eu-west-2", PollingConfig: polling},
+	}}
+	runner.Run(context.Background(), state)
+	return state
+}
+
+func TestBuilder_volumeSteps(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+
+	state := runVolumeSteps(t, server)
+	if err, ok := state.GetOk("error"); ok {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	omis := state.Get("omis").(map[string]string)
+	var built *osc.Image
+	for _, image := range server.Images() {
+		if image.ImageId == omis["eu-west-2"] {
+			built = &image
+		}
+	}
+	snapshotID := state.Get("snapshot_id").(string)
+	if built == nil || built.State != "available" || built.BlockDeviceMappings[0].Bsu.SnapshotId != snapshotID {
+		t.Fatalf("bad OMI: %#v", built)
+	}
+
+	// Only the root volume of the VM Packer runs on is left.
+	if volumes := server.Volumes(); len(volumes) != 1 || len(volumes[0].LinkedVolumes) != 1 {
+		t.Fatalf("the volume should be deleted, got %#v", volumes)
+	}
+}
+
+func TestBuilder_volumeStepsCleanup(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	server.Fail("CreateImage", http.StatusConflict, "ResourceConflict")
+
+	state := runVolumeSteps(t, server)
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+
+	if volumes := server.Volumes(); len(volumes) != 1 {
+		t.Fatalf("the volume should be deleted, got %#v", volumes)
+	}
+	for _, snapshot := range server.Snapshots() {
+		if snapshot.SnapshotId == state.Get("snapshot_id") {
+			t.Fatalf("the snapshot should be deleted, got %#v", snapshot)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 type StepCreateOMI struct {
 	RootVolumeSize int64
 	RawRegion      string
+	PollingConfig  *osccommon.PollingConfig
 }
 
 func (s *StepCreateOMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -74,6 +75,7 @@ func (s *StepCreateOMI) Run(ctx context.Context, state multistep.StateBag) multi
 	} else {
 		registerOpts = buildRegisterOpts(config, image, newMappings)
 	}
+	registerOpts.ImageName = osccommon.OMIName(state, registerOpts.ImageName)
 
 	if config.OMIDescription != "" {
 		registerOpts.Description = config.OMIDescription
@@ -83,7 +85,7 @@ func (s *StepCreateOMI) Run(ctx context.Context, state multistep.StateBag) multi
 		CreateImageRequest: optional.NewInterface(registerOpts),
 	})
 	if err != nil {
-		state.Put("error", fmt.Errorf("Error registering OMI: %s", err))
+		state.Put("error", fmt.Errorf("Error registering OMI: %s", osccommon.DecodeError(err)))
 		ui.Error(state.Get("error").(error).Error())
 		return multistep.ActionHalt
 	}
@@ -97,7 +99,7 @@ func (s *StepCreateOMI) Run(ctx context.Context, state multistep.StateBag) multi
 	state.Put("omis", omis)
 
 	ui.Say("Waiting for OMI to become ready...")
-	if err := osccommon.WaitUntilOscImageAvailable(osconn, imageID); err != nil {
+	if err := s.PollingConfig.WaitUntilOscImageAvailable(ctx, osconn, imageID); err != nil {
 		err := fmt.Errorf("Error waiting for OMI: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
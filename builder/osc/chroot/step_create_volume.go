@@ -26,6 +26,7 @@ type StepCreateVolume struct {
 	RootVolumeType string
 	RootVolumeTags osccommon.TagMap
 	RawRegion      string
+	PollingConfig  *osccommon.PollingConfig
 	Ctx            interpolate.Context
 }
 
@@ -89,7 +90,7 @@ func (s *StepCreateVolume) Run(ctx context.Context, state multistep.StateBag) mu
 		CreateVolumeRequest: optional.NewInterface(*createVolume),
 	})
 	if err != nil {
-		err := fmt.Errorf("Error creating root volume: %s", err)
+		err := fmt.Errorf("Error creating root volume: %s", osccommon.DecodeError(err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
@@ -102,7 +103,7 @@ func (s *StepCreateVolume) Run(ctx context.Context, state multistep.StateBag) mu
 	//Create tags for volume
 	if len(volTags) > 0 {
 		if err := osccommon.CreateOSCTags(oscconn, s.volumeId, ui, volTags); err != nil {
-			err := fmt.Errorf("Error creating tags for volume: %s", err)
+			err := fmt.Errorf("Error creating tags for volume: %s", osccommon.DecodeError(err))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
@@ -110,7 +111,7 @@ func (s *StepCreateVolume) Run(ctx context.Context, state multistep.StateBag) mu
 	}
 
 	// Wait for the volume to become ready
-	err = osccommon.WaitUntilOscVolumeAvailable(oscconn, s.volumeId)
+	err = s.PollingConfig.WaitUntilOscVolumeAvailable(ctx, oscconn, s.volumeId)
 	if err != nil {
 		err := fmt.Errorf("Error waiting for volume: %s", err)
 		state.Put("error", err)
@@ -135,7 +136,7 @@ func (s *StepCreateVolume) Cleanup(state multistep.StateBag) {
 		DeleteVolumeRequest: optional.NewInterface(osc.DeleteVolumeRequest{VolumeId: s.volumeId}),
 	})
 	if err != nil {
-		ui.Error(fmt.Sprintf("Error deleting BSU volume: %s", err))
+		ui.Error(fmt.Sprintf("Error deleting BSU volume: %s", osccommon.DecodeError(err)))
 	}
 }
 
@@ -19,6 +19,8 @@ import (
 //	device string - The location where the volume was attached.
 //	attach_cleanup CleanupFunc
 type StepLinkVolume struct {
+	PollingConfig *osccommon.PollingConfig
+
 	attached bool
 	volumeId string
 }
@@ -44,7 +46,7 @@ func (s *StepLinkVolume) Run(ctx context.Context, state multistep.StateBag) mult
 	})
 
 	if err != nil {
-		err := fmt.Errorf("Error attaching volume: %s", err)
+		err := fmt.Errorf("Error attaching volume: %s", osccommon.DecodeError(err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
@@ -55,7 +57,7 @@ func (s *StepLinkVolume) Run(ctx context.Context, state multistep.StateBag) mult
 	s.volumeId = volumeId
 
 	// Wait for the volume to become attached
-	err = osccommon.WaitUntilOscVolumeIsLinked(oscconn, s.volumeId)
+	err = s.PollingConfig.WaitUntilOscVolumeIsLinked(ctx, oscconn, s.volumeId)
 	if err != nil {
 		err := fmt.Errorf("Error waiting for volume: %s", err)
 		state.Put("error", err)
@@ -88,13 +90,13 @@ func (s *StepLinkVolume) CleanupFunc(state multistep.StateBag) error {
 	})
 
 	if err != nil {
-		return fmt.Errorf("Error detaching BSU volume: %s", err)
+		return fmt.Errorf("Error detaching BSU volume: %s", osccommon.DecodeError(err))
 	}
 
 	s.attached = false
 
 	// Wait for the volume to detach
-	err = osccommon.WaitUntilOscVolumeIsUnlinked(oscconn, s.volumeId)
+	err = s.PollingConfig.WaitUntilOscVolumeIsUnlinked(context.Background(), oscconn, s.volumeId)
 	if err != nil {
 		return fmt.Errorf("Error waiting for volume: %s", err)
 	}
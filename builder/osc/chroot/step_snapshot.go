@@ -18,8 +18,9 @@ import (
 //
 //	snapshot_id string - ID of the created snapshot
 type StepSnapshot struct {
-	snapshotId string
-	RawRegion  string
+	snapshotId    string
+	RawRegion     string
+	PollingConfig *osccommon.PollingConfig
 }
 
 func (s *StepSnapshot) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -37,7 +38,7 @@ func (s *StepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 		}),
 	})
 	if err != nil {
-		err := fmt.Errorf("Error creating snapshot: %s", err)
+		err := fmt.Errorf("Error creating snapshot: %s", osccommon.DecodeError(err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
@@ -48,7 +49,7 @@ func (s *StepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 	ui.Message(fmt.Sprintf("Snapshot ID: %s", s.snapshotId))
 
 	// Wait for the snapshot to be ready
-	err = osccommon.WaitUntilOscSnapshotDone(oscconn, s.snapshotId)
+	err = s.PollingConfig.WaitUntilOscSnapshotDone(ctx, oscconn, s.snapshotId)
 	if err != nil {
 		err := fmt.Errorf("Error waiting for snapshot: %s", err)
 		state.Put("error", err)
@@ -82,7 +83,7 @@ func (s *StepSnapshot) Cleanup(state multistep.StateBag) {
 			DeleteSnapshotRequest: optional.NewInterface(osc.DeleteSnapshotRequest{SnapshotId: s.snapshotId}),
 		})
 		if err != nil {
-			ui.Error(fmt.Sprintf("Error: %s", err))
+			ui.Error(fmt.Sprintf("Error: %s", osccommon.DecodeError(err)))
 		}
 	}
 }
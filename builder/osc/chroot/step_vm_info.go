@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
 )
 
 // StepVmInfo verifies that this builder is running on an Outscale vm.
@@ -43,7 +44,7 @@ func (s *StepVmInfo) Run(_ context.Context, state multistep.StateBag) multistep.
 		}}),
 	})
 	if err != nil {
-		err := fmt.Errorf("Error getting vm data: %s", err)
+		err := fmt.Errorf("Error getting vm data: %s", osccommon.DecodeError(err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
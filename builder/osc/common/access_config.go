@@ -5,30 +5,58 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/outscale/osc-sdk-go/osc"
 	"github.com/outscale/packer-plugin-outscale/version"
+	"golang.org/x/time/rate"
 )
 
 // AccessConfig is for common configuration related to Outscale API access
 type AccessConfig struct {
-	AccessKey             string `mapstructure:"access_key"`
-	CustomEndpointOAPI    string `mapstructure:"custom_endpoint_oapi"`
-	InsecureSkipTLSVerify bool   `mapstructure:"insecure_skip_tls_verify"`
-	MFACode               string `mapstructure:"mfa_code"`
-	ProfileName           string `mapstructure:"profile"`
-	RawRegion             string `mapstructure:"region"`
-	SecretKey             string `mapstructure:"secret_key"`
-	SkipValidation        bool   `mapstructure:"skip_region_validation"`
-	SkipMetadataApiCheck  bool   `mapstructure:"skip_metadata_api_check"`
-	Token                 string `mapstructure:"token"`
-	X509certPath          string `mapstructure:"x509_cert_path"`
-	X509keyPath           string `mapstructure:"x509_key_path"`
+	AccessKey             string  `mapstructure:"access_key"`
+	CustomEndpointOAPI    string  `mapstructure:"custom_endpoint_oapi"`
+	InsecureSkipTLSVerify bool    `mapstructure:"insecure_skip_tls_verify"`
+	MaxRetries            int     `mapstructure:"max_retries"`
+	MFACode               string  `mapstructure:"mfa_code"`
+	MFASerial             string  `mapstructure:"mfa_serial"`
+	ProfileName           string  `mapstructure:"profile"`
+	RawRegion             string  `mapstructure:"region"`
+	RequestBurst          int     `mapstructure:"request_burst"`
+	RequestsPerSecond     float64 `mapstructure:"requests_per_second"`
+	SecretKey             string  `mapstructure:"secret_key"`
+	SkipValidation        bool    `mapstructure:"skip_region_validation"`
+	SkipMetadataApiCheck  bool    `mapstructure:"skip_metadata_api_check"`
+	Token                 string  `mapstructure:"token"`
+	X509certPath          string  `mapstructure:"x509_cert_path"`
+	X509keyPath           string  `mapstructure:"x509_key_path"`
+
+	// limiter is shared by every client of the build, so that the copies in
+	// several regions do not add up their requests. It is created with the
+	// first client, see rateLimiter.
+	limiter *rate.Limiter
+	// cassette records or replays the OAPI calls, as set up by the
+	// OSC_CASSETTE_MODE and OSC_CASSETTE environment variables.
+	cassette *cassette
 }
 
+const (
+	defaultMaxRetries        = 5
+	defaultRequestsPerSecond = 10
+)
+
 func getValueFromEnvVariables(envVariables []string) (string, bool) {
 
 	for _, envVariable := range envVariables {
@@ -40,32 +68,63 @@ func getValueFromEnvVariables(envVariables []string) (string, bool) {
 	return "", false
 }
 
+// credentialVariables returns the environment variables holding a
+// credential, or none when the template names a profile: its keys and
+// certificates must not be mixed with those of the environment.
+func (c *AccessConfig) credentialVariables(envVariables ...string) []string {
+	if c.ProfileName != "" {
+		return nil
+	}
+	return envVariables
+}
+
+// getValue looks the environment variables up and falls back on the given
+// value, typically read from a profile.
+func getValue(envVariables []string, fallback string) (string, bool) {
+	if value, ok := getValueFromEnvVariables(envVariables); ok {
+		return value, true
+	}
+
+	return fallback, fallback != ""
+}
+
 // NewOSCClient retrieves the Outscale OSC-SDK client
+//
+// Each setting is taken, in order of precedence, from the template, from the
+// OSC_* and OUTSCALE_* environment variables, and finally from the profile of
+// the Outscale configuration file (see loadProfile). The credentials of a
+// profile named in the template are not overridden by the environment.
 func (c *AccessConfig) NewOSCClient() (*osc.APIClient, error) {
+	profile, err := c.loadProfile()
+	if err != nil {
+		return nil, err
+	}
+
 	if c.AccessKey == "" {
 		var ok bool
-		if c.AccessKey, ok = getValueFromEnvVariables([]string{"OSC_ACCESS_KEY", "OUTSCALE_ACCESSKEYID"}); !ok {
-			return nil, errors.New("No access key has been setted (configuration file, environment variable : OSC_ACCESS_KEY or OUTSCALE_ACCESSKEYID)")
+		if c.AccessKey, ok = getValue(c.credentialVariables("OSC_ACCESS_KEY", "OUTSCALE_ACCESSKEYID"), profile.AccessKey); !ok {
+			return nil, errors.New("No access key has been setted (configuration file, environment variable : OSC_ACCESS_KEY or OUTSCALE_ACCESSKEYID, profile)")
 		}
 	}
 
 	if c.SecretKey == "" {
 		var ok bool
-		if c.SecretKey, ok = getValueFromEnvVariables([]string{"OSC_SECRET_KEY", "OUTSCALE_SECRETKEYID"}); !ok {
-			return nil, errors.New("No secret key has been setted (configuration file, environment variable : OSC_SECRET_KEY or OUTSCALE_SECRETKEYID)")
+		if c.SecretKey, ok = getValue(c.credentialVariables("OSC_SECRET_KEY", "OUTSCALE_SECRETKEYID"), profile.SecretKey); !ok {
+			return nil, errors.New("No secret key has been setted (configuration file, environment variable : OSC_SECRET_KEY or OUTSCALE_SECRETKEYID, profile)")
 		}
 	}
+	packersdk.LogSecretFilter.Set(c.SecretKey)
 
 	if c.RawRegion == "" {
 		var ok bool
-		if c.RawRegion, ok = getValueFromEnvVariables([]string{"OSC_REGION", "OUTSCALE_REGION"}); !ok {
-			return nil, errors.New("No region has been setted (configuration file, environment variable : OSC_REGION or OUTSCALE_REGION)")
+		if c.RawRegion, ok = getValue([]string{"OSC_REGION", "OUTSCALE_REGION"}, profile.Region); !ok {
+			return nil, errors.New("No region has been setted (configuration file, environment variable : OSC_REGION or OUTSCALE_REGION, profile)")
 		}
 	}
 
 	if c.CustomEndpointOAPI == "" {
 		var ok bool
-		if c.CustomEndpointOAPI, ok = getValueFromEnvVariables([]string{"OSC_ENDPOINT_API", "OUTSCALE_OAPI_URL"}); !ok {
+		if c.CustomEndpointOAPI, ok = getValue([]string{"OSC_ENDPOINT_API", "OUTSCALE_OAPI_URL"}, profile.customEndpoint()); !ok {
 			log.Printf("No Custom Endpoint has been setted")
 		}
 	}
@@ -81,18 +140,24 @@ func (c *AccessConfig) NewOSCClient() (*osc.APIClient, error) {
 
 	if c.X509certPath == "" {
 		var ok bool
-		if c.X509certPath, ok = getValueFromEnvVariables([]string{"OSC_X509_CLIENT_CERT", "OUTSCALE_X509CERT"}); !ok {
+		if c.X509certPath, ok = getValue(c.credentialVariables("OSC_X509_CLIENT_CERT", "OUTSCALE_X509CERT"), profile.X509ClientCert); !ok {
 			log.Printf("No Certificat Path has been setted")
 		}
 	}
 
 	if c.X509keyPath == "" {
 		var ok bool
-		if c.X509certPath, ok = getValueFromEnvVariables([]string{"OSC_X509_CLIENT_KEY", "OUTSCALE_X509KEY"}); !ok {
+		if c.X509keyPath, ok = getValue(c.credentialVariables("OSC_X509_CLIENT_KEY", "OUTSCALE_X509KEY"), profile.X509ClientKey); !ok {
 			log.Printf("No Key Path has been setted")
 		}
 	}
 
+	if c.MFACode != "" {
+		if err := c.getSessionCredentials(); err != nil {
+			return nil, err
+		}
+	}
+
 	return c.NewOSCClientByRegion(c.RawRegion), nil
 }
 
@@ -103,6 +168,84 @@ func (c *AccessConfig) GetRegion() string {
 
 // NewOSCClientByRegion returns the connection depdending of the region given
 func (c *AccessConfig) NewOSCClientByRegion(region string) *osc.APIClient {
+	skipClient := &http.Client{
+		Transport: c.httpTransport(),
+	}
+
+	transport := NewTransport(c.AccessKey, c.SecretKey, c.Token, region, skipClient.Transport)
+	transport.maxRetries = c.MaxRetries
+	transport.limiter = c.rateLimiter()
+	transport.cassette = c.cassette
+	skipClient.Transport = transport
+
+	return osc.NewAPIClient(&osc.Configuration{
+		BasePath:      c.oapiEndpoint(region),
+		DefaultHeader: make(map[string]string),
+		UserAgent:     fmt.Sprintf("packer-osc/%s", version.PluginVersion.String()),
+		HTTPClient:    skipClient,
+		Debug:         true,
+	})
+}
+
+// oapiEndpoint returns the OAPI endpoint of the region. A full URL of a
+// regional endpoint, such as `https://api.eu-west-2.outscale.com/api/v1`, is
+// reduced to its domain and path so that each region is reached on its own
+// endpoint. Any other full URL, such as the one of a proxy, is used as is for
+// every region.
+func (c *AccessConfig) oapiEndpoint(region string) string {
+	endpoint := normalizeEndpoint(c.CustomEndpointOAPI)
+	if strings.Contains(endpoint, "://") {
+		return endpoint
+	}
+	return fmt.Sprintf("https://api.%s.%s", region, endpoint)
+}
+
+// reRegionalAPI matches the `api.<region>.` prefix of an OAPI endpoint.
+var reRegionalAPI = regexp.MustCompile(`^api\.[a-z0-9]+(-[a-z0-9]+)+\.`)
+
+// normalizeEndpoint converts an OAPI endpoint into the form expected by
+// `custom_endpoint_oapi`: the scheme and the `api.<region>.` prefix of a
+// regional endpoint are dropped, whatever the region. Other full URLs are
+// kept.
+func normalizeEndpoint(endpoint string) string {
+	if i := strings.Index(endpoint, "://"); i >= 0 {
+		if !reRegionalAPI.MatchString(endpoint[i+len("://"):]) {
+			return endpoint
+		}
+		endpoint = endpoint[i+len("://"):]
+	}
+	return reRegionalAPI.ReplaceAllString(endpoint, "")
+}
+
+// limiterLock guards the creation of the rate limiters, as the clients of the
+// copies are created concurrently. AccessConfig itself is copied by value and
+// cannot hold the lock.
+var limiterLock sync.Mutex
+
+// rateLimiter returns the limiter throttling the OAPI calls client-side,
+// creating it on the first call. The defaults of Prepare apply when the
+// config has not been prepared, so that every client is throttled.
+func (c *AccessConfig) rateLimiter() *rate.Limiter {
+	limiterLock.Lock()
+	defer limiterLock.Unlock()
+
+	if c.limiter == nil {
+		requestsPerSecond := c.RequestsPerSecond
+		if requestsPerSecond == 0 {
+			requestsPerSecond = defaultRequestsPerSecond
+		}
+		burst := c.RequestBurst
+		if burst == 0 {
+			burst = int(math.Ceil(requestsPerSecond))
+		}
+		c.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+	}
+	return c.limiter
+}
+
+// httpTransport returns the transport honoring the TLS settings and the x509
+// client certificate, if any.
+func (c *AccessConfig) httpTransport() *http.Transport {
 	transport := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: c.InsecureSkipTLSVerify},
 		Proxy:           http.ProxyFromEnvironment,
@@ -118,19 +261,58 @@ func (c *AccessConfig) NewOSCClientByRegion(region string) *osc.APIClient {
 		}
 	}
 
-	skipClient := &http.Client{
-		Transport: transport,
+	return transport
+}
+
+// endpointDomain returns the domain of the OAPI endpoint, on which the other
+// services of the region are reached.
+func (c *AccessConfig) endpointDomain() string {
+	endpoint := normalizeEndpoint(c.CustomEndpointOAPI)
+	if i := strings.Index(endpoint, "://"); i >= 0 {
+		endpoint = endpoint[i+len("://"):]
 	}
+	return strings.SplitN(endpoint, "/", 2)[0]
+}
 
-	skipClient.Transport = NewTransport(c.AccessKey, c.SecretKey, c.RawRegion, skipClient.Transport)
+// stsEndpoint returns the STS-compatible endpoint of the region, on the same
+// domain as the OAPI endpoint.
+func (c *AccessConfig) stsEndpoint() string {
+	return fmt.Sprintf("https://sts.%s.%s", c.RawRegion, c.endpointDomain())
+}
 
-	return osc.NewAPIClient(&osc.Configuration{
-		BasePath:      fmt.Sprintf("https://api.%s.%s", region, c.CustomEndpointOAPI),
-		DefaultHeader: make(map[string]string),
-		UserAgent:     fmt.Sprintf("packer-osc/%s", version.PluginVersion.String()),
-		HTTPClient:    skipClient,
-		Debug:         true,
+// getSessionCredentials exchanges the access and secret keys, along with the
+// MFA code, for temporary credentials which replace them for the whole build.
+// The MFA code is cleared once used, as it cannot be exchanged twice.
+func (c *AccessConfig) getSessionCredentials() error {
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(c.AccessKey, c.SecretKey, ""),
+		Endpoint:    aws.String(c.stsEndpoint()),
+		Region:      aws.String(c.RawRegion),
+		HTTPClient:  &http.Client{Transport: c.httpTransport()},
 	})
+	if err != nil {
+		return err
+	}
+
+	input := &sts.GetSessionTokenInput{
+		TokenCode: aws.String(c.MFACode),
+	}
+	if c.MFASerial != "" {
+		input.SerialNumber = aws.String(c.MFASerial)
+	}
+
+	resp, err := sts.New(sess).GetSessionToken(input)
+	if err != nil {
+		return fmt.Errorf("Error getting temporary credentials with the MFA code: %s", err)
+	}
+
+	c.AccessKey = aws.StringValue(resp.Credentials.AccessKeyId)
+	c.SecretKey = aws.StringValue(resp.Credentials.SecretAccessKey)
+	c.Token = aws.StringValue(resp.Credentials.SessionToken)
+	c.MFACode = ""
+	packersdk.LogSecretFilter.Set(c.SecretKey, c.Token)
+
+	return nil
 }
 
 func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
@@ -146,5 +328,38 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 			fmt.Errorf("`access_key` and `secret_key` must both be either set or not set."))
 	}
 
+	if c.MFACode != "" && c.Token != "" {
+		errs = append(errs,
+			fmt.Errorf("`mfa_code` cannot be used with `token`, which already holds temporary credentials."))
+	}
+
+	if c.MFASerial != "" && c.MFACode == "" {
+		errs = append(errs, fmt.Errorf("`mfa_serial` requires `mfa_code` to be set."))
+	}
+
+	if c.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("`max_retries` must be positive."))
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = defaultMaxRetries
+	}
+
+	if c.RequestsPerSecond < 0 {
+		errs = append(errs, fmt.Errorf("`requests_per_second` must be positive."))
+	} else if c.RequestsPerSecond == 0 {
+		c.RequestsPerSecond = defaultRequestsPerSecond
+	}
+
+	if c.RequestBurst < 0 {
+		errs = append(errs, fmt.Errorf("`request_burst` must be positive."))
+	} else if c.RequestBurst == 0 {
+		c.RequestBurst = int(math.Ceil(c.RequestsPerSecond))
+	}
+
+	cassette, err := cassetteFromEnv()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	c.cassette = cassette
+
 	return errs
 }
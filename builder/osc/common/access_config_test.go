@@ -1,5 +1,343 @@
 package common
 
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
 func testAccessConfig() *AccessConfig {
 	return &AccessConfig{}
 }
+
+// testServerConfig returns the config of the clients of the fake OAPI server,
+// in eu-west-2.
+func testServerConfig(server *oapitest.Server) *AccessConfig {
+	return &AccessConfig{AccessKey: "AK", SecretKey: "SK", RawRegion: "eu-west-2", CustomEndpointOAPI: server.URL}
+}
+
+// testServerState returns the state of a step run against the fake OAPI server,
+// holding the ui, the access config and its client.
+func testServerState(t *testing.T, server *oapitest.Server) multistep.StateBag {
+	config := testServerConfig(server)
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("accessConfig", config)
+	state.Put("osc", config.NewOSCClientByRegion(config.RawRegion))
+	return state
+}
+
+const testConfigFile = `{
+  "default": {
+    "access_key": "DEFAULTAK",
+    "secret_key": "DEFAULTSK",
+    "region": "eu-west-2"
+  },
+  "other": {
+    "access_key": "OTHERAK",
+    "secret_key": "OTHERSK",
+    "region": "us-east-2",
+    "endpoints": {"api": "https://api.us-east-2.outscale.com/api/v1"},
+    "x509_client_cert": "/tmp/cert.pem",
+    "x509_client_key": "/tmp/key.pem"
+  }
+}`
+
+func testConfigFileEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(testConfigFile), 0600); err != nil {
+		t.Fatalf("cannot write config file: %s", err)
+	}
+
+	for _, env := range []string{
+		"OSC_ACCESS_KEY", "OUTSCALE_ACCESSKEYID", "OSC_SECRET_KEY", "OUTSCALE_SECRETKEYID",
+		"OSC_REGION", "OUTSCALE_REGION", "OSC_ENDPOINT_API", "OUTSCALE_OAPI_URL",
+		"OSC_X509_CLIENT_CERT", "OUTSCALE_X509CERT", "OSC_X509_CLIENT_KEY", "OUTSCALE_X509KEY",
+		"OSC_PROFILE",
+	} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("OSC_CONFIG_FILE", path)
+}
+
+func TestAccessConfig_defaultProfile(t *testing.T) {
+	testConfigFileEnv(t)
+
+	c := testAccessConfig()
+	if _, err := c.NewOSCClient(); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	if c.AccessKey != "DEFAULTAK" || c.SecretKey != "DEFAULTSK" || c.RawRegion != "eu-west-2" {
+		t.Fatalf("default profile not loaded: %#v", c)
+	}
+	if c.CustomEndpointOAPI != "outscale.com/oapi/latest" {
+		t.Fatalf("bad endpoint: %s", c.CustomEndpointOAPI)
+	}
+}
+
+func TestAccessConfig_namedProfile(t *testing.T) {
+	testConfigFileEnv(t)
+
+	c := testAccessConfig()
+	c.ProfileName = "other"
+	if _, err := c.NewOSCClient(); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	if c.AccessKey != "OTHERAK" || c.SecretKey != "OTHERSK" || c.RawRegion != "us-east-2" {
+		t.Fatalf("named profile not loaded: %#v", c)
+	}
+	if c.CustomEndpointOAPI != "outscale.com/api/v1" {
+		t.Fatalf("bad endpoint: %s", c.CustomEndpointOAPI)
+	}
+	if c.X509certPath != "/tmp/cert.pem" || c.X509keyPath != "/tmp/key.pem" {
+		t.Fatalf("bad x509 paths: %s %s", c.X509certPath, c.X509keyPath)
+	}
+}
+
+func TestAccessConfig_profileFromEnv(t *testing.T) {
+	testConfigFileEnv(t)
+	t.Setenv("OSC_PROFILE", "other")
+
+	c := testAccessConfig()
+	if _, err := c.NewOSCClient(); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	if c.AccessKey != "OTHERAK" {
+		t.Fatalf("OSC_PROFILE not honored: %#v", c)
+	}
+}
+
+func TestAccessConfig_profilePrecedence(t *testing.T) {
+	testConfigFileEnv(t)
+	t.Setenv("OSC_REGION", "cloudgouv-eu-west-1")
+
+	c := testAccessConfig()
+	c.ProfileName = "other"
+	c.AccessKey = "TEMPLATEAK"
+	c.SecretKey = "TEMPLATESK"
+	if _, err := c.NewOSCClient(); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	if c.AccessKey != "TEMPLATEAK" || c.SecretKey != "TEMPLATESK" {
+		t.Fatalf("template values should win over the profile: %#v", c)
+	}
+	if c.RawRegion != "cloudgouv-eu-west-1" {
+		t.Fatalf("environment should win over the profile: %s", c.RawRegion)
+	}
+}
+
+func TestAccessConfig_namedProfileIgnoresEnvCredentials(t *testing.T) {
+	testConfigFileEnv(t)
+	t.Setenv("OSC_ACCESS_KEY", "ENVAK")
+	t.Setenv("OSC_SECRET_KEY", "ENVSK")
+	t.Setenv("OSC_X509_CLIENT_CERT", "/tmp/env-cert.pem")
+
+	c := testAccessConfig()
+	c.ProfileName = "other"
+	if _, err := c.NewOSCClient(); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	if c.AccessKey != "OTHERAK" || c.SecretKey != "OTHERSK" || c.X509certPath != "/tmp/cert.pem" {
+		t.Fatalf("the credentials of the named profile should win over the environment: %#v", c)
+	}
+
+	// Without a profile in the template, the environment still wins.
+	t.Setenv("OSC_PROFILE", "other")
+	c = testAccessConfig()
+	if _, err := c.NewOSCClient(); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	if c.AccessKey != "ENVAK" || c.SecretKey != "ENVSK" {
+		t.Fatalf("environment should win over the OSC_PROFILE profile: %#v", c)
+	}
+}
+
+func TestAccessConfig_unknownProfile(t *testing.T) {
+	testConfigFileEnv(t)
+
+	c := testAccessConfig()
+	c.ProfileName = "missing"
+	if _, err := c.NewOSCClient(); err == nil {
+		t.Fatal("should have error on an unknown profile")
+	}
+}
+
+func TestAccessConfigPrepare_MFA(t *testing.T) {
+	c := testAccessConfig()
+	c.MFACode = "123456"
+	c.Token = "TOKEN"
+	if err := c.Prepare(nil); len(err) == 0 {
+		t.Fatal("should have error when both mfa_code and token are set")
+	}
+
+	c = testAccessConfig()
+	c.MFASerial = "mfa-device"
+	if err := c.Prepare(nil); len(err) == 0 {
+		t.Fatal("should have error when mfa_serial is set without mfa_code")
+	}
+
+	c = testAccessConfig()
+	c.MFACode = "123456"
+	c.MFASerial = "mfa-device"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+}
+
+func TestAccessConfigPrepare_retries(t *testing.T) {
+	c := testAccessConfig()
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if c.MaxRetries != defaultMaxRetries || c.RequestsPerSecond != defaultRequestsPerSecond || c.RequestBurst != 10 {
+		t.Fatalf("bad defaults: %d, %f, %d", c.MaxRetries, c.RequestsPerSecond, c.RequestBurst)
+	}
+
+	first := c.NewOSCClientByRegion("eu-west-2").GetConfig().HTTPClient.Transport.(*Transport)
+	second := c.NewOSCClientByRegion("us-east-2").GetConfig().HTTPClient.Transport.(*Transport)
+	if first.limiter == nil || first.limiter != second.limiter {
+		t.Fatal("the rate limiter should be shared by every client")
+	}
+	if first.limiter.Limit() != defaultRequestsPerSecond || first.limiter.Burst() != 10 {
+		t.Fatalf("bad rate limiter: %f, %d", first.limiter.Limit(), first.limiter.Burst())
+	}
+	if first.maxRetries != defaultMaxRetries {
+		t.Fatalf("bad max retries: %d", first.maxRetries)
+	}
+
+	c = testAccessConfig()
+	c.MaxRetries = -1
+	c.RequestsPerSecond = -1
+	c.RequestBurst = -1
+	if err := c.Prepare(nil); len(err) != 3 {
+		t.Fatalf("should have 3 errors, got %v", err)
+	}
+}
+
+func TestAccessConfig_rateLimiterWithoutPrepare(t *testing.T) {
+	c := &AccessConfig{AccessKey: "AK", SecretKey: "SK", RawRegion: "eu-west-2"}
+
+	first := c.NewOSCClientByRegion("eu-west-2").GetConfig().HTTPClient.Transport.(*Transport)
+	second := c.NewOSCClientByRegion("us-east-2").GetConfig().HTTPClient.Transport.(*Transport)
+	if first.limiter == nil || first.limiter != second.limiter {
+		t.Fatal("clients of an unprepared config should share a rate limiter")
+	}
+	if first.limiter.Limit() != defaultRequestsPerSecond {
+		t.Fatalf("bad rate limit: %f", first.limiter.Limit())
+	}
+}
+
+func TestAccessConfig_stsEndpoint(t *testing.T) {
+	c := testAccessConfig()
+	c.RawRegion = "eu-west-2"
+	c.CustomEndpointOAPI = "outscale.com/oapi/latest"
+	if got := c.stsEndpoint(); got != "https://sts.eu-west-2.outscale.com" {
+		t.Fatalf("bad sts endpoint: %s", got)
+	}
+
+	for _, endpoint := range []string{
+		"https://outscale.com/oapi/latest",
+		"https://api.eu-west-2.outscale.com/api/v1",
+	} {
+		c.CustomEndpointOAPI = endpoint
+		if got := c.stsEndpoint(); got != "https://sts.eu-west-2.outscale.com" {
+			t.Fatalf("bad sts endpoint for %s: %s", endpoint, got)
+		}
+	}
+}
+
+func TestAccessConfig_oosEndpoint(t *testing.T) {
+	c := testAccessConfig()
+	c.RawRegion = "eu-west-2"
+	c.CustomEndpointOAPI = "outscale.com/oapi/latest"
+	if got := c.oosEndpoint("eu-west-2"); got != "https://oos.eu-west-2.outscale.com" {
+		t.Fatalf("bad oos endpoint: %s", got)
+	}
+
+	c.CustomEndpointOAPI = "https://api.us-east-2.outscale.com/api/v1"
+	if got := c.oosEndpoint("us-east-2"); got != "https://oos.us-east-2.outscale.com" {
+		t.Fatalf("bad oos endpoint for a full URL: %s", got)
+	}
+}
+
+func TestAccessConfig_oapiEndpoint(t *testing.T) {
+	c := testAccessConfig()
+	c.CustomEndpointOAPI = "outscale.com/oapi/latest"
+	if got := c.oapiEndpoint("us-east-2"); got != "https://api.us-east-2.outscale.com/oapi/latest" {
+		t.Fatalf("bad oapi endpoint: %s", got)
+	}
+
+	// A full URL of any region reaches the API of the requested region.
+	for _, endpoint := range []string{
+		"https://api.eu-west-2.outscale.com/api/v1",
+		"http://api.cloudgouv-eu-west-1.outscale.com/api/v1",
+	} {
+		c.CustomEndpointOAPI = endpoint
+		if got := c.oapiEndpoint("us-east-2"); got != "https://api.us-east-2.outscale.com/api/v1" {
+			t.Fatalf("bad oapi endpoint for %s: %s", endpoint, got)
+		}
+	}
+
+	// Other full URLs, such as a proxy, are used as is.
+	for _, endpoint := range []string{"http://127.0.0.1:8080", "https://oapi.corp/api/v1"} {
+		c.CustomEndpointOAPI = endpoint
+		if got := c.oapiEndpoint("us-east-2"); got != endpoint {
+			t.Fatalf("bad oapi endpoint for %s: %s", endpoint, got)
+		}
+	}
+}
+
+func TestAccessConfig_endpointFromEnv(t *testing.T) {
+	testConfigFileEnv(t)
+	t.Setenv("OSC_ENDPOINT_API", "https://api.eu-west-2.outscale.com/api/v1")
+
+	c := testAccessConfig()
+	if _, err := c.NewOSCClient(); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	if got := c.NewOSCClientByRegion("us-east-2").GetConfig().BasePath; got != "https://api.us-east-2.outscale.com/api/v1" {
+		t.Fatalf("the copies should reach the API of their region, got %s", got)
+	}
+}
+
+func TestAccessConfig_signsWithRegion(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "{}")
+	}))
+	defer server.Close()
+
+	c := &AccessConfig{AccessKey: "AK", SecretKey: "SK", RawRegion: "eu-west-2", CustomEndpointOAPI: server.URL}
+	client := c.NewOSCClientByRegion("us-east-2").GetConfig().HTTPClient
+	req, err := http.NewRequest("POST", server.URL+"/ReadVms", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("cannot create request: %s", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	resp.Body.Close()
+
+	// The requests to another region are signed for that region.
+	if !strings.Contains(authorization, "/us-east-2/osc/") {
+		t.Fatalf("the request should be signed for us-east-2, got %q", authorization)
+	}
+}
@@ -18,6 +18,18 @@ type Artifact struct {
 	// A map of regions to OMI IDs.
 	Omis map[string]string
 
+	// A map of regions to the IDs of the snapshots of the OMIs.
+	Snapshots map[string][]string
+
+	// Endpoint is the OAPI endpoint the OMIs were built with, such as
+	// outscale.com/oapi/latest.
+	Endpoint string
+
+	// AccessConfig is used to destroy the OMIs. When it is not set, as for
+	// an artifact rebuilt from its state, the credentials are read from the
+	// environment or the profile, and the endpoint from Endpoint.
+	AccessConfig *AccessConfig
+
 	// BuilderId is the unique ID for the builder that created this OMI
 	BuilderIdValue string
 
@@ -62,6 +74,10 @@ func (a *Artifact) State(name string) interface{} {
 	}
 
 	switch name {
+	case "snapshots":
+		return a.Snapshots
+	case "endpoint":
+		return a.Endpoint
 	case "atlas.artifact.metadata":
 		return a.stateAtlasMetadata()
 	case registryimage.ArtifactStateURI:
@@ -71,54 +87,104 @@ func (a *Artifact) State(name string) interface{} {
 	}
 }
 
+// Destroy deletes the OMIs and their snapshots in every region. It goes on
+// after a failure, and reports every error in a MultiError.
 func (a *Artifact) Destroy() error {
-	errors := make([]error, 0)
+	config, err := a.accessConfig()
+	if err != nil {
+		return &packersdk.MultiError{Errors: []error{err}}
+	}
 
-	config := a.State("accessConfig").(*AccessConfig)
+	regions := make([]string, 0, len(a.Omis))
+	for region := range a.Omis {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
 
-	for region, imageId := range a.Omis {
-		log.Printf("Deregistering image ID (%s) from region (%s)", imageId, region)
+	var errs []error
+	for _, region := range regions {
+		errs = append(errs, a.destroyRegion(config.NewOSCClientByRegion(region), region)...)
+	}
 
-		regionConn := config.NewOSCClientByRegion(region)
+	if len(errs) > 0 {
+		return &packersdk.MultiError{Errors: errs}
+	}
+	return nil
+}
 
-		// Get image metadata
-		imageResp, _, err := regionConn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
-			ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
-				Filters: osc.FiltersImage{
-					ImageIds: []string{imageId},
-				},
-			}),
-		})
-		if err != nil {
-			errors = append(errors, err)
-		}
-		if len(imageResp.Images) == 0 {
-			err := fmt.Errorf("Error retrieving details for OMI (%s), no images found", imageId)
-			errors = append(errors, err)
-		}
+// accessConfig returns the AccessConfig of the build or, if the artifact has
+// none, one reading the credentials from the environment or the profile.
+func (a *Artifact) accessConfig() (*AccessConfig, error) {
+	if a.AccessConfig != nil {
+		return a.AccessConfig, nil
+	}
 
-		// Deregister ami
-		input := osc.DeleteImageRequest{
-			ImageId: imageId,
+	config := &AccessConfig{CustomEndpointOAPI: a.Endpoint}
+	for region := range a.Omis {
+		config.RawRegion = region
+		break
+	}
+	if _, err := config.NewOSCClient(); err != nil {
+		return nil, fmt.Errorf("Error creating the client to destroy the OMIs: %s", err)
+	}
+	return config, nil
+}
+
+// destroyRegion deletes the OMI of the region, then the snapshots recorded
+// by the build and the ones of its block device mappings.
+func (a *Artifact) destroyRegion(conn *osc.APIClient, region string) []error {
+	var errs []error
+	imageId := a.Omis[region]
+	snapshotIds := append([]string(nil), a.Snapshots[region]...)
+
+	log.Printf("Deregistering image ID (%s) from region (%s)", imageId, region)
+	imageResp, _, err := conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
+			Filters: osc.FiltersImage{
+				ImageIds: []string{imageId},
+			},
+		}),
+	})
+	switch {
+	case err != nil:
+		errs = append(errs, fmt.Errorf("Error retrieving details for OMI %s in %s: %s", imageId, region, DecodeError(err)))
+	case len(imageResp.Images) == 0:
+		log.Printf("OMI %s not found in %s, deleting its snapshots only", imageId, region)
+	default:
+		for _, mapping := range imageResp.Images[0].BlockDeviceMappings {
+			if mapping.Bsu.SnapshotId != "" {
+				snapshotIds = append(snapshotIds, mapping.Bsu.SnapshotId)
+			}
 		}
-		if _, _, err := regionConn.ImageApi.DeleteImage(context.Background(), &osc.DeleteImageOpts{
-			DeleteImageRequest: optional.NewInterface(input),
+
+		if _, _, err := conn.ImageApi.DeleteImage(context.Background(), &osc.DeleteImageOpts{
+			DeleteImageRequest: optional.NewInterface(osc.DeleteImageRequest{
+				ImageId: imageId,
+			}),
 		}); err != nil {
-			errors = append(errors, err)
+			// The snapshots cannot be deleted while the OMI uses them.
+			return append(errs, fmt.Errorf("Error deleting OMI %s in %s: %s", imageId, region, DecodeError(err)))
 		}
-
-		// TODO: Delete the snapshots associated with an OMI too
 	}
 
-	if len(errors) > 0 {
-		if len(errors) == 1 {
-			return errors[0]
-		} else {
-			return &packersdk.MultiError{Errors: errors}
+	deleted := make(map[string]bool)
+	for _, snapshotId := range snapshotIds {
+		if deleted[snapshotId] {
+			continue
+		}
+		deleted[snapshotId] = true
+
+		log.Printf("Deleting snapshot ID (%s) from region (%s)", snapshotId, region)
+		if _, _, err := conn.SnapshotApi.DeleteSnapshot(context.Background(), &osc.DeleteSnapshotOpts{
+			DeleteSnapshotRequest: optional.NewInterface(osc.DeleteSnapshotRequest{
+				SnapshotId: snapshotId,
+			}),
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("Error deleting snapshot %s in %s: %s", snapshotId, region, DecodeError(err)))
 		}
 	}
 
-	return nil
+	return errs
 }
 
 func (a *Artifact) stateAtlasMetadata() interface{} {
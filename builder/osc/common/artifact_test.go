@@ -1,6 +1,7 @@
 package common
 
 import (
+	"path/filepath"
 	"reflect"
 	"sort"
 	"testing"
@@ -8,6 +9,8 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
 	"github.com/mitchellh/mapstructure"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
 )
 
 func TestArtifact_Impl(t *testing.T) {
@@ -145,3 +148,62 @@ func TestArtifactState_hcpPackerRegistryMetadata(t *testing.T) {
 		t.Fatalf("bad: %#v", images)
 	}
 }
+
+func TestArtifactDestroy(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	west := server.AddImage("eu-west-2", osc.Image{ImageName: "packer"})
+	east := server.AddImage("us-east-2", osc.Image{ImageName: "packer"})
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	artifact := &Artifact{
+		Omis: map[string]string{"eu-west-2": west.ImageId, "us-east-2": east.ImageId},
+		Snapshots: map[string][]string{
+			"eu-west-2": {west.BlockDeviceMappings[0].Bsu.SnapshotId},
+			"us-east-2": {"snap-00000000"},
+		},
+		AccessConfig: testServerConfig(server),
+	}
+
+	err := artifact.Destroy()
+	merr, ok := err.(*packersdk.MultiError)
+	if !ok || len(merr.Errors) != 1 {
+		t.Fatalf("should report the unknown snapshot only, got %v", err)
+	}
+
+	if images := server.Images(); len(images) != 1 || images[0].ImageId != source.ImageId {
+		t.Fatalf("the OMIs should be deleted in every region, got %#v", images)
+	}
+	snapshots := server.Snapshots()
+	if len(snapshots) != 1 || snapshots[0].SnapshotId != source.BlockDeviceMappings[0].Bsu.SnapshotId {
+		t.Fatalf("the snapshots should be deleted in every region, got %#v", snapshots)
+	}
+}
+
+func TestArtifactDestroy_fromState(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	image := server.AddImage("eu-west-2", osc.Image{ImageName: "packer"})
+
+	t.Setenv("OSC_CONFIG_FILE", filepath.Join(t.TempDir(), "config.json"))
+	t.Setenv("OSC_ACCESS_KEY", "AK")
+	t.Setenv("OSC_SECRET_KEY", "SK")
+
+	built := &Artifact{
+		Omis:      map[string]string{"eu-west-2": image.ImageId},
+		Snapshots: map[string][]string{"eu-west-2": {image.BlockDeviceMappings[0].Bsu.SnapshotId}},
+		Endpoint:  server.URL,
+	}
+	artifact := &Artifact{
+		Omis:      built.Omis,
+		Snapshots: built.State("snapshots").(map[string][]string),
+		Endpoint:  built.State("endpoint").(string),
+	}
+
+	if err := artifact.Destroy(); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if images, snapshots := server.Images(), server.Snapshots(); len(images) != 0 || len(snapshots) != 0 {
+		t.Fatalf("the OMI and its snapshot should be deleted, got %#v and %#v", images, snapshots)
+	}
+}
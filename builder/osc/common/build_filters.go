@@ -59,6 +59,30 @@ func buildOscSubnetFilters(input map[string]string) osc.FiltersSubnet {
 	return filters
 }
 
+func buildOscPublicIpFilters(input map[string]string) osc.FiltersPublicIp {
+	var filters osc.FiltersPublicIp
+	for k, v := range input {
+		filterValue := []string{v}
+		switch name := k; name {
+		case "placements":
+			filters.Placements = filterValue
+		case "public-ip-ids":
+			filters.PublicIpIds = filterValue
+		case "public-ips":
+			filters.PublicIps = filterValue
+		case "tag-keys":
+			filters.TagKeys = filterValue
+		case "tag-values":
+			filters.TagValues = filterValue
+		case "tags":
+			filters.Tags = filterValue
+		default:
+			log.Printf("[Debug] Unknown Filter Name: %s.", name)
+		}
+	}
+	return filters
+}
+
 func buildOSCOMIFilters(input map[string]string) osc.FiltersImage {
 	var filters osc.FiltersImage
 	for k, v := range input {
@@ -0,0 +1,203 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sync"
+)
+
+const (
+	cassetteRecord = "record"
+	cassetteReplay = "replay"
+
+	// scrubbed replaces the secrets of the recorded requests and responses.
+	scrubbed = "REDACTED"
+)
+
+// scrubbedFields are the fields of the OAPI requests and responses holding
+// secrets, which are never written to a cassette.
+var scrubbedFields = map[string]bool{
+	"AccessKeyId":   true,
+	"AdminPassword": true,
+	"PrivateKey":    true,
+	"SecretKey":     true,
+	"UserData":      true,
+}
+
+// A cassette holds the OAPI interactions of a build, so that the build can be
+// replayed without network access, for example to re-run acceptance tests in
+// CI.
+//
+// Only the action, the region, the bodies and the status code are recorded:
+// the headers, which hold the signatures, are not, and the secrets of the
+// bodies are scrubbed. When replaying, the calls of each region must come in
+// the order they were recorded in: each request is answered with the next
+// interaction recorded in its region, which must be for the same action. The
+// bodies of the requests are not compared, as they hold generated names.
+type cassette struct {
+	mu       sync.Mutex
+	path     string
+	mode     string
+	replayed []bool
+
+	Interactions []cassetteInteraction `json:"interactions"`
+}
+
+type cassetteInteraction struct {
+	Action     string          `json:"action"`
+	Region     string          `json:"region"`
+	Request    json.RawMessage `json:"request,omitempty"`
+	StatusCode int             `json:"status_code"`
+	Response   json.RawMessage `json:"response,omitempty"`
+}
+
+var (
+	cassettesMu sync.Mutex
+	// cassettes are shared by the transports of every region and client.
+	cassettes = map[string]*cassette{}
+)
+
+// cassetteFromEnv returns the cassette set up by the OSC_CASSETTE_MODE and
+// OSC_CASSETTE environment variables, or nil if there is none.
+func cassetteFromEnv() (*cassette, error) {
+	mode, ok := getValueFromEnvVariables([]string{"OSC_CASSETTE_MODE"})
+	if !ok {
+		return nil, nil
+	}
+	if mode != cassetteRecord && mode != cassetteReplay {
+		return nil, fmt.Errorf("OSC_CASSETTE_MODE must be %q or %q, not %q", cassetteRecord, cassetteReplay, mode)
+	}
+	file, ok := getValueFromEnvVariables([]string{"OSC_CASSETTE"})
+	if !ok {
+		return nil, fmt.Errorf("OSC_CASSETTE must be set to the path of the cassette to %s", mode)
+	}
+	return openCassette(file, mode)
+}
+
+// openCassette returns the cassette of the file. Recording starts from an
+// empty cassette, while replaying reads the file.
+func openCassette(file, mode string) (*cassette, error) {
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+
+	if c, ok := cassettes[file]; ok {
+		if c.mode != mode {
+			return nil, fmt.Errorf("the cassette %s is already open to %s", file, c.mode)
+		}
+		return c, nil
+	}
+
+	c := &cassette{path: file, mode: mode, Interactions: []cassetteInteraction{}}
+	if mode == cassetteReplay {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading cassette: %s", err)
+		}
+		if err := json.Unmarshal(content, c); err != nil {
+			return nil, fmt.Errorf("Error reading cassette %s: %s", file, err)
+		}
+		c.replayed = make([]bool, len(c.Interactions))
+	}
+
+	cassettes[file] = c
+	return c, nil
+}
+
+// record adds the interaction to the cassette, which is saved right away as
+// the plugin may be killed at the end of the build. The body of the response
+// is read and replaced.
+func (c *cassette) record(region string, req *http.Request, body []byte, resp *http.Response) error {
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Interactions = append(c.Interactions, cassetteInteraction{
+		Action:     path.Base(req.URL.Path),
+		Region:     region,
+		Request:    scrub(body),
+		StatusCode: resp.StatusCode,
+		Response:   scrub(respBody),
+	})
+
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, content, 0600)
+}
+
+// replay answers the request with the next interaction recorded in its
+// region, failing if that interaction is for another action.
+func (c *cassette) replay(region string, req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	action := path.Base(req.URL.Path)
+	for i, interaction := range c.Interactions {
+		if c.replayed[i] || interaction.Region != region {
+			continue
+		}
+		if interaction.Action != action {
+			return nil, fmt.Errorf("the cassette %s expected a %s call in %s, got %s", c.path, interaction.Action, region, action)
+		}
+		c.replayed[i] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+			StatusCode:    interaction.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          ioutil.NopCloser(bytes.NewReader(interaction.Response)),
+			ContentLength: int64(len(interaction.Response)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("the cassette %s has no more interaction in %s, got %s", c.path, region, action)
+}
+
+// scrub returns the JSON body with the secrets replaced. Bodies which are not
+// JSON are dropped.
+func scrub(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil
+	}
+	scrubbedBody, err := json.Marshal(scrubValue(value))
+	if err != nil {
+		return nil
+	}
+	return scrubbedBody
+}
+
+func scrubValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if _, ok := field.(string); ok && scrubbedFields[key] {
+				v[key] = scrubbed
+				continue
+			}
+			v[key] = scrubValue(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scrubValue(item)
+		}
+	}
+	return value
+}
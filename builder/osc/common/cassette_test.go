@@ -0,0 +1,184 @@
+package common
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antihax/optional"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+// runCassetteCalls runs the calls recorded and replayed by the cassette tests.
+func runCassetteCalls(t *testing.T, conn *osc.APIClient) (osc.KeypairCreated, []osc.Image, error) {
+	keypairResp, _, err := conn.KeypairApi.CreateKeypair(context.Background(), &osc.CreateKeypairOpts{
+		CreateKeypairRequest: optional.NewInterface(osc.CreateKeypairRequest{KeypairName: "packer"}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", DecodeError(err))
+	}
+
+	imagesResp, _, err := conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", DecodeError(err))
+	}
+
+	_, _, err = conn.VmApi.StopVms(context.Background(), &osc.StopVmsOpts{
+		StopVmsRequest: optional.NewInterface(osc.StopVmsRequest{VmIds: []string{"i-12345678"}}),
+	})
+	return keypairResp.Keypair, imagesResp.Images, err
+}
+
+func TestCassette_recordReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cassette.json")
+	server := oapitest.NewServer()
+	server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	config := &AccessConfig{
+		AccessKey:          "AKRECORD",
+		SecretKey:          "SKRECORD",
+		RawRegion:          "eu-west-2",
+		CustomEndpointOAPI: server.URL,
+	}
+	cassette, err := openCassette(file, cassetteRecord)
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	config.cassette = cassette
+
+	keypair, images, err := runCassetteCalls(t, config.NewOSCClientByRegion("eu-west-2"))
+	if !IsNotFoundError(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if !strings.Contains(keypair.PrivateKey, "PRIVATE KEY") {
+		t.Fatalf("the recorded call should return the private key, got %q", keypair.PrivateKey)
+	}
+	server.Close()
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	for _, secret := range []string{"PRIVATE KEY", "AKRECORD", "SKRECORD", "Authorization", "Signature"} {
+		if strings.Contains(string(content), secret) {
+			t.Fatalf("the cassette shouldn't hold %q:\n%s", secret, content)
+		}
+	}
+
+	// Replay from another build, without the server.
+	config = &AccessConfig{
+		AccessKey:          "AKREPLAY",
+		SecretKey:          "SKREPLAY",
+		RawRegion:          "eu-west-2",
+		CustomEndpointOAPI: server.URL,
+	}
+	cassettesMu.Lock()
+	delete(cassettes, file)
+	cassettesMu.Unlock()
+	if config.cassette, err = openCassette(file, cassetteReplay); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	conn := config.NewOSCClientByRegion("eu-west-2")
+
+	replayedKeypair, replayedImages, err := runCassetteCalls(t, conn)
+	if !IsNotFoundError(err) {
+		t.Fatalf("expected the recorded not found error, got %v", err)
+	}
+	if replayedKeypair.KeypairName != keypair.KeypairName || replayedKeypair.PrivateKey != scrubbed {
+		t.Fatalf("bad replayed keypair: %#v", replayedKeypair)
+	}
+	if len(replayedImages) != 1 || replayedImages[0].ImageId != images[0].ImageId {
+		t.Fatalf("bad replayed images: %#v", replayedImages)
+	}
+
+	// Every interaction has been replayed.
+	_, _, err = conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{}),
+	})
+	if err == nil || !strings.Contains(err.Error(), "no more interaction in eu-west-2") {
+		t.Fatalf("expected the cassette to be exhausted, got %v", err)
+	}
+
+	// Interactions are replayed for the region they were recorded in.
+	_, _, err = config.NewOSCClientByRegion("us-east-2").KeypairApi.CreateKeypair(context.Background(), &osc.CreateKeypairOpts{
+		CreateKeypairRequest: optional.NewInterface(osc.CreateKeypairRequest{KeypairName: "packer"}),
+	})
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestCassette_replayOrder(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cassette.json")
+	content := `{"interactions": [
+  {"action": "CreateKeypair", "region": "eu-west-2", "status_code": 200, "response": {"Keypair": {"KeypairName": "packer"}}},
+  {"action": "ReadImages", "region": "eu-west-2", "status_code": 200, "response": {"Images": []}}
+]}`
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatalf("cannot write cassette: %s", err)
+	}
+
+	config := &AccessConfig{AccessKey: "AK", SecretKey: "SK", RawRegion: "eu-west-2", CustomEndpointOAPI: "http://127.0.0.1:1"}
+	var err error
+	if config.cassette, err = openCassette(file, cassetteReplay); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	conn := config.NewOSCClientByRegion("eu-west-2")
+
+	// The calls must be replayed in the order they were recorded in.
+	_, _, err = conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{}),
+	})
+	if err == nil || !strings.Contains(err.Error(), "expected a CreateKeypair call") {
+		t.Fatalf("expected an out of order error, got %v", err)
+	}
+
+	if _, _, err := conn.KeypairApi.CreateKeypair(context.Background(), &osc.CreateKeypairOpts{
+		CreateKeypairRequest: optional.NewInterface(osc.CreateKeypairRequest{KeypairName: "packer"}),
+	}); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if _, _, err := conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{}),
+	}); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+}
+
+func TestCassetteFromEnv(t *testing.T) {
+	t.Setenv("OSC_CASSETTE_MODE", "")
+	if c, err := cassetteFromEnv(); c != nil || err != nil {
+		t.Fatalf("there should be no cassette, got %v, %v", c, err)
+	}
+
+	t.Setenv("OSC_CASSETTE_MODE", "rewind")
+	if _, err := cassetteFromEnv(); err == nil {
+		t.Fatal("should have error")
+	}
+
+	t.Setenv("OSC_CASSETTE_MODE", cassetteReplay)
+	t.Setenv("OSC_CASSETTE", "")
+	if _, err := cassetteFromEnv(); err == nil {
+		t.Fatal("should have error")
+	}
+
+	t.Setenv("OSC_CASSETTE", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := cassetteFromEnv(); err == nil {
+		t.Fatal("replaying a missing cassette should fail")
+	}
+}
+
+func TestScrub(t *testing.T) {
+	body := scrub([]byte(`{"Vms":[{"VmId":"i-1","UserData":"c2VjcmV0"}],"AdminPassword":"secret"}`))
+	if expected := `{"AdminPassword":"REDACTED","Vms":[{"UserData":"REDACTED","VmId":"i-1"}]}`; string(body) != expected {
+		t.Fatalf("expected %s, got %s", expected, body)
+	}
+	if body := scrub([]byte("not json")); body != nil {
+		t.Fatalf("bodies which are not JSON should be dropped, got %s", body)
+	}
+}
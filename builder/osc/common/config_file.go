@@ -0,0 +1,93 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const defaultProfileName = "default"
+
+// oscEndpoints holds the service endpoints of a profile.
+type oscEndpoints struct {
+	API string `json:"api"`
+}
+
+// oscProfile is a profile of the Outscale configuration file, as written by
+// the Outscale CLIs.
+type oscProfile struct {
+	AccessKey      string       `json:"access_key"`
+	SecretKey      string       `json:"secret_key"`
+	Region         string       `json:"region"`
+	Endpoints      oscEndpoints `json:"endpoints"`
+	X509ClientCert string       `json:"x509_client_cert"`
+	X509ClientKey  string       `json:"x509_client_key"`
+}
+
+// configFilePath returns the path of the Outscale configuration file:
+// OSC_CONFIG_FILE if set, ~/.osc/config.json otherwise.
+func configFilePath() (string, error) {
+	if path, ok := getValueFromEnvVariables([]string{"OSC_CONFIG_FILE"}); ok {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".osc", "config.json"), nil
+}
+
+// loadProfile reads the profile named by the `profile` option, or by
+// OSC_PROFILE, from the Outscale configuration file. When no profile has been
+// asked for explicitly, a missing file or a missing `default` profile is not
+// an error and an empty profile is returned.
+func (c *AccessConfig) loadProfile() (*oscProfile, error) {
+	name := c.ProfileName
+	if name == "" {
+		name, _ = getValueFromEnvVariables([]string{"OSC_PROFILE"})
+	}
+	explicit := name != ""
+	if !explicit {
+		name = defaultProfileName
+	}
+
+	path, err := configFilePath()
+	if err != nil {
+		if explicit {
+			return nil, fmt.Errorf("Error locating the Outscale configuration file: %s", err)
+		}
+		return &oscProfile{}, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if explicit || !os.IsNotExist(err) {
+			return nil, fmt.Errorf("Error reading the Outscale configuration file %s: %s", path, err)
+		}
+		return &oscProfile{}, nil
+	}
+
+	profiles := make(map[string]oscProfile)
+	if err := json.Unmarshal(content, &profiles); err != nil {
+		return nil, fmt.Errorf("Error parsing the Outscale configuration file %s: %s", path, err)
+	}
+
+	profile, ok := profiles[name]
+	if !ok {
+		if explicit {
+			return nil, fmt.Errorf("Profile %q not found in the Outscale configuration file %s", name, path)
+		}
+		return &oscProfile{}, nil
+	}
+
+	return &profile, nil
+}
+
+// customEndpoint converts the API endpoint of the profile, such as
+// `https://api.eu-west-2.outscale.com/api/v1`, into the form expected by
+// `custom_endpoint_oapi` (see normalizeEndpoint).
+func (p *oscProfile) customEndpoint() string {
+	return normalizeEndpoint(p.Endpoints.API)
+}
@@ -0,0 +1,175 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// OAPIError is an error returned by the OUTSCALE API, decoded from the body
+// of the response.
+type OAPIError struct {
+	// The HTTP status code of the response.
+	StatusCode int
+	// The code, type and details of the first error of the response.
+	Code    string
+	Type    string
+	Details string
+	// The ID of the request, to be given to the support.
+	RequestId string
+
+	err error
+}
+
+func (e *OAPIError) Error() string {
+	var b strings.Builder
+	if e.Type != "" {
+		b.WriteString(e.Type)
+	} else {
+		b.WriteString(http.StatusText(e.StatusCode))
+	}
+	if e.Code != "" {
+		fmt.Fprintf(&b, " (%s)", e.Code)
+	}
+	if e.Details != "" {
+		fmt.Fprintf(&b, ": %s", e.Details)
+	}
+	fmt.Fprintf(&b, " [HTTP %d", e.StatusCode)
+	if e.RequestId != "" {
+		fmt.Fprintf(&b, ", request %s", e.RequestId)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+func (e *OAPIError) Unwrap() error {
+	return e.err
+}
+
+// IsNotFound reports whether the resource of the request does not exist, or
+// does not exist yet since the API is eventually consistent.
+func (e *OAPIError) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound ||
+		e.Type == "InvalidResource" ||
+		strings.HasSuffix(e.Code, "NotFound")
+}
+
+// IsThrottled reports whether the request was rejected because of the rate
+// limit of the account.
+func (e *OAPIError) IsThrottled() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode == http.StatusServiceUnavailable ||
+		isThrottlingType(e.Type)
+}
+
+// isThrottlingType reports whether the type of an OAPI error is one of the
+// rate limit of the account.
+func isThrottlingType(errorType string) bool {
+	return errorType == "RequestLimitExceeded" || errorType == "Throttling"
+}
+
+// IsConflict reports whether the request conflicts with the current state of
+// the resource, e.g. a volume still being linked.
+func (e *OAPIError) IsConflict() bool {
+	return e.StatusCode == http.StatusConflict ||
+		e.Type == "ResourceConflict" ||
+		e.Type == "InvalidState"
+}
+
+// IsCapacity reports whether the request failed for lack of capacity, so that
+// it may succeed with another VM type or subregion. A quota of the account
+// (TooManyResources) applies to every VM type and subregion, and is not a
+// capacity error.
+func (e *OAPIError) IsCapacity() bool {
+	return e.Type == "InsufficientCapacity"
+}
+
+// IsUnsupported reports whether the request asked for something the region
+// or the subregion does not provide, such as a VM type.
+func (e *OAPIError) IsUnsupported() bool {
+	return strings.HasPrefix(e.Type, "Unsupported")
+}
+
+// DecodeError decodes the body of the errors returned by the osc-sdk-go into
+// an *OAPIError. Other errors are returned unchanged.
+func DecodeError(err error) error {
+	if e, ok := AsOAPIError(err); ok {
+		return e
+	}
+	return err
+}
+
+// AsOAPIError returns the *OAPIError matching err, decoding it if needed.
+func AsOAPIError(err error) (*OAPIError, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	var oapiErr *OAPIError
+	if errors.As(err, &oapiErr) {
+		return oapiErr, true
+	}
+
+	var genericErr osc.GenericOpenAPIError
+	if !errors.As(err, &genericErr) {
+		return nil, false
+	}
+
+	// The SDK only keeps the status line, such as "400 Bad Request".
+	status, _ := strconv.Atoi(strings.SplitN(genericErr.Error(), " ", 2)[0])
+	if status == 0 {
+		// The response could not be decoded at all.
+		return nil, false
+	}
+
+	oapiErr = &OAPIError{StatusCode: status, err: err}
+
+	response, ok := genericErr.Model().(osc.ErrorResponse)
+	if !ok {
+		_ = json.Unmarshal(genericErr.Body(), &response)
+	}
+	oapiErr.RequestId = response.ResponseContext.RequestId
+	if len(response.Errors) > 0 {
+		oapiErr.Code = response.Errors[0].Code
+		oapiErr.Type = response.Errors[0].Type
+		oapiErr.Details = response.Errors[0].Details
+	}
+
+	return oapiErr, true
+}
+
+// IsNotFoundError reports whether err is an OAPI not-found error.
+func IsNotFoundError(err error) bool {
+	e, ok := AsOAPIError(err)
+	return ok && e.IsNotFound()
+}
+
+// IsThrottledError reports whether err is an OAPI throttling error.
+func IsThrottledError(err error) bool {
+	e, ok := AsOAPIError(err)
+	return ok && e.IsThrottled()
+}
+
+// IsConflictError reports whether err is an OAPI conflict error.
+func IsConflictError(err error) bool {
+	e, ok := AsOAPIError(err)
+	return ok && e.IsConflict()
+}
+
+// IsCapacityError reports whether err is an OAPI capacity error.
+func IsCapacityError(err error) bool {
+	e, ok := AsOAPIError(err)
+	return ok && e.IsCapacity()
+}
+
+// IsUnsupportedError reports whether err is an OAPI error about something the
+// region or the subregion does not provide.
+func IsUnsupportedError(err error) bool {
+	e, ok := AsOAPIError(err)
+	return ok && e.IsUnsupported()
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/antihax/optional"
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// testAPIError returns the error of a StopVms call answered with the given
+// status and body.
+func testAPIError(t *testing.T, status int, body string) error {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	conn := osc.NewAPIClient(&osc.Configuration{
+		BasePath:      server.URL,
+		DefaultHeader: make(map[string]string),
+		HTTPClient:    server.Client(),
+	})
+	_, _, err := conn.VmApi.StopVms(context.Background(), &osc.StopVmsOpts{
+		StopVmsRequest: optional.NewInterface(osc.StopVmsRequest{VmIds: []string{"i-12345"}}),
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	return err
+}
+
+func TestDecodeError(t *testing.T) {
+	err := testAPIError(t, http.StatusBadRequest, `{
+		"Errors": [{"Code": "5063", "Type": "InvalidResource", "Details": "The VmId 'i-12345' doesn't exist."}],
+		"ResponseContext": {"RequestId": "0475ca1e-d0c5-441d-712a-da55a4175157"}
+	}`)
+
+	oapiErr, ok := AsOAPIError(err)
+	if !ok {
+		t.Fatalf("should decode %#v", err)
+	}
+	if oapiErr.StatusCode != 400 || oapiErr.Code != "5063" || oapiErr.Type != "InvalidResource" ||
+		oapiErr.RequestId != "0475ca1e-d0c5-441d-712a-da55a4175157" {
+		t.Fatalf("bad decoded error: %#v", oapiErr)
+	}
+
+	message := DecodeError(err).Error()
+	for _, part := range []string{"InvalidResource", "5063", "i-12345", "0475ca1e"} {
+		if !strings.Contains(message, part) {
+			t.Fatalf("message %q should contain %q", message, part)
+		}
+	}
+
+	if !IsNotFoundError(err) || IsThrottledError(err) || IsConflictError(err) || IsCapacityError(err) {
+		t.Fatalf("should only be classified as not found: %s", message)
+	}
+
+	wrapped := fmt.Errorf("stopping: %w", DecodeError(err))
+	if !IsNotFoundError(wrapped) {
+		t.Fatal("should classify a wrapped error")
+	}
+}
+
+func TestDecodeError_classes(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		check  func(error) bool
+	}{
+		{http.StatusServiceUnavailable, `{"Errors": [{"Code": "10", "Type": "RequestLimitExceeded"}]}`, IsThrottledError},
+		{http.StatusTooManyRequests, `{}`, IsThrottledError},
+		{http.StatusConflict, `{"Errors": [{"Code": "9029", "Type": "ResourceConflict"}]}`, IsConflictError},
+		{http.StatusBadRequest, `{"Errors": [{"Code": "10001", "Type": "InsufficientCapacity"}]}`, IsCapacityError},
+		{http.StatusBadRequest, `{"Errors": [{"Code": "8018", "Type": "UnsupportedOperation"}]}`, IsUnsupportedError},
+	}
+
+	for _, tc := range cases {
+		err := testAPIError(t, tc.status, tc.body)
+		if !tc.check(err) {
+			t.Errorf("%d %s: not classified as expected: %s", tc.status, tc.body, DecodeError(err))
+		}
+	}
+}
+
+func TestDecodeError_quotaIsNotCapacity(t *testing.T) {
+	err := testAPIError(t, http.StatusBadRequest, `{"Errors": [{"Code": "10023", "Type": "TooManyResources (QuotaExceded)"}]}`)
+	if IsCapacityError(err) || IsUnsupportedError(err) {
+		t.Fatalf("a quota error should not be retried on another VM type or subregion: %s", DecodeError(err))
+	}
+}
+
+func TestDecodeError_other(t *testing.T) {
+	err := errors.New("connection refused")
+	if DecodeError(err) != err {
+		t.Fatal("should leave other errors unchanged")
+	}
+	if IsNotFoundError(err) || IsThrottledError(nil) {
+		t.Fatal("should not classify other errors")
+	}
+}
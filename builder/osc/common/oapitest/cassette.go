@@ -0,0 +1,55 @@
+package oapitest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// CassetteDir is the directory, relative to the package of the test, where
+// the cassettes of the acceptance tests are kept.
+const CassetteDir = "testdata/cassettes"
+
+// UseCassette makes the plugin started by the acceptance test record its OAPI
+// calls to, or replay them from, the cassette of the given name, when the
+// OSC_CASSETTE_MODE environment variable is set to record or replay. It
+// returns the JSON template to build.
+//
+// The connection to the build VM cannot be replayed, so that the builders of
+// the template are set to use no communicator, both when recording and when
+// replaying. Replaying skips the test if its cassette has not been recorded.
+func UseCassette(t *testing.T, name, template string) string {
+	mode := os.Getenv("OSC_CASSETTE_MODE")
+	if mode == "" {
+		return template
+	}
+
+	path, err := filepath.Abs(filepath.Join(CassetteDir, name+".json"))
+	if err != nil {
+		t.Fatalf("cannot resolve the cassette path: %s", err)
+	}
+	if _, err := os.Stat(path); mode == "replay" && os.IsNotExist(err) {
+		t.Skipf("no cassette recorded for %s, run make testacc-record first", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("cannot create the cassette directory: %s", err)
+	}
+	t.Setenv("OSC_CASSETTE", path)
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(template), &parsed); err != nil {
+		t.Fatalf("cannot parse the template: %s", err)
+	}
+	builders, _ := parsed["builders"].([]interface{})
+	for _, builder := range builders {
+		if builder, ok := builder.(map[string]interface{}); ok {
+			builder["communicator"] = "none"
+		}
+	}
+	rewritten, err := json.Marshal(parsed)
+	if err != nil {
+		t.Fatalf("cannot write the template: %s", err)
+	}
+	return string(rewritten)
+}
@@ -0,0 +1,62 @@
+package oapitest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+func TestUseCassette(t *testing.T) {
+	template := `{"builders": [{"type": "outscale-bsu", "ssh_username": "outscale"}]}`
+
+	t.Setenv("OSC_CASSETTE_MODE", "")
+	if got := oapitest.UseCassette(t, "test", template); got != template {
+		t.Fatalf("the template shouldn't change without cassette, got %s", got)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	t.Setenv("OSC_CASSETTE_MODE", "record")
+	got := oapitest.UseCassette(t, "test", template)
+	if expected := `{"builders":[{"communicator":"none","ssh_username":"outscale","type":"outscale-bsu"}]}`; got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+
+	path := os.Getenv("OSC_CASSETTE")
+	if filepath.Base(path) != "test.json" || !filepath.IsAbs(path) {
+		t.Fatalf("bad cassette path: %s", path)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); err != nil {
+		t.Fatalf("the cassette directory should be created: %s", err)
+	}
+}
+
+func TestUseCassette_missingReplay(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	t.Setenv("OSC_CASSETTE_MODE", "replay")
+	skipped := true
+	t.Run("replay", func(t *testing.T) {
+		oapitest.UseCassette(t, "missing", `{"builders": []}`)
+		skipped = false
+	})
+	if !skipped {
+		t.Fatal("replaying without a cassette should skip the test")
+	}
+}
@@ -0,0 +1,64 @@
+package oapitest
+
+import (
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// vmTypes are the predefined VM types listed by ReadVmTypes. The tinavX
+// types built from a vCore count, a memory size and a performance are not
+// listed.
+var vmTypes = []osc.VmType{
+	{VmTypeName: "t2.small", VcoreCount: 1, MemorySize: 2, MaxPrivateIps: 4},
+	{VmTypeName: "t2.medium", VcoreCount: 2, MemorySize: 4, MaxPrivateIps: 6},
+	{VmTypeName: "m4.large", VcoreCount: 2, MemorySize: 8, MaxPrivateIps: 10, BsuOptimized: true},
+	{VmTypeName: "c4.large", VcoreCount: 2, MemorySize: 3.75, MaxPrivateIps: 10, BsuOptimized: true},
+}
+
+// subregionSuffixes are the suffixes of the Subregions of every region.
+var subregionSuffixes = []string{"a", "b", "c"}
+
+func (s *Server) readVmTypes(region string, body []byte) (interface{}, error) {
+	var req osc.ReadVmTypesRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadVmTypesResponse{ResponseContext: s.responseContext(), VmTypes: []osc.VmType{}}
+	for _, vmType := range vmTypes {
+		if matches(f.VmTypeNames, vmType.VmTypeName) {
+			resp.VmTypes = append(resp.VmTypes, vmType)
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) readSubregions(region string, body []byte) (interface{}, error) {
+	var req osc.ReadSubregionsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadSubregionsResponse{ResponseContext: s.responseContext(), Subregions: []osc.Subregion{}}
+	for _, suffix := range subregionSuffixes {
+		subregion := osc.Subregion{RegionName: region, State: "available", SubregionName: region + suffix}
+		if matches(f.SubregionNames, subregion.SubregionName) {
+			resp.Subregions = append(resp.Subregions, subregion)
+		}
+	}
+	return resp, nil
+}
+
+// capacity is a VM type in a Subregion, either being empty for any.
+type capacity struct {
+	vmType, subregion string
+}
+
+// RemoveCapacity makes the launch of VMs of the type in the Subregion fail
+// for lack of capacity. An empty VM type or Subregion stands for any.
+func (s *Server) RemoveCapacity(vmType, subregion string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noCapacity[capacity{vmType, subregion}] = true
+}
@@ -0,0 +1,71 @@
+package oapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// apiError is answered as an osc.ErrorResponse.
+type apiError struct {
+	status int
+	osc.Errors
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s (%s): %s", e.Type, e.Code, e.Details)
+}
+
+func errNotFound(kind, id string) error {
+	return &apiError{status: http.StatusBadRequest, Errors: osc.Errors{
+		Code: "5063", Type: "InvalidResource", Details: fmt.Sprintf("The %s '%s' doesn't exist.", kind, id),
+	}}
+}
+
+func errMissing(parameter string) error {
+	return &apiError{status: http.StatusBadRequest, Errors: osc.Errors{
+		Code: "7000", Type: "MissingParameter", Details: fmt.Sprintf("Parameter cannot be empty: %s", parameter),
+	}}
+}
+
+func errInvalid(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusBadRequest, Errors: osc.Errors{
+		Code: "4045", Type: "InvalidParameterValue", Details: fmt.Sprintf(format, args...),
+	}}
+}
+
+func errConflict(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusConflict, Errors: osc.Errors{
+		Code: "9011", Type: "ResourceConflict", Details: fmt.Sprintf(format, args...),
+	}}
+}
+
+func errCapacity(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusBadRequest, Errors: osc.Errors{
+		Code: "10001", Type: "InsufficientCapacity", Details: fmt.Sprintf(format, args...),
+	}}
+}
+
+func errState(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusConflict, Errors: osc.Errors{
+		Code: "6003", Type: "InvalidState", Details: fmt.Sprintf(format, args...),
+	}}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*apiError)
+	if !ok {
+		e = &apiError{status: http.StatusInternalServerError, Errors: osc.Errors{
+			Code: "2000", Type: "InternalError", Details: err.Error(),
+		}}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.status)
+	json.NewEncoder(w).Encode(osc.ErrorResponse{
+		Errors:          []osc.Errors{e.Errors},
+		ResponseContext: osc.ResponseContext{RequestId: "00000000-0000-4000-8000-000000000000"},
+	})
+}
@@ -0,0 +1,290 @@
+package oapitest
+
+import (
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func (s *Server) createImage(region string, body []byte) (interface{}, error) {
+	var req osc.CreateImageRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.ImageName == "" {
+		return nil, errMissing("ImageName")
+	}
+	for _, id := range s.ids("ami", region) {
+		if s.images[id].ImageName == req.ImageName {
+			return nil, errConflict("The ImageName '%s' is already used by the ImageId '%s'.", req.ImageName, id)
+		}
+	}
+
+	image := &osc.Image{
+		AccountId:      AccountId,
+		Architecture:   req.Architecture,
+		CreationDate:   now(),
+		Description:    req.Description,
+		FileLocation:   req.FileLocation,
+		ImageName:      req.ImageName,
+		ImageType:      "machine",
+		RootDeviceName: req.RootDeviceName,
+		RootDeviceType: "bsu",
+		State:          "pending",
+	}
+
+	switch {
+	case req.VmId != "":
+		vm, err := s.vm(region, req.VmId)
+		if err != nil {
+			return nil, err
+		}
+		if vm.State != "running" && vm.State != "stopped" {
+			return nil, errState("The VmId '%s' is in the '%s' state.", vm.VmId, vm.State)
+		}
+		if image.Architecture == "" {
+			image.Architecture = vm.Architecture
+		}
+		if image.RootDeviceName == "" {
+			image.RootDeviceName = vm.RootDeviceName
+		}
+		image.ProductCodes = vm.ProductCodes
+
+		for _, mapping := range vm.BlockDeviceMappings {
+			volume := s.volumes[mapping.Bsu.VolumeId]
+			snapshot := s.newSnapshot(region, volume.VolumeId, volume.Size, "Created by CreateImage for "+req.ImageName)
+			image.BlockDeviceMappings = append(image.BlockDeviceMappings, osc.BlockDeviceMappingImage{
+				DeviceName: mapping.DeviceName,
+				Bsu: osc.BsuToCreate{
+					DeleteOnVmDeletion: mapping.Bsu.DeleteOnVmDeletion,
+					Iops:               volume.Iops,
+					SnapshotId:         snapshot.SnapshotId,
+					VolumeSize:         volume.Size,
+					VolumeType:         volume.VolumeType,
+				},
+			})
+		}
+		for _, mapping := range req.BlockDeviceMappings {
+			if !hasDevice(image.BlockDeviceMappings, mapping.DeviceName) {
+				image.BlockDeviceMappings = append(image.BlockDeviceMappings, mapping)
+			}
+		}
+	case req.SourceImageId != "":
+		if req.SourceRegionName == "" {
+			return nil, errMissing("SourceRegionName")
+		}
+		source, err := s.image(req.SourceRegionName, req.SourceImageId)
+		if err != nil {
+			return nil, err
+		}
+		if image.Architecture == "" {
+			image.Architecture = source.Architecture
+		}
+		if image.RootDeviceName == "" {
+			image.RootDeviceName = source.RootDeviceName
+		}
+		if image.Description == "" {
+			image.Description = source.Description
+		}
+		image.ProductCodes = source.ProductCodes
+
+		// The snapshots are copied along with the image.
+		for _, mapping := range source.BlockDeviceMappings {
+			if mapping.Bsu.SnapshotId != "" {
+				snapshot := s.newSnapshot(region, "", mapping.Bsu.VolumeSize, "Copied by CreateImage for "+req.ImageName)
+				mapping.Bsu.SnapshotId = snapshot.SnapshotId
+			}
+			image.BlockDeviceMappings = append(image.BlockDeviceMappings, mapping)
+		}
+	case req.FileLocation != "":
+	default:
+		if len(req.BlockDeviceMappings) == 0 {
+			return nil, errMissing("VmId")
+		}
+		if image.RootDeviceName == "" {
+			return nil, errMissing("RootDeviceName")
+		}
+		for _, mapping := range req.BlockDeviceMappings {
+			if mapping.Bsu.SnapshotId == "" {
+				continue
+			}
+			snapshot, err := s.snapshot(region, mapping.Bsu.SnapshotId)
+			if err != nil {
+				return nil, err
+			}
+			if mapping.Bsu.VolumeSize == 0 {
+				mapping.Bsu.VolumeSize = snapshot.VolumeSize
+			}
+			image.BlockDeviceMappings = append(image.BlockDeviceMappings, mapping)
+		}
+		if !hasDevice(image.BlockDeviceMappings, image.RootDeviceName) {
+			return nil, errInvalid("No block device mapping for the root device '%s'.", image.RootDeviceName)
+		}
+	}
+
+	if image.Architecture == "" {
+		image.Architecture = "x86_64"
+	}
+	if image.RootDeviceName == "" {
+		image.RootDeviceName = "/dev/sda1"
+	}
+
+	image.ImageId = s.newID("ami", region)
+	s.images[image.ImageId] = image
+	s.transition(image.ImageId, func() { image.State = "available" })
+
+	return osc.CreateImageResponse{ResponseContext: s.responseContext(), Image: *image}, nil
+}
+
+func hasDevice(mappings []osc.BlockDeviceMappingImage, deviceName string) bool {
+	for _, mapping := range mappings {
+		if mapping.DeviceName == deviceName {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) readImages(region string, body []byte) (interface{}, error) {
+	var req osc.ReadImagesRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadImagesResponse{ResponseContext: s.responseContext(), Images: []osc.Image{}}
+	for _, id := range s.ids("ami", region) {
+		image := s.images[id]
+
+		var snapshotIds []string
+		for _, mapping := range image.BlockDeviceMappings {
+			snapshotIds = append(snapshotIds, mapping.Bsu.SnapshotId)
+		}
+
+		if !matches(f.ImageIds, id) || !matches(f.ImageNames, image.ImageName) ||
+			!matches(f.AccountIds, image.AccountId) || !matches(f.AccountAliases, image.AccountAlias) ||
+			!matches(f.Architectures, image.Architecture) || !matches(f.Descriptions, image.Description) ||
+			!matches(f.RootDeviceNames, image.RootDeviceName) || !matches(f.RootDeviceTypes, image.RootDeviceType) ||
+			!matches(f.VirtualizationTypes, "hvm") || !matchesAny(f.ProductCodes, image.ProductCodes) ||
+			!matchesAny(f.BlockDeviceMappingSnapshotIds, snapshotIds) ||
+			!matchesAny(f.PermissionsToLaunchAccountIds, image.PermissionsToLaunch.AccountIds) ||
+			!s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+		s.observe(id)
+		if !matches(f.States, image.State) {
+			continue
+		}
+
+		v := *image
+		v.Tags = s.tagsOf(id)
+		resp.Images = append(resp.Images, v)
+	}
+	return resp, nil
+}
+
+func (s *Server) updateImage(region string, body []byte) (interface{}, error) {
+	var req osc.UpdateImageRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	image, err := s.image(region, req.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	if image.AccountId != AccountId {
+		return nil, errInvalid("The ImageId '%s' is not owned by the account.", image.ImageId)
+	}
+	image.PermissionsToLaunch = updatePermissions(image.PermissionsToLaunch, req.PermissionsToLaunch)
+
+	return osc.UpdateImageResponse{ResponseContext: s.responseContext(), Image: *image}, nil
+}
+
+func (s *Server) deleteImage(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteImageRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	image, err := s.image(region, req.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	if image.AccountId != AccountId {
+		return nil, errInvalid("The ImageId '%s' is not owned by the account.", image.ImageId)
+	}
+
+	delete(s.images, image.ImageId)
+	s.forget(image.ImageId)
+	return osc.DeleteImageResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) image(region, id string) (*osc.Image, error) {
+	image, ok := s.images[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("ImageId", id)
+	}
+	return image, nil
+}
+
+// AddImage adds an available image to the region, such as a source OMI. The
+// image is given an ID and, if it has no block device mappings, a 10 GiB root
+// snapshot. It is owned by the account unless AccountId is set.
+func (s *Server) AddImage(region string, image osc.Image) osc.Image {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if image.AccountId == "" {
+		image.AccountId = AccountId
+	}
+	if image.Architecture == "" {
+		image.Architecture = "x86_64"
+	}
+	if image.RootDeviceName == "" {
+		image.RootDeviceName = "/dev/sda1"
+	}
+	if image.RootDeviceType == "" {
+		image.RootDeviceType = "bsu"
+	}
+	if image.ImageType == "" {
+		image.ImageType = "machine"
+	}
+	if image.CreationDate == "" {
+		image.CreationDate = now()
+	}
+	if len(image.BlockDeviceMappings) == 0 {
+		snapshot := s.newSnapshot(region, "", 10, "")
+		delete(s.transitions, snapshot.SnapshotId)
+		snapshot.State = "completed"
+		snapshot.Progress = 100
+		snapshot.AccountId = image.AccountId
+		image.BlockDeviceMappings = []osc.BlockDeviceMappingImage{{
+			DeviceName: image.RootDeviceName,
+			Bsu: osc.BsuToCreate{
+				DeleteOnVmDeletion: true,
+				SnapshotId:         snapshot.SnapshotId,
+				VolumeSize:         snapshot.VolumeSize,
+				VolumeType:         "standard",
+			},
+		}}
+	}
+	image.State = "available"
+	image.ImageId = s.newID("ami", region)
+	s.tags[image.ImageId] = image.Tags
+
+	s.images[image.ImageId] = &image
+	return image
+}
+
+// Images returns the images of every region.
+func (s *Server) Images() []osc.Image {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var images []osc.Image
+	for _, id := range s.ids("ami", "") {
+		image := *s.images[id]
+		image.Tags = s.tagsOf(id)
+		images = append(images, image)
+	}
+	return images
+}
@@ -0,0 +1,132 @@
+package oapitest
+
+import (
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func (s *Server) createInternetService(region string, body []byte) (interface{}, error) {
+	var req osc.CreateInternetServiceRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	service := &osc.InternetService{InternetServiceId: s.newID("igw", region)}
+	s.internetServices[service.InternetServiceId] = service
+
+	return osc.CreateInternetServiceResponse{ResponseContext: s.responseContext(), InternetService: *service}, nil
+}
+
+func (s *Server) readInternetServices(region string, body []byte) (interface{}, error) {
+	var req osc.ReadInternetServicesRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadInternetServicesResponse{ResponseContext: s.responseContext(), InternetServices: []osc.InternetService{}}
+	for _, id := range s.ids("igw", region) {
+		service := s.internetServices[id]
+		if !matches(f.InternetServiceIds, id) || !matches(f.LinkNetIds, service.NetId) ||
+			!matches(f.LinkStates, service.State) || !s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+
+		v := *service
+		v.Tags = s.tagsOf(id)
+		resp.InternetServices = append(resp.InternetServices, v)
+	}
+	return resp, nil
+}
+
+func (s *Server) linkInternetService(region string, body []byte) (interface{}, error) {
+	var req osc.LinkInternetServiceRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	service, err := s.internetService(region, req.InternetServiceId)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.net(region, req.NetId)
+	if err != nil {
+		return nil, err
+	}
+	if service.NetId != "" {
+		return nil, errConflict("The InternetServiceId '%s' is already linked to the NetId '%s'.", service.InternetServiceId, service.NetId)
+	}
+	for _, id := range s.ids("igw", region) {
+		if s.internetServices[id].NetId == n.NetId {
+			return nil, errConflict("The NetId '%s' already has the InternetServiceId '%s'.", n.NetId, id)
+		}
+	}
+
+	service.NetId = n.NetId
+	service.State = "available"
+	return osc.LinkInternetServiceResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) unlinkInternetService(region string, body []byte) (interface{}, error) {
+	var req osc.UnlinkInternetServiceRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	service, err := s.internetService(region, req.InternetServiceId)
+	if err != nil {
+		return nil, err
+	}
+	if service.NetId == "" || service.NetId != req.NetId {
+		return nil, errState("The InternetServiceId '%s' is not linked to the NetId '%s'.", service.InternetServiceId, req.NetId)
+	}
+	for _, id := range s.ids("nat", region) {
+		if nat := s.natServices[id]; nat.NetId == service.NetId && nat.State != "deleted" {
+			return nil, errState("The NetId '%s' still has the NatServiceId '%s'.", service.NetId, id)
+		}
+	}
+
+	service.NetId = ""
+	service.State = ""
+	return osc.UnlinkInternetServiceResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) deleteInternetService(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteInternetServiceRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	service, err := s.internetService(region, req.InternetServiceId)
+	if err != nil {
+		return nil, err
+	}
+	if service.NetId != "" {
+		return nil, errState("The InternetServiceId '%s' is linked to the NetId '%s'.", service.InternetServiceId, service.NetId)
+	}
+
+	delete(s.internetServices, service.InternetServiceId)
+	s.forget(service.InternetServiceId)
+	return osc.DeleteInternetServiceResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) internetService(region, id string) (*osc.InternetService, error) {
+	service, ok := s.internetServices[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("InternetServiceId", id)
+	}
+	return service, nil
+}
+
+// InternetServices returns the internet services of every region.
+func (s *Server) InternetServices() []osc.InternetService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var services []osc.InternetService
+	for _, id := range s.ids("igw", "") {
+		service := *s.internetServices[id]
+		service.Tags = s.tagsOf(id)
+		services = append(services, service)
+	}
+	return services
+}
@@ -0,0 +1,137 @@
+package oapitest
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/outscale/osc-sdk-go/osc"
+	"golang.org/x/crypto/ssh"
+)
+
+func (s *Server) createKeypair(region string, body []byte) (interface{}, error) {
+	var req osc.CreateKeypairRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.KeypairName == "" {
+		return nil, errMissing("KeypairName")
+	}
+	if s.keypair(region, req.KeypairName) != nil {
+		return nil, errConflict("The KeypairName '%s' already exists.", req.KeypairName)
+	}
+
+	created := osc.KeypairCreated{KeypairName: req.KeypairName}
+	if req.PublicKey != "" {
+		// The public key is Base64-encoded, in the OpenSSH format.
+		publicKey := []byte(req.PublicKey)
+		if decoded, err := base64.StdEncoding.DecodeString(req.PublicKey); err == nil {
+			publicKey = decoded
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey(publicKey)
+		if err != nil {
+			return nil, errInvalid("The PublicKey is invalid: %s", err)
+		}
+		created.KeypairFingerprint = colonHex(md5Sum(key.Marshal()))
+	} else {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha1.Sum(der)
+		created.KeypairFingerprint = colonHex(sum[:])
+		created.PrivateKey = string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}))
+	}
+
+	// Keypairs are named, and stored under an internal ID.
+	id := s.newID("key", region)
+	s.keypairs[id] = &osc.Keypair{
+		KeypairName:        created.KeypairName,
+		KeypairFingerprint: created.KeypairFingerprint,
+	}
+
+	return osc.CreateKeypairResponse{ResponseContext: s.responseContext(), Keypair: created}, nil
+}
+
+func (s *Server) readKeypairs(region string, body []byte) (interface{}, error) {
+	var req osc.ReadKeypairsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadKeypairsResponse{ResponseContext: s.responseContext(), Keypairs: []osc.Keypair{}}
+	for _, id := range s.ids("key", region) {
+		k := s.keypairs[id]
+		if matches(f.KeypairNames, k.KeypairName) && matches(f.KeypairFingerprints, k.KeypairFingerprint) {
+			resp.Keypairs = append(resp.Keypairs, *k)
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) deleteKeypair(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteKeypairRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.KeypairName == "" {
+		return nil, errMissing("KeypairName")
+	}
+
+	for _, id := range s.ids("key", region) {
+		if s.keypairs[id].KeypairName == req.KeypairName {
+			delete(s.keypairs, id)
+			s.forget(id)
+			break
+		}
+	}
+	return osc.DeleteKeypairResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) keypair(region, name string) *osc.Keypair {
+	for _, id := range s.ids("key", region) {
+		if s.keypairs[id].KeypairName == name {
+			return s.keypairs[id]
+		}
+	}
+	return nil
+}
+
+// Keypairs returns the keypairs of every region.
+func (s *Server) Keypairs() []osc.Keypair {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keypairs []osc.Keypair
+	for _, id := range s.ids("key", "") {
+		keypairs = append(keypairs, *s.keypairs[id])
+	}
+	return keypairs
+}
+
+func md5Sum(b []byte) []byte {
+	sum := md5.Sum(b)
+	return sum[:]
+}
+
+func colonHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(parts, ":")
+}
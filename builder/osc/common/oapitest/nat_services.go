@@ -0,0 +1,122 @@
+package oapitest
+
+import (
+	"fmt"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func (s *Server) createNatService(region string, body []byte) (interface{}, error) {
+	var req osc.CreateNatServiceRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	ip, err := s.publicIp(region, req.PublicIpId, "")
+	if err != nil {
+		return nil, err
+	}
+	if ip.LinkPublicIpId != "" {
+		return nil, errConflict("The PublicIpId '%s' is already in use.", ip.PublicIpId)
+	}
+	subnet, err := s.subnet(region, req.SubnetId)
+	if err != nil {
+		return nil, err
+	}
+
+	nat := &osc.NatService{
+		NatServiceId: s.newID("nat", region),
+		NetId:        subnet.NetId,
+		PublicIps:    []osc.PublicIpLight{{PublicIp: ip.PublicIp, PublicIpId: ip.PublicIpId}},
+		State:        "pending",
+		SubnetId:     subnet.SubnetId,
+	}
+	s.natServices[nat.NatServiceId] = nat
+	s.transition(nat.NatServiceId, func() { nat.State = "available" })
+
+	// The public IP is linked to the network interface of the NAT service.
+	s.lastID++
+	ip.LinkPublicIpId = fmt.Sprintf("eipassoc-%08x", s.lastID)
+	ip.PrivateIp = s.allocatePrivateIp(subnet, "", nat.NatServiceId)
+
+	return osc.CreateNatServiceResponse{ResponseContext: s.responseContext(), NatService: *nat}, nil
+}
+
+func (s *Server) readNatServices(region string, body []byte) (interface{}, error) {
+	var req osc.ReadNatServicesRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadNatServicesResponse{ResponseContext: s.responseContext(), NatServices: []osc.NatService{}}
+	for _, id := range s.ids("nat", region) {
+		nat := s.natServices[id]
+		if !matches(f.NatServiceIds, id) || !matches(f.NetIds, nat.NetId) ||
+			!matches(f.SubnetIds, nat.SubnetId) || !s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+		s.observe(id)
+		if !matches(f.States, nat.State) {
+			continue
+		}
+
+		v := *nat
+		v.Tags = s.tagsOf(id)
+		resp.NatServices = append(resp.NatServices, v)
+	}
+	return resp, nil
+}
+
+func (s *Server) deleteNatService(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteNatServiceRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	nat, err := s.natService(region, req.NatServiceId)
+	if err != nil {
+		return nil, err
+	}
+	if nat.State == "deleted" {
+		return nil, errNotFound("NatServiceId", nat.NatServiceId)
+	}
+
+	// Deleted NAT services remain readable, and release their public IPs
+	// once deleted.
+	nat.State = "deleting"
+	s.transition(nat.NatServiceId, func() {
+		nat.State = "deleted"
+		s.releasePrivateIps(nat.NatServiceId)
+		for _, light := range nat.PublicIps {
+			if ip, ok := s.publicIps[light.PublicIpId]; ok {
+				unlinkPublicIp(ip)
+			}
+		}
+	})
+
+	return osc.DeleteNatServiceResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) natService(region, id string) (*osc.NatService, error) {
+	nat, ok := s.natServices[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("NatServiceId", id)
+	}
+	return nat, nil
+}
+
+// NatServices returns the NAT services of every region, including the
+// deleted ones.
+func (s *Server) NatServices() []osc.NatService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var services []osc.NatService
+	for _, id := range s.ids("nat", "") {
+		nat := *s.natServices[id]
+		nat.Tags = s.tagsOf(id)
+		services = append(services, nat)
+	}
+	return services
+}
@@ -0,0 +1,326 @@
+package oapitest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func (s *Server) createNet(region string, body []byte) (interface{}, error) {
+	var req osc.CreateNetRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.IpRange == "" {
+		return nil, errMissing("IpRange")
+	}
+	_, ipNet, err := net.ParseCIDR(req.IpRange)
+	if err != nil {
+		return nil, errInvalid("The IpRange '%s' is invalid.", req.IpRange)
+	}
+	if ones, _ := ipNet.Mask.Size(); ones < 16 || ones > 28 {
+		return nil, errInvalid("The IpRange '%s' must be between /16 and /28.", req.IpRange)
+	}
+
+	n := s.newNet(region, ipNet.String(), req.Tenancy)
+	n.State = "pending"
+	s.transition(n.NetId, func() { n.State = "available" })
+
+	return osc.CreateNetResponse{ResponseContext: s.responseContext(), Net: *n}, nil
+}
+
+func (s *Server) newNet(region, ipRange, tenancy string) *osc.Net {
+	if tenancy == "" {
+		tenancy = "default"
+	}
+	n := &osc.Net{
+		NetId:            s.newID("vpc", region),
+		DhcpOptionsSetId: "dopt-00000000",
+		IpRange:          ipRange,
+		State:            "available",
+		Tenancy:          tenancy,
+	}
+	s.nets[n.NetId] = n
+
+	main := s.newRouteTable(region, n)
+	s.lastID++
+	main.LinkRouteTables = []osc.LinkRouteTable{{
+		LinkRouteTableId: fmt.Sprintf("rtbassoc-%08x", s.lastID),
+		Main:             true,
+		RouteTableId:     main.RouteTableId,
+	}}
+	return n
+}
+
+func (s *Server) readNets(region string, body []byte) (interface{}, error) {
+	var req osc.ReadNetsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadNetsResponse{ResponseContext: s.responseContext(), Nets: []osc.Net{}}
+	for _, id := range s.ids("vpc", region) {
+		n := s.nets[id]
+		if !matches(f.NetIds, id) || !matches(f.IpRanges, n.IpRange) ||
+			!matches(f.DhcpOptionsSetIds, n.DhcpOptionsSetId) ||
+			!s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+		s.observe(id)
+		if !matches(f.States, n.State) {
+			continue
+		}
+
+		v := *n
+		v.Tags = s.tagsOf(id)
+		resp.Nets = append(resp.Nets, v)
+	}
+	return resp, nil
+}
+
+func (s *Server) deleteNet(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteNetRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	n, err := s.net(region, req.NetId)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range s.ids("subnet", region) {
+		if s.subnets[id].NetId == n.NetId {
+			return nil, errState("The NetId '%s' still has the SubnetId '%s'.", n.NetId, id)
+		}
+	}
+	for _, id := range s.ids("sg", region) {
+		if s.securityGroups[id].NetId == n.NetId {
+			return nil, errState("The NetId '%s' still has the SecurityGroupId '%s'.", n.NetId, id)
+		}
+	}
+	for _, id := range s.ids("igw", region) {
+		if s.internetServices[id].NetId == n.NetId {
+			return nil, errState("The NetId '%s' still has the InternetServiceId '%s'.", n.NetId, id)
+		}
+	}
+	var main string
+	for _, id := range s.ids("rtb", region) {
+		table := s.routeTables[id]
+		if table.NetId != n.NetId {
+			continue
+		}
+		if len(table.LinkRouteTables) == 0 || !table.LinkRouteTables[0].Main {
+			return nil, errState("The NetId '%s' still has the RouteTableId '%s'.", n.NetId, id)
+		}
+		main = id
+	}
+
+	// The main route table goes with the Net.
+	delete(s.routeTables, main)
+	s.forget(main)
+	delete(s.nets, n.NetId)
+	s.forget(n.NetId)
+	return osc.DeleteNetResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) net(region, id string) (*osc.Net, error) {
+	n, ok := s.nets[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("NetId", id)
+	}
+	return n, nil
+}
+
+func (s *Server) createSubnet(region string, body []byte) (interface{}, error) {
+	var req osc.CreateSubnetRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.IpRange == "" {
+		return nil, errMissing("IpRange")
+	}
+
+	n, err := s.net(region, req.NetId)
+	if err != nil {
+		return nil, err
+	}
+	_, ipNet, err := net.ParseCIDR(req.IpRange)
+	if err != nil {
+		return nil, errInvalid("The IpRange '%s' is invalid.", req.IpRange)
+	}
+	_, netRange, _ := net.ParseCIDR(n.IpRange)
+	netOnes, _ := netRange.Mask.Size()
+	ones, _ := ipNet.Mask.Size()
+	if !netRange.Contains(ipNet.IP) || ones < netOnes || ones > 28 {
+		return nil, errInvalid("The IpRange '%s' is not within the range of the NetId '%s'.", req.IpRange, n.NetId)
+	}
+	for _, id := range s.ids("subnet", region) {
+		_, other, _ := net.ParseCIDR(s.subnets[id].IpRange)
+		if s.subnets[id].NetId == n.NetId && (other.Contains(ipNet.IP) || ipNet.Contains(other.IP)) {
+			return nil, errConflict("The IpRange '%s' overlaps the SubnetId '%s'.", req.IpRange, id)
+		}
+	}
+
+	subregion := req.SubregionName
+	if subregion == "" {
+		subregion = region + "a"
+	}
+
+	subnet := s.newSubnet(region, n.NetId, ipNet.String(), subregion)
+	subnet.State = "pending"
+	s.transition(subnet.SubnetId, func() { subnet.State = "available" })
+
+	return osc.CreateSubnetResponse{ResponseContext: s.responseContext(), Subnet: *subnet}, nil
+}
+
+func (s *Server) newSubnet(region, netID, ipRange, subregion string) *osc.Subnet {
+	_, ipNet, _ := net.ParseCIDR(ipRange)
+	ones, bits := ipNet.Mask.Size()
+
+	// The first four addresses and the last one are reserved.
+	subnet := &osc.Subnet{
+		SubnetId:          s.newID("subnet", region),
+		AvailableIpsCount: int32(1<<(bits-ones) - 5),
+		IpRange:           ipRange,
+		NetId:             netID,
+		State:             "available",
+		SubregionName:     subregion,
+	}
+	s.subnets[subnet.SubnetId] = subnet
+	return subnet
+}
+
+func (s *Server) readSubnets(region string, body []byte) (interface{}, error) {
+	var req osc.ReadSubnetsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadSubnetsResponse{ResponseContext: s.responseContext(), Subnets: []osc.Subnet{}}
+	for _, id := range s.ids("subnet", region) {
+		subnet := s.subnets[id]
+		if !matches(f.SubnetIds, id) || !matches(f.NetIds, subnet.NetId) ||
+			!matches(f.SubregionNames, subnet.SubregionName) || !matches(f.IpRanges, subnet.IpRange) ||
+			!s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+		s.observe(id)
+		if !matches(f.States, subnet.State) {
+			continue
+		}
+
+		v := *subnet
+		v.Tags = s.tagsOf(id)
+		resp.Subnets = append(resp.Subnets, v)
+	}
+	return resp, nil
+}
+
+func (s *Server) deleteSubnet(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteSubnetRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	subnet, err := s.subnet(region, req.SubnetId)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range s.ids("i", region) {
+		if vm := s.vms[id]; vm.SubnetId == subnet.SubnetId && vm.State != "terminated" {
+			return nil, errState("The SubnetId '%s' is used by the VmId '%s'.", subnet.SubnetId, id)
+		}
+	}
+	for _, id := range s.ids("nat", region) {
+		if nat := s.natServices[id]; nat.SubnetId == subnet.SubnetId && nat.State != "deleted" {
+			return nil, errState("The SubnetId '%s' is used by the NatServiceId '%s'.", subnet.SubnetId, id)
+		}
+	}
+
+	// The links of the route tables go with the Subnet.
+	for _, id := range s.ids("rtb", region) {
+		table := s.routeTables[id]
+		var links []osc.LinkRouteTable
+		for _, link := range table.LinkRouteTables {
+			if link.SubnetId != subnet.SubnetId {
+				links = append(links, link)
+			}
+		}
+		table.LinkRouteTables = links
+	}
+
+	delete(s.subnets, subnet.SubnetId)
+	s.forget(subnet.SubnetId)
+	return osc.DeleteSubnetResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) subnet(region, id string) (*osc.Subnet, error) {
+	subnet, ok := s.subnets[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("SubnetId", id)
+	}
+	return subnet, nil
+}
+
+// AddNet adds an available Net to the region.
+func (s *Server) AddNet(region, ipRange string) osc.Net {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.newNet(region, ipRange, "")
+}
+
+// AddSubnet adds an available Subnet to the Net, in the given Subregion.
+func (s *Server) AddSubnet(region, netID, ipRange, subregion string) osc.Subnet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.newSubnet(region, netID, ipRange, subregion)
+}
+
+// Nets returns the Nets of every region.
+func (s *Server) Nets() []osc.Net {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var nets []osc.Net
+	for _, id := range s.ids("vpc", "") {
+		n := *s.nets[id]
+		n.Tags = s.tagsOf(id)
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// Subnets returns the Subnets of every region.
+func (s *Server) Subnets() []osc.Subnet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var subnets []osc.Subnet
+	for _, id := range s.ids("subnet", "") {
+		subnet := *s.subnets[id]
+		subnet.Tags = s.tagsOf(id)
+		subnets = append(subnets, subnet)
+	}
+	return subnets
+}
+
+// nthHost returns the nth address of the IP range.
+func nthHost(ipRange string, n int) string {
+	_, ipNet, err := net.ParseCIDR(ipRange)
+	if err != nil {
+		return ""
+	}
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(ipNet.IP.To4())+uint32(n))
+	return ip.String()
+}
+
+// dashed returns the address with dashes, as used in DNS names.
+func dashed(ip string) string {
+	return strings.ReplaceAll(ip, ".", "-")
+}
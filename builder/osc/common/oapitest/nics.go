@@ -0,0 +1,271 @@
+package oapitest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// privateIp is a private IP of a Subnet.
+type privateIp struct {
+	subnetID string
+	ip       string
+}
+
+// nicSpec is a NIC requested for a new VM.
+type nicSpec struct {
+	deviceNumber       int32
+	description        string
+	deleteOnVmDeletion bool
+	subnet             *osc.Subnet
+	securityGroups     []osc.SecurityGroupLight
+	privateIps         []string
+	secondaryCount     int
+}
+
+// vmNics returns the NICs of the VMs requested, either by Nics or by
+// SubnetId, SecurityGroupIds and PrivateIps, and their Subregion. VMs
+// outside of Nets have no NIC.
+func (s *Server) vmNics(region string, req osc.CreateVmsRequest, count int) ([]nicSpec, string, error) {
+	subregion := req.Placement.SubregionName
+
+	requested := req.Nics
+	if len(requested) > 0 {
+		if req.SubnetId != "" || len(req.SecurityGroupIds) > 0 || len(req.PrivateIps) > 0 {
+			return nil, "", errInvalid("Nics cannot be used with SubnetId, SecurityGroupIds or PrivateIps.")
+		}
+		if count > 1 {
+			return nil, "", errInvalid("Nics can only be used to create one VM.")
+		}
+	} else if req.SubnetId != "" {
+		requested = []osc.NicForVmCreation{{
+			DeleteOnVmDeletion: true,
+			SecurityGroupIds:   req.SecurityGroupIds,
+			SubnetId:           req.SubnetId,
+		}}
+	} else {
+		for _, id := range req.SecurityGroupIds {
+			sg, err := s.securityGroup(region, id)
+			if err != nil {
+				return nil, "", err
+			}
+			if sg.NetId != "" {
+				return nil, "", errInvalid("The SecurityGroupId '%s' is not in the Net of the VM.", id)
+			}
+		}
+		return nil, subregion, nil
+	}
+
+	var specs []nicSpec
+	seen := make(map[int32]bool)
+	for _, nic := range requested {
+		if nic.SubnetId == "" {
+			return nil, "", errMissing("Nics.SubnetId")
+		}
+		if seen[nic.DeviceNumber] {
+			return nil, "", errInvalid("The DeviceNumber %d is used by several NICs.", nic.DeviceNumber)
+		}
+		seen[nic.DeviceNumber] = true
+
+		subnet, err := s.subnet(region, nic.SubnetId)
+		if err != nil {
+			return nil, "", err
+		}
+		if subregion != "" && subregion != subnet.SubregionName {
+			return nil, "", errInvalid("The SubnetId '%s' is not in the Subregion '%s'.", subnet.SubnetId, subregion)
+		}
+		subregion = subnet.SubregionName
+		if len(specs) > 0 && specs[0].subnet.NetId != subnet.NetId {
+			return nil, "", errInvalid("The SubnetId '%s' is not in the Net of the VM.", subnet.SubnetId)
+		}
+
+		spec := nicSpec{
+			deviceNumber:       nic.DeviceNumber,
+			description:        nic.Description,
+			deleteOnVmDeletion: nic.DeleteOnVmDeletion,
+			subnet:             subnet,
+			secondaryCount:     int(nic.SecondaryPrivateIpCount),
+		}
+		for _, id := range nic.SecurityGroupIds {
+			sg, err := s.securityGroup(region, id)
+			if err != nil {
+				return nil, "", err
+			}
+			if sg.NetId != subnet.NetId {
+				return nil, "", errInvalid("The SecurityGroupId '%s' is not in the Net of the VM.", id)
+			}
+			spec.securityGroups = append(spec.securityGroups, osc.SecurityGroupLight{
+				SecurityGroupId:   sg.SecurityGroupId,
+				SecurityGroupName: sg.SecurityGroupName,
+			})
+		}
+
+		// The primary private IP comes first, and is picked by the server
+		// when only secondary private IPs are given.
+		var primary string
+		for _, ip := range nic.PrivateIps {
+			if err := s.checkPrivateIp(subnet, ip.PrivateIp); err != nil {
+				return nil, "", err
+			}
+			if ip.IsPrimary {
+				primary = ip.PrivateIp
+			} else {
+				spec.privateIps = append(spec.privateIps, ip.PrivateIp)
+			}
+		}
+		if len(nic.PrivateIps) > 0 {
+			spec.privateIps = append([]string{primary}, spec.privateIps...)
+		}
+
+		needed := count * (len(spec.privateIps) + spec.secondaryCount)
+		if len(spec.privateIps) == 0 {
+			needed += count
+		}
+		if int(subnet.AvailableIpsCount) < needed {
+			return nil, "", errInvalid("The SubnetId '%s' has not enough free IPs.", subnet.SubnetId)
+		}
+		specs = append(specs, spec)
+	}
+	if !seen[0] {
+		return nil, "", errMissing("Nics.DeviceNumber 0")
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].deviceNumber < specs[j].deviceNumber })
+
+	// PrivateIps gives the primary private IP of each VM.
+	if len(req.PrivateIps) > 0 {
+		if len(req.PrivateIps) != count {
+			return nil, "", errInvalid("PrivateIps must have one IP for each VM.")
+		}
+		for _, ip := range req.PrivateIps {
+			if err := s.checkPrivateIp(specs[0].subnet, ip); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	return specs, subregion, nil
+}
+
+// checkPrivateIp checks that the IP is a free host address of the Subnet.
+func (s *Server) checkPrivateIp(subnet *osc.Subnet, ip string) error {
+	address := net.ParseIP(ip).To4()
+	_, ipNet, _ := net.ParseCIDR(subnet.IpRange)
+	if address == nil || !ipNet.Contains(address) {
+		return errInvalid("The PrivateIp '%s' is not in the IpRange '%s' of the SubnetId '%s'.", ip, subnet.IpRange, subnet.SubnetId)
+	}
+
+	// The first four addresses and the last one are reserved.
+	ones, bits := ipNet.Mask.Size()
+	n := binary.BigEndian.Uint32(address) - binary.BigEndian.Uint32(ipNet.IP.To4())
+	if n < 4 || n == 1<<(bits-ones)-1 {
+		return errInvalid("The PrivateIp '%s' is reserved in the SubnetId '%s'.", ip, subnet.SubnetId)
+	}
+
+	if _, used := s.privateIps[privateIp{subnet.SubnetId, ip}]; used {
+		return errConflict("The PrivateIp '%s' is already in use in the SubnetId '%s'.", ip, subnet.SubnetId)
+	}
+	return nil
+}
+
+// allocatePrivateIp reserves the given IP of the Subnet or, if it is empty,
+// the first free one.
+func (s *Server) allocatePrivateIp(subnet *osc.Subnet, ip, owner string) string {
+	for n := 4; ip == ""; n++ {
+		candidate := nthHost(subnet.IpRange, n)
+		if _, used := s.privateIps[privateIp{subnet.SubnetId, candidate}]; !used {
+			ip = candidate
+		}
+	}
+	s.privateIps[privateIp{subnet.SubnetId, ip}] = owner
+	subnet.AvailableIpsCount--
+	return ip
+}
+
+// releasePrivateIps frees the private IPs reserved for the owner.
+func (s *Server) releasePrivateIps(owner string) {
+	for key, o := range s.privateIps {
+		if o != owner {
+			continue
+		}
+		delete(s.privateIps, key)
+		if subnet, ok := s.subnets[key.subnetID]; ok {
+			subnet.AvailableIpsCount++
+		}
+	}
+}
+
+// attachNics gives the NICs to the VM, the first private IP of the first
+// NIC being the private IP of the VM. primaryIp, if set, is the primary
+// private IP of the first NIC.
+func (s *Server) attachNics(region string, vm *osc.Vm, specs []nicSpec, primaryIp string) {
+	for i, spec := range specs {
+		s.lastID++
+		nic := osc.NicLight{
+			AccountId:   AccountId,
+			Description: spec.description,
+			LinkNic: osc.LinkNicLight{
+				DeleteOnVmDeletion: spec.deleteOnVmDeletion,
+				DeviceNumber:       spec.deviceNumber,
+				LinkNicId:          fmt.Sprintf("eni-attach-%08x", s.lastID),
+				State:              "attached",
+			},
+			MacAddress:     fmt.Sprintf("aa:bb:cc:%02x:%02x:%02x", byte(s.lastID>>16), byte(s.lastID>>8), byte(s.lastID)),
+			NetId:          spec.subnet.NetId,
+			NicId:          fmt.Sprintf("eni-%08x", s.lastID),
+			SecurityGroups: spec.securityGroups,
+			State:          "in-use",
+			SubnetId:       spec.subnet.SubnetId,
+		}
+
+		ips := append([]string(nil), spec.privateIps...)
+		if i == 0 && primaryIp != "" {
+			ips = append([]string{primaryIp}, ips...)
+		}
+		if len(ips) == 0 {
+			ips = []string{""}
+		}
+		for j := 0; j < spec.secondaryCount; j++ {
+			ips = append(ips, "")
+		}
+		// The requested IPs are reserved before the ones picked by the
+		// server, which could otherwise take them.
+		for _, ip := range ips {
+			if ip != "" {
+				s.allocatePrivateIp(spec.subnet, ip, vm.VmId)
+			}
+		}
+		for j, ip := range ips {
+			if ip == "" {
+				ip = s.allocatePrivateIp(spec.subnet, ip, vm.VmId)
+			}
+			nic.PrivateIps = append(nic.PrivateIps, osc.PrivateIpLightForVm{
+				IsPrimary:      j == 0,
+				PrivateDnsName: fmt.Sprintf("ip-%s.%s.compute.internal", dashed(ip), region),
+				PrivateIp:      ip,
+			})
+		}
+		nic.PrivateDnsName = nic.PrivateIps[0].PrivateDnsName
+		vm.Nics = append(vm.Nics, nic)
+	}
+
+	vm.SubnetId = specs[0].subnet.SubnetId
+	vm.NetId = specs[0].subnet.NetId
+	vm.PrivateIp = vm.Nics[0].PrivateIps[0].PrivateIp
+	vm.SecurityGroups = specs[0].securityGroups
+}
+
+// vmNic returns the NIC with the given ID and the VM it is attached to.
+func (s *Server) vmNic(region, nicID string) (*osc.Vm, *osc.NicLight, error) {
+	for _, id := range s.ids("i", region) {
+		vm := s.vms[id]
+		for i := range vm.Nics {
+			if vm.Nics[i].NicId == nicID {
+				return vm, &vm.Nics[i], nil
+			}
+		}
+	}
+	return nil, nil, errNotFound("NicId", nicID)
+}
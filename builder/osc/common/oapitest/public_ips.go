@@ -0,0 +1,224 @@
+package oapitest
+
+import (
+	"fmt"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func (s *Server) createPublicIp(region string, body []byte) (interface{}, error) {
+	var req osc.CreatePublicIpRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	ip := s.newPublicIp(region)
+	return osc.CreatePublicIpResponse{ResponseContext: s.responseContext(), PublicIp: *ip}, nil
+}
+
+func (s *Server) readPublicIps(region string, body []byte) (interface{}, error) {
+	var req osc.ReadPublicIpsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadPublicIpsResponse{ResponseContext: s.responseContext(), PublicIps: []osc.PublicIp{}}
+	for _, id := range s.ids("eipalloc", region) {
+		ip := s.publicIps[id]
+		if !matches(f.PublicIpIds, id) || !matches(f.PublicIps, ip.PublicIp) ||
+			!matches(f.LinkPublicIpIds, ip.LinkPublicIpId) || !matches(f.VmIds, ip.VmId) ||
+			!matches(f.PrivateIps, ip.PrivateIp) || !s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+
+		v := *ip
+		v.Tags = s.tagsOf(id)
+		resp.PublicIps = append(resp.PublicIps, v)
+	}
+	return resp, nil
+}
+
+func (s *Server) linkPublicIp(region string, body []byte) (interface{}, error) {
+	var req osc.LinkPublicIpRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.VmId == "" && req.NicId == "" {
+		return nil, errMissing("VmId")
+	}
+	if req.VmId != "" && req.NicId != "" {
+		return nil, errInvalid("VmId and NicId cannot be used together.")
+	}
+
+	ip, err := s.publicIp(region, req.PublicIpId, req.PublicIp)
+	if err != nil {
+		return nil, err
+	}
+	var vm *osc.Vm
+	var nic *osc.NicLight
+	if req.NicId != "" {
+		vm, nic, err = s.vmNic(region, req.NicId)
+	} else {
+		vm, err = s.vm(region, req.VmId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if nic == nil && len(vm.Nics) > 1 {
+		return nil, errInvalid("The VmId '%s' has several NICs, NicId must be used instead.", vm.VmId)
+	}
+	if vm.State == "shutting-down" || vm.State == "terminated" {
+		return nil, errState("The VmId '%s' is in the '%s' state.", vm.VmId, vm.State)
+	}
+	if ip.LinkPublicIpId != "" && ip.VmId == "" {
+		return nil, errConflict("The PublicIpId '%s' is used by a NAT service.", ip.PublicIpId)
+	}
+	if ip.VmId != "" && ip.VmId != vm.VmId && !req.AllowRelink {
+		return nil, errConflict("The PublicIpId '%s' is already linked to the VmId '%s'.", ip.PublicIpId, ip.VmId)
+	}
+
+	// The public IP of the VM is the one of its first NIC.
+	privateIp := vm.PrivateIp
+	if nic != nil {
+		privateIp = nic.PrivateIps[0].PrivateIp
+	}
+	primary := privateIp == vm.PrivateIp
+
+	if previous, ok := s.vms[ip.VmId]; ok && previous.PublicIp == ip.PublicIp {
+		previous.PublicIp = ""
+		previous.PublicDnsName = ""
+	}
+	for _, other := range s.publicIps {
+		if other.VmId == vm.VmId && other.PrivateIp == privateIp {
+			unlinkPublicIp(other)
+		}
+	}
+
+	s.lastID++
+	ip.LinkPublicIpId = fmt.Sprintf("eipassoc-%08x", s.lastID)
+	ip.VmId = vm.VmId
+	ip.PrivateIp = privateIp
+	if primary {
+		vm.PublicIp = ip.PublicIp
+		vm.PublicDnsName = fmt.Sprintf("ows-%s.%s.compute.outscale.com", dashed(ip.PublicIp), region)
+	}
+
+	return osc.LinkPublicIpResponse{ResponseContext: s.responseContext(), LinkPublicIpId: ip.LinkPublicIpId}, nil
+}
+
+func (s *Server) unlinkPublicIp(region string, body []byte) (interface{}, error) {
+	var req osc.UnlinkPublicIpRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	var ip *osc.PublicIp
+	for _, id := range s.ids("eipalloc", region) {
+		candidate := s.publicIps[id]
+		if req.LinkPublicIpId != "" && candidate.LinkPublicIpId == req.LinkPublicIpId ||
+			req.LinkPublicIpId == "" && req.PublicIp != "" && candidate.PublicIp == req.PublicIp {
+			ip = candidate
+		}
+	}
+	if ip == nil {
+		if req.LinkPublicIpId == "" && req.PublicIp == "" {
+			return nil, errMissing("LinkPublicIpId")
+		}
+		return nil, errNotFound("LinkPublicIpId", req.LinkPublicIpId+req.PublicIp)
+	}
+	if ip.VmId == "" {
+		return nil, errState("The PublicIp '%s' is not linked.", ip.PublicIp)
+	}
+
+	if vm, ok := s.vms[ip.VmId]; ok && vm.PublicIp == ip.PublicIp {
+		vm.PublicIp = ""
+		vm.PublicDnsName = ""
+	}
+	unlinkPublicIp(ip)
+
+	return osc.UnlinkPublicIpResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func unlinkPublicIp(ip *osc.PublicIp) {
+	ip.LinkPublicIpId = ""
+	ip.VmId = ""
+	ip.PrivateIp = ""
+}
+
+func (s *Server) deletePublicIp(region string, body []byte) (interface{}, error) {
+	var req osc.DeletePublicIpRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	ip, err := s.publicIp(region, req.PublicIpId, req.PublicIp)
+	if err != nil {
+		return nil, err
+	}
+	if ip.VmId != "" {
+		return nil, errState("The PublicIpId '%s' is linked to the VmId '%s'.", ip.PublicIpId, ip.VmId)
+	}
+	if ip.LinkPublicIpId != "" {
+		return nil, errState("The PublicIpId '%s' is used by a NAT service.", ip.PublicIpId)
+	}
+
+	delete(s.publicIps, ip.PublicIpId)
+	s.forget(ip.PublicIpId)
+	return osc.DeletePublicIpResponse{ResponseContext: s.responseContext()}, nil
+}
+
+// publicIp returns the public IP with the given ID or, if it is empty, the
+// given address.
+func (s *Server) publicIp(region, id, address string) (*osc.PublicIp, error) {
+	if id == "" {
+		if address == "" {
+			return nil, errMissing("PublicIpId")
+		}
+		for _, candidate := range s.ids("eipalloc", region) {
+			if s.publicIps[candidate].PublicIp == address {
+				return s.publicIps[candidate], nil
+			}
+		}
+		return nil, errNotFound("PublicIp", address)
+	}
+
+	ip, ok := s.publicIps[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("PublicIpId", id)
+	}
+	return ip, nil
+}
+
+func (s *Server) newPublicIp(region string) *osc.PublicIp {
+	ip := &osc.PublicIp{PublicIpId: s.newID("eipalloc", region)}
+	ip.PublicIp = nthHost("192.0.2.0/24", s.lastID%254+1)
+	s.publicIps[ip.PublicIpId] = ip
+	return ip
+}
+
+// AddPublicIp adds an unlinked public IP with the given tags to the region.
+func (s *Server) AddPublicIp(region string, tags ...osc.ResourceTag) osc.PublicIp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ip := s.newPublicIp(region)
+	s.tags[ip.PublicIpId] = tags
+	v := *ip
+	v.Tags = s.tagsOf(ip.PublicIpId)
+	return v
+}
+
+// PublicIps returns the public IPs of every region.
+func (s *Server) PublicIps() []osc.PublicIp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ips []osc.PublicIp
+	for _, id := range s.ids("eipalloc", "") {
+		ip := *s.publicIps[id]
+		ip.Tags = s.tagsOf(id)
+		ips = append(ips, ip)
+	}
+	return ips
+}
@@ -0,0 +1,83 @@
+package oapitest
+
+import (
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// quotas are the global quotas of the account, with their default maximum.
+// The used value of each is counted from the resources of the region.
+var quotas = []struct {
+	name, shortDescription, description string
+	max                                 int32
+	prefix                              string
+}{
+	{"vm_limit", "VM Limit", "Maximum number of VM this user can own", 20, "i"},
+	{"volume_limit", "Volume Limit", "Maximum number of volumes this user can own", 100, "vol"},
+	{"eip_limit", "Public IP Limit", "Maximum number of public IPs this user can own", 5, "eipalloc"},
+}
+
+// SetQuota sets the maximum value of a quota of the account, such as
+// vm_limit, volume_limit or eip_limit.
+func (s *Server) SetQuota(name string, max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotas[name] = int32(max)
+}
+
+func (s *Server) readQuotas(region string, body []byte) (interface{}, error) {
+	var req osc.ReadQuotasRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	global := osc.QuotaTypes{QuotaType: "global", Quotas: []osc.Quota{}}
+	for _, q := range quotas {
+		quota := osc.Quota{
+			AccountId:        AccountId,
+			Description:      q.description,
+			MaxValue:         q.max,
+			Name:             q.name,
+			QuotaCollection:  "Compute",
+			ShortDescription: q.shortDescription,
+			UsedValue:        s.used(q.prefix, region),
+		}
+		if max, ok := s.quotas[q.name]; ok {
+			quota.MaxValue = max
+		}
+		if matches(f.QuotaNames, quota.Name) && matches(f.Collections, quota.QuotaCollection) && matches(f.ShortDescriptions, quota.ShortDescription) {
+			global.Quotas = append(global.Quotas, quota)
+		}
+	}
+
+	resp := osc.ReadQuotasResponse{ResponseContext: s.responseContext(), QuotaTypes: []osc.QuotaTypes{}}
+	if matches(f.QuotaTypes, global.QuotaType) {
+		resp.QuotaTypes = append(resp.QuotaTypes, global)
+	}
+	return resp, nil
+}
+
+// used returns how many resources with the ID prefix the region holds,
+// terminated VMs aside.
+func (s *Server) used(prefix, region string) int32 {
+	var n int32
+	for _, id := range s.ids(prefix, region) {
+		if vm, ok := s.vms[id]; ok && vm.State == "terminated" {
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// readAccounts returns the account owning every resource of the server.
+func (s *Server) readAccounts(region string, body []byte) (interface{}, error) {
+	var req osc.ReadAccountsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	return osc.ReadAccountsResponse{
+		ResponseContext: s.responseContext(),
+		Accounts:        []osc.Account{{AccountId: AccountId}},
+	}, nil
+}
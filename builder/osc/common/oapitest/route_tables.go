@@ -0,0 +1,221 @@
+package oapitest
+
+import (
+	"fmt"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// newRouteTable adds a route table to the Net, with the local route of the
+// Net.
+func (s *Server) newRouteTable(region string, n *osc.Net) *osc.RouteTable {
+	table := &osc.RouteTable{
+		RouteTableId: s.newID("rtb", region),
+		NetId:        n.NetId,
+		Routes: []osc.Route{{
+			CreationMethod:     "CreateRouteTable",
+			DestinationIpRange: n.IpRange,
+			GatewayId:          "local",
+			State:              "active",
+		}},
+	}
+	s.routeTables[table.RouteTableId] = table
+	return table
+}
+
+func (s *Server) createRouteTable(region string, body []byte) (interface{}, error) {
+	var req osc.CreateRouteTableRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	n, err := s.net(region, req.NetId)
+	if err != nil {
+		return nil, err
+	}
+
+	table := s.newRouteTable(region, n)
+	return osc.CreateRouteTableResponse{ResponseContext: s.responseContext(), RouteTable: *table}, nil
+}
+
+func (s *Server) readRouteTables(region string, body []byte) (interface{}, error) {
+	var req osc.ReadRouteTablesRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadRouteTablesResponse{ResponseContext: s.responseContext(), RouteTables: []osc.RouteTable{}}
+	for _, id := range s.ids("rtb", region) {
+		table := s.routeTables[id]
+		var subnetIDs, linkIDs, gatewayIDs, natServiceIDs []string
+		for _, link := range table.LinkRouteTables {
+			subnetIDs = append(subnetIDs, link.SubnetId)
+			linkIDs = append(linkIDs, link.LinkRouteTableId)
+		}
+		for _, route := range table.Routes {
+			gatewayIDs = append(gatewayIDs, route.GatewayId)
+			natServiceIDs = append(natServiceIDs, route.NatServiceId)
+		}
+		if !matches(f.RouteTableIds, id) || !matches(f.NetIds, table.NetId) ||
+			!matchesAny(f.LinkSubnetIds, subnetIDs) || !matchesAny(f.LinkRouteTableLinkRouteTableIds, linkIDs) ||
+			!matchesAny(f.RouteGatewayIds, gatewayIDs) || !matchesAny(f.RouteNatServiceIds, natServiceIDs) ||
+			!s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+
+		v := *table
+		v.Tags = s.tagsOf(id)
+		resp.RouteTables = append(resp.RouteTables, v)
+	}
+	return resp, nil
+}
+
+func (s *Server) createRoute(region string, body []byte) (interface{}, error) {
+	var req osc.CreateRouteRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.DestinationIpRange == "" {
+		return nil, errMissing("DestinationIpRange")
+	}
+
+	table, err := s.routeTable(region, req.RouteTableId)
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range table.Routes {
+		if route.DestinationIpRange == req.DestinationIpRange {
+			return nil, errConflict("The route to %s already exists.", req.DestinationIpRange)
+		}
+	}
+
+	route := osc.Route{CreationMethod: "CreateRoute", DestinationIpRange: req.DestinationIpRange, State: "active"}
+	switch {
+	case req.GatewayId != "":
+		service, err := s.internetService(region, req.GatewayId)
+		if err != nil {
+			return nil, err
+		}
+		if service.NetId != table.NetId {
+			return nil, errInvalid("The InternetServiceId '%s' is not linked to the NetId '%s'.", service.InternetServiceId, table.NetId)
+		}
+		route.GatewayId = service.InternetServiceId
+	case req.NatServiceId != "":
+		nat, err := s.natService(region, req.NatServiceId)
+		if err != nil {
+			return nil, err
+		}
+		if nat.NetId != table.NetId || nat.State == "deleting" || nat.State == "deleted" {
+			return nil, errInvalid("The NatServiceId '%s' cannot be used by the RouteTableId '%s'.", nat.NatServiceId, table.RouteTableId)
+		}
+		route.NatServiceId = nat.NatServiceId
+	default:
+		return nil, errMissing("GatewayId")
+	}
+	table.Routes = append(table.Routes, route)
+
+	return osc.CreateRouteResponse{ResponseContext: s.responseContext(), RouteTable: *table}, nil
+}
+
+func (s *Server) linkRouteTable(region string, body []byte) (interface{}, error) {
+	var req osc.LinkRouteTableRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	table, err := s.routeTable(region, req.RouteTableId)
+	if err != nil {
+		return nil, err
+	}
+	subnet, err := s.subnet(region, req.SubnetId)
+	if err != nil {
+		return nil, err
+	}
+	if subnet.NetId != table.NetId {
+		return nil, errInvalid("The SubnetId '%s' is not in the NetId '%s'.", subnet.SubnetId, table.NetId)
+	}
+	for _, id := range s.ids("rtb", region) {
+		for _, link := range s.routeTables[id].LinkRouteTables {
+			if link.SubnetId == subnet.SubnetId {
+				return nil, errConflict("The SubnetId '%s' is already linked to the RouteTableId '%s'.", subnet.SubnetId, id)
+			}
+		}
+	}
+
+	s.lastID++
+	link := osc.LinkRouteTable{
+		LinkRouteTableId: fmt.Sprintf("rtbassoc-%08x", s.lastID),
+		RouteTableId:     table.RouteTableId,
+		SubnetId:         subnet.SubnetId,
+	}
+	table.LinkRouteTables = append(table.LinkRouteTables, link)
+
+	return osc.LinkRouteTableResponse{ResponseContext: s.responseContext(), LinkRouteTableId: link.LinkRouteTableId}, nil
+}
+
+func (s *Server) unlinkRouteTable(region string, body []byte) (interface{}, error) {
+	var req osc.UnlinkRouteTableRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.LinkRouteTableId == "" {
+		return nil, errMissing("LinkRouteTableId")
+	}
+
+	for _, id := range s.ids("rtb", region) {
+		table := s.routeTables[id]
+		for i, link := range table.LinkRouteTables {
+			if link.LinkRouteTableId != req.LinkRouteTableId {
+				continue
+			}
+			if link.Main {
+				return nil, errState("The LinkRouteTableId '%s' links the main route table.", link.LinkRouteTableId)
+			}
+			table.LinkRouteTables = append(table.LinkRouteTables[:i], table.LinkRouteTables[i+1:]...)
+			return osc.UnlinkRouteTableResponse{ResponseContext: s.responseContext()}, nil
+		}
+	}
+	return nil, errNotFound("LinkRouteTableId", req.LinkRouteTableId)
+}
+
+func (s *Server) deleteRouteTable(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteRouteTableRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	table, err := s.routeTable(region, req.RouteTableId)
+	if err != nil {
+		return nil, err
+	}
+	if len(table.LinkRouteTables) > 0 {
+		return nil, errState("The RouteTableId '%s' is linked to the SubnetId '%s'.", table.RouteTableId, table.LinkRouteTables[0].SubnetId)
+	}
+
+	delete(s.routeTables, table.RouteTableId)
+	s.forget(table.RouteTableId)
+	return osc.DeleteRouteTableResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) routeTable(region, id string) (*osc.RouteTable, error) {
+	table, ok := s.routeTables[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("RouteTableId", id)
+	}
+	return table, nil
+}
+
+// RouteTables returns the route tables of every region.
+func (s *Server) RouteTables() []osc.RouteTable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tables []osc.RouteTable
+	for _, id := range s.ids("rtb", "") {
+		table := *s.routeTables[id]
+		table.Tags = s.tagsOf(id)
+		tables = append(tables, table)
+	}
+	return tables
+}
@@ -0,0 +1,281 @@
+package oapitest
+
+import (
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func (s *Server) createSecurityGroup(region string, body []byte) (interface{}, error) {
+	var req osc.CreateSecurityGroupRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.SecurityGroupName == "" {
+		return nil, errMissing("SecurityGroupName")
+	}
+	if req.Description == "" {
+		return nil, errMissing("Description")
+	}
+	if req.NetId != "" {
+		if _, err := s.net(region, req.NetId); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range s.ids("sg", region) {
+		sg := s.securityGroups[id]
+		if sg.SecurityGroupName == req.SecurityGroupName && sg.NetId == req.NetId {
+			return nil, errConflict("The SecurityGroupName '%s' already exists.", req.SecurityGroupName)
+		}
+	}
+
+	sg := &osc.SecurityGroup{
+		SecurityGroupId:   s.newID("sg", region),
+		AccountId:         AccountId,
+		Description:       req.Description,
+		NetId:             req.NetId,
+		SecurityGroupName: req.SecurityGroupName,
+	}
+	// Security groups of Nets allow all outbound traffic by default.
+	if sg.NetId != "" {
+		sg.OutboundRules = []osc.SecurityGroupRule{{IpProtocol: "-1", IpRanges: []string{"0.0.0.0/0"}}}
+	}
+	s.securityGroups[sg.SecurityGroupId] = sg
+
+	return osc.CreateSecurityGroupResponse{ResponseContext: s.responseContext(), SecurityGroup: *sg}, nil
+}
+
+func (s *Server) readSecurityGroups(region string, body []byte) (interface{}, error) {
+	var req osc.ReadSecurityGroupsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadSecurityGroupsResponse{ResponseContext: s.responseContext(), SecurityGroups: []osc.SecurityGroup{}}
+	for _, id := range s.ids("sg", region) {
+		sg := s.securityGroups[id]
+		if !matches(f.SecurityGroupIds, id) || !matches(f.SecurityGroupNames, sg.SecurityGroupName) ||
+			!matches(f.AccountIds, sg.AccountId) || !matches(f.Descriptions, sg.Description) ||
+			!matches(f.NetIds, sg.NetId) || !s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+
+		v := *sg
+		v.Tags = s.tagsOf(id)
+		resp.SecurityGroups = append(resp.SecurityGroups, v)
+	}
+	return resp, nil
+}
+
+func (s *Server) deleteSecurityGroup(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteSecurityGroupRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	var sg *osc.SecurityGroup
+	switch {
+	case req.SecurityGroupId != "":
+		var err error
+		if sg, err = s.securityGroup(region, req.SecurityGroupId); err != nil {
+			return nil, err
+		}
+	case req.SecurityGroupName != "":
+		for _, id := range s.ids("sg", region) {
+			if s.securityGroups[id].SecurityGroupName == req.SecurityGroupName {
+				sg = s.securityGroups[id]
+			}
+		}
+		if sg == nil {
+			return nil, errNotFound("SecurityGroupName", req.SecurityGroupName)
+		}
+	default:
+		return nil, errMissing("SecurityGroupId")
+	}
+
+	for _, id := range s.ids("i", region) {
+		vm := s.vms[id]
+		if vm.State == "terminated" {
+			continue
+		}
+		for _, light := range vm.SecurityGroups {
+			if light.SecurityGroupId == sg.SecurityGroupId {
+				return nil, errState("The SecurityGroupId '%s' is used by the VmId '%s'.", sg.SecurityGroupId, id)
+			}
+		}
+	}
+
+	delete(s.securityGroups, sg.SecurityGroupId)
+	s.forget(sg.SecurityGroupId)
+	return osc.DeleteSecurityGroupResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) createSecurityGroupRule(region string, body []byte) (interface{}, error) {
+	var req osc.CreateSecurityGroupRuleRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	sg, rules, err := s.ruleRequest(region, req.SecurityGroupId, req.Flow)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := req.Rules
+	if len(requested) == 0 {
+		rule := osc.SecurityGroupRule{
+			FromPortRange: req.FromPortRange,
+			IpProtocol:    req.IpProtocol,
+			ToPortRange:   req.ToPortRange,
+		}
+		if req.IpRange != "" {
+			rule.IpRanges = []string{req.IpRange}
+		}
+		if req.SecurityGroupNameToLink != "" {
+			rule.SecurityGroupsMembers = []osc.SecurityGroupsMember{{
+				AccountId:         req.SecurityGroupAccountIdToLink,
+				SecurityGroupName: req.SecurityGroupNameToLink,
+			}}
+		}
+		requested = []osc.SecurityGroupRule{rule}
+	}
+
+	updated := append([]osc.SecurityGroupRule(nil), *rules...)
+	for _, rule := range requested {
+		if rule.IpProtocol == "" {
+			return nil, errMissing("IpProtocol")
+		}
+		if len(rule.IpRanges) == 0 && len(rule.SecurityGroupsMembers) == 0 && len(rule.ServiceIds) == 0 {
+			return nil, errMissing("IpRange")
+		}
+
+		i := findRule(updated, rule)
+		if i < 0 {
+			updated = append(updated, osc.SecurityGroupRule{
+				FromPortRange: rule.FromPortRange,
+				IpProtocol:    rule.IpProtocol,
+				ToPortRange:   rule.ToPortRange,
+			})
+			i = len(updated) - 1
+		}
+		existing := &updated[i]
+
+		for _, ipRange := range rule.IpRanges {
+			if contains(existing.IpRanges, ipRange) {
+				return nil, errConflict("The rule for %s already exists.", ipRange)
+			}
+			existing.IpRanges = append(existing.IpRanges, ipRange)
+		}
+		existing.SecurityGroupsMembers = append(existing.SecurityGroupsMembers, rule.SecurityGroupsMembers...)
+		existing.ServiceIds = append(existing.ServiceIds, rule.ServiceIds...)
+	}
+	*rules = updated
+
+	return osc.CreateSecurityGroupRuleResponse{ResponseContext: s.responseContext(), SecurityGroup: *sg}, nil
+}
+
+func (s *Server) deleteSecurityGroupRule(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteSecurityGroupRuleRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	sg, rules, err := s.ruleRequest(region, req.SecurityGroupId, req.Flow)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := req.Rules
+	if len(deleted) == 0 {
+		rule := osc.SecurityGroupRule{
+			FromPortRange: req.FromPortRange,
+			IpProtocol:    req.IpProtocol,
+			ToPortRange:   req.ToPortRange,
+		}
+		if req.IpRange != "" {
+			rule.IpRanges = []string{req.IpRange}
+		}
+		deleted = []osc.SecurityGroupRule{rule}
+	}
+
+	updated := append([]osc.SecurityGroupRule(nil), *rules...)
+	for _, rule := range deleted {
+		i := findRule(updated, rule)
+		if i < 0 {
+			return nil, errNotFound("rule", rule.IpProtocol)
+		}
+
+		var kept []string
+		for _, ipRange := range updated[i].IpRanges {
+			if !contains(rule.IpRanges, ipRange) {
+				kept = append(kept, ipRange)
+			}
+		}
+		if len(kept) == len(updated[i].IpRanges) && len(rule.IpRanges) > 0 {
+			return nil, errNotFound("rule", rule.IpRanges[0])
+		}
+
+		updated[i].IpRanges = kept
+		if len(kept) == 0 && len(updated[i].SecurityGroupsMembers) == 0 && len(updated[i].ServiceIds) == 0 {
+			updated = append(updated[:i], updated[i+1:]...)
+		}
+	}
+	*rules = updated
+
+	return osc.DeleteSecurityGroupRuleResponse{ResponseContext: s.responseContext(), SecurityGroup: *sg}, nil
+}
+
+// ruleRequest returns the security group and the rules of the flow a rule
+// request applies to.
+func (s *Server) ruleRequest(region, id, flow string) (*osc.SecurityGroup, *[]osc.SecurityGroupRule, error) {
+	sg, err := s.securityGroup(region, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch flow {
+	case "Inbound":
+		return sg, &sg.InboundRules, nil
+	case "Outbound":
+		if sg.NetId == "" {
+			return nil, nil, errInvalid("Outbound rules are only supported by the security groups of Nets.")
+		}
+		return sg, &sg.OutboundRules, nil
+	case "":
+		return nil, nil, errMissing("Flow")
+	default:
+		return nil, nil, errInvalid("The Flow '%s' is invalid.", flow)
+	}
+}
+
+// findRule returns the index of the rule with the same protocol and ports,
+// or -1.
+func findRule(rules []osc.SecurityGroupRule, rule osc.SecurityGroupRule) int {
+	for i, r := range rules {
+		if r.IpProtocol == rule.IpProtocol && r.FromPortRange == rule.FromPortRange && r.ToPortRange == rule.ToPortRange {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Server) securityGroup(region, id string) (*osc.SecurityGroup, error) {
+	sg, ok := s.securityGroups[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("SecurityGroupId", id)
+	}
+	return sg, nil
+}
+
+// SecurityGroups returns the security groups of every region.
+func (s *Server) SecurityGroups() []osc.SecurityGroup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var securityGroups []osc.SecurityGroup
+	for _, id := range s.ids("sg", "") {
+		sg := *s.securityGroups[id]
+		sg.Tags = s.tagsOf(id)
+		securityGroups = append(securityGroups, sg)
+	}
+	return securityGroups
+}
@@ -0,0 +1,364 @@
+// Package oapitest serves an in-memory OAPI over httptest, so that the
+// builder steps can run offline through AccessConfig.CustomEndpointOAPI.
+//
+// The server implements the VM, image, snapshot, volume, keypair, security
+// group, public IP, Net, Subnet, internet service, route table, NAT service,
+// tag, VM type, Subregion and quota calls used by the plugin. Resources go through their transitional
+// states (pending, stopping, shutting-down...) for Transitions reads before
+// reaching their final state, and the resources of each region are kept apart
+// using the region the request is signed for.
+package oapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+const (
+	// DefaultRegion is the region of the requests whose signature has no region.
+	DefaultRegion = "eu-west-2"
+	// AccountId is the account owning every resource created on the server.
+	AccountId = "123456789012"
+)
+
+// Server is an in-memory OAPI.
+type Server struct {
+	*httptest.Server
+
+	// Transitions is the number of reads for which a resource stays in a
+	// transitional state. Defaults to 1; 0 makes the first read return the
+	// final state.
+	Transitions int
+
+	mu          sync.Mutex
+	lastID      int
+	regions     map[string]string
+	tags        map[string][]osc.ResourceTag
+	transitions map[string]*transition
+	failures    map[string]osc.Errors
+	statuses    map[string]int
+	failOnce    map[string]bool
+	calls       map[string]int
+	quotas      map[string]int32
+	noCapacity  map[capacity]bool
+
+	vms              map[string]*osc.Vm
+	volumes          map[string]*osc.Volume
+	snapshots        map[string]*osc.Snapshot
+	images           map[string]*osc.Image
+	keypairs         map[string]*osc.Keypair
+	securityGroups   map[string]*osc.SecurityGroup
+	publicIps        map[string]*osc.PublicIp
+	nets             map[string]*osc.Net
+	subnets          map[string]*osc.Subnet
+	privateIps       map[privateIp]string
+	internetServices map[string]*osc.InternetService
+	routeTables      map[string]*osc.RouteTable
+	natServices      map[string]*osc.NatService
+}
+
+type handler func(s *Server, region string, body []byte) (interface{}, error)
+
+var handlers = map[string]handler{
+	"CreateImage":             (*Server).createImage,
+	"CreateInternetService":   (*Server).createInternetService,
+	"CreateKeypair":           (*Server).createKeypair,
+	"CreateNatService":        (*Server).createNatService,
+	"CreateNet":               (*Server).createNet,
+	"CreatePublicIp":          (*Server).createPublicIp,
+	"CreateRoute":             (*Server).createRoute,
+	"CreateRouteTable":        (*Server).createRouteTable,
+	"CreateSecurityGroup":     (*Server).createSecurityGroup,
+	"CreateSecurityGroupRule": (*Server).createSecurityGroupRule,
+	"CreateSnapshot":          (*Server).createSnapshot,
+	"CreateSubnet":            (*Server).createSubnet,
+	"CreateTags":              (*Server).createTags,
+	"CreateVms":               (*Server).createVms,
+	"CreateVolume":            (*Server).createVolume,
+	"DeleteImage":             (*Server).deleteImage,
+	"DeleteInternetService":   (*Server).deleteInternetService,
+	"DeleteKeypair":           (*Server).deleteKeypair,
+	"DeleteNatService":        (*Server).deleteNatService,
+	"DeleteNet":               (*Server).deleteNet,
+	"DeletePublicIp":          (*Server).deletePublicIp,
+	"DeleteRouteTable":        (*Server).deleteRouteTable,
+	"DeleteSecurityGroup":     (*Server).deleteSecurityGroup,
+	"DeleteSecurityGroupRule": (*Server).deleteSecurityGroupRule,
+	"DeleteSnapshot":          (*Server).deleteSnapshot,
+	"DeleteSubnet":            (*Server).deleteSubnet,
+	"DeleteTags":              (*Server).deleteTags,
+	"DeleteVms":               (*Server).deleteVms,
+	"DeleteVolume":            (*Server).deleteVolume,
+	"LinkInternetService":     (*Server).linkInternetService,
+	"LinkPublicIp":            (*Server).linkPublicIp,
+	"LinkRouteTable":          (*Server).linkRouteTable,
+	"LinkVolume":              (*Server).linkVolume,
+	"ReadAccounts":            (*Server).readAccounts,
+	"ReadImages":              (*Server).readImages,
+	"ReadInternetServices":    (*Server).readInternetServices,
+	"ReadKeypairs":            (*Server).readKeypairs,
+	"ReadNatServices":         (*Server).readNatServices,
+	"ReadNets":                (*Server).readNets,
+	"ReadPublicIps":           (*Server).readPublicIps,
+	"ReadQuotas":              (*Server).readQuotas,
+	"ReadRouteTables":         (*Server).readRouteTables,
+	"ReadSecurityGroups":      (*Server).readSecurityGroups,
+	"ReadSnapshots":           (*Server).readSnapshots,
+	"ReadSubnets":             (*Server).readSubnets,
+	"ReadSubregions":          (*Server).readSubregions,
+	"ReadVmTypes":             (*Server).readVmTypes,
+	"ReadVms":                 (*Server).readVms,
+	"ReadVolumes":             (*Server).readVolumes,
+	"StopVms":                 (*Server).stopVms,
+	"UnlinkInternetService":   (*Server).unlinkInternetService,
+	"UnlinkPublicIp":          (*Server).unlinkPublicIp,
+	"UnlinkRouteTable":        (*Server).unlinkRouteTable,
+	"UnlinkVolume":            (*Server).unlinkVolume,
+	"UpdateImage":             (*Server).updateImage,
+	"UpdateSnapshot":          (*Server).updateSnapshot,
+}
+
+// NewServer starts and returns a new server. The caller should call Close
+// when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		Transitions:      1,
+		regions:          make(map[string]string),
+		tags:             make(map[string][]osc.ResourceTag),
+		transitions:      make(map[string]*transition),
+		failures:         make(map[string]osc.Errors),
+		statuses:         make(map[string]int),
+		failOnce:         make(map[string]bool),
+		calls:            make(map[string]int),
+		quotas:           make(map[string]int32),
+		noCapacity:       make(map[capacity]bool),
+		vms:              make(map[string]*osc.Vm),
+		volumes:          make(map[string]*osc.Volume),
+		snapshots:        make(map[string]*osc.Snapshot),
+		images:           make(map[string]*osc.Image),
+		keypairs:         make(map[string]*osc.Keypair),
+		securityGroups:   make(map[string]*osc.SecurityGroup),
+		publicIps:        make(map[string]*osc.PublicIp),
+		nets:             make(map[string]*osc.Net),
+		subnets:          make(map[string]*osc.Subnet),
+		privateIps:       make(map[privateIp]string),
+		internetServices: make(map[string]*osc.InternetService),
+		routeTables:      make(map[string]*osc.RouteTable),
+		natServices:      make(map[string]*osc.NatService),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Fail makes every later call to the action answer with the given HTTP status
+// and error type. A zero status makes the action succeed again.
+func (s *Server) Fail(action string, status int, errorType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if status == 0 {
+		delete(s.failures, action)
+		delete(s.statuses, action)
+		delete(s.failOnce, action)
+		return
+	}
+	s.failures[action] = osc.Errors{Code: "0", Type: errorType, Details: "Injected failure"}
+	s.statuses[action] = status
+	delete(s.failOnce, action)
+}
+
+// FailOnce makes the next call to the action answer with the given HTTP
+// status and error type, the later ones succeeding.
+func (s *Server) FailOnce(action string, status int, errorType string) {
+	s.Fail(action, status, errorType)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failOnce[action] = true
+}
+
+// Calls returns how many times the action was called.
+func (s *Server) Calls(action string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[action]
+}
+
+// Region returns the region of the resource, or an empty string if it does
+// not exist.
+func (s *Server) Region(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.regions[id]
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	action := path.Base(r.URL.Path)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, errInvalid("Cannot read the request: %s", err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[action]++
+
+	if r.Header.Get("Authorization") == "" {
+		writeError(w, &apiError{status: http.StatusUnauthorized, Errors: osc.Errors{
+			Code: "1", Type: "AccessDenied", Details: "The request is not signed.",
+		}})
+		return
+	}
+	if failure, ok := s.failures[action]; ok {
+		writeError(w, &apiError{status: s.statuses[action], Errors: failure})
+		if s.failOnce[action] {
+			delete(s.failures, action)
+			delete(s.statuses, action)
+			delete(s.failOnce, action)
+		}
+		return
+	}
+
+	h, ok := handlers[action]
+	if !ok {
+		writeError(w, errInvalid("The action %s is not implemented.", action))
+		return
+	}
+
+	resp, err := h(s, signedRegion(r), body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// signedRegion returns the region of the credential scope of the signature,
+// such as "Credential=AK/20221010/eu-west-2/osc/aws4_request".
+func signedRegion(r *http.Request) string {
+	_, scope, ok := strings.Cut(r.Header.Get("Authorization"), "Credential=")
+	if !ok {
+		return DefaultRegion
+	}
+	parts := strings.Split(scope, "/")
+	if len(parts) < 3 || parts[2] == "" {
+		return DefaultRegion
+	}
+	return parts[2]
+}
+
+func decode(body []byte, v interface{}) error {
+	if len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return errInvalid("Cannot decode the request: %s", err)
+	}
+	return nil
+}
+
+func (s *Server) responseContext() osc.ResponseContext {
+	return osc.ResponseContext{RequestId: fmt.Sprintf("%08x-0000-4000-8000-000000000000", s.lastID)}
+}
+
+// newID returns a new resource ID with the given prefix, recording its
+// region. IDs sort in creation order.
+func (s *Server) newID(prefix, region string) string {
+	s.lastID++
+	id := fmt.Sprintf("%s-%08x", prefix, s.lastID)
+	s.regions[id] = region
+	return id
+}
+
+func (s *Server) forget(id string) {
+	delete(s.regions, id)
+	delete(s.tags, id)
+	delete(s.transitions, id)
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// ids returns the IDs with the given prefix of the resources of the region,
+// or of every region if it is empty, in creation order.
+func (s *Server) ids(prefix, region string) []string {
+	var ids []string
+	for id, r := range s.regions {
+		if strings.HasPrefix(id, prefix+"-") && (region == "" || r == region) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+type transition struct {
+	reads int
+	apply func()
+}
+
+// transition applies the change after the resource is read Transitions
+// times. A pending change of the resource is applied right away.
+func (s *Server) transition(id string, apply func()) {
+	if t, ok := s.transitions[id]; ok {
+		delete(s.transitions, id)
+		t.apply()
+	}
+	s.transitions[id] = &transition{reads: s.Transitions, apply: apply}
+}
+
+// observe is called when the resource is read, before answering.
+func (s *Server) observe(id string) {
+	t, ok := s.transitions[id]
+	if !ok {
+		return
+	}
+	if t.reads > 0 {
+		t.reads--
+		return
+	}
+	delete(s.transitions, id)
+	t.apply()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the value is one of the filter values, or whether
+// the filter is not set.
+func matches(filter []string, value string) bool {
+	return len(filter) == 0 || contains(filter, value)
+}
+
+func matchesAny(filter []string, values []string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if matches(filter, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package oapitest_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/antihax/optional"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+func testClient(t *testing.T, server *oapitest.Server, region string) *osc.APIClient {
+	t.Helper()
+	config := &osccommon.AccessConfig{
+		AccessKey:          "AK",
+		SecretKey:          "SK",
+		RawRegion:          "eu-west-2",
+		CustomEndpointOAPI: server.URL,
+	}
+	return config.NewOSCClientByRegion(region)
+}
+
+func readVm(t *testing.T, conn *osc.APIClient, id string) osc.Vm {
+	t.Helper()
+	resp, _, err := conn.VmApi.ReadVms(context.Background(), &osc.ReadVmsOpts{
+		ReadVmsRequest: optional.NewInterface(osc.ReadVmsRequest{Filters: osc.FiltersVm{VmIds: []string{id}}}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", osccommon.DecodeError(err))
+	}
+	if len(resp.Vms) != 1 {
+		t.Fatalf("expected one VM, got %d", len(resp.Vms))
+	}
+	return resp.Vms[0]
+}
+
+func TestServer_vmLifecycle(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	conn := testClient(t, server, "eu-west-2")
+	image := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	createResp, _, err := conn.VmApi.CreateVms(context.Background(), &osc.CreateVmsOpts{
+		CreateVmsRequest: optional.NewInterface(osc.CreateVmsRequest{ImageId: image.ImageId, VmType: "tinav4.c1r1p2"}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", osccommon.DecodeError(err))
+	}
+	vm := createResp.Vms[0]
+	if vm.State != "pending" || len(vm.BlockDeviceMappings) != 1 {
+		t.Fatalf("bad created VM: %#v", vm)
+	}
+
+	for _, expected := range []string{"pending", "running", "running"} {
+		if state := readVm(t, conn, vm.VmId).State; state != expected {
+			t.Fatalf("expected %q, got %q", expected, state)
+		}
+	}
+
+	if _, _, err := conn.VmApi.StopVms(context.Background(), &osc.StopVmsOpts{
+		StopVmsRequest: optional.NewInterface(osc.StopVmsRequest{VmIds: []string{vm.VmId}}),
+	}); err != nil {
+		t.Fatalf("shouldn't have err: %s", osccommon.DecodeError(err))
+	}
+	for _, expected := range []string{"stopping", "stopped"} {
+		if state := readVm(t, conn, vm.VmId).State; state != expected {
+			t.Fatalf("expected %q, got %q", expected, state)
+		}
+	}
+
+	imageResp, _, err := conn.ImageApi.CreateImage(context.Background(), &osc.CreateImageOpts{
+		CreateImageRequest: optional.NewInterface(osc.CreateImageRequest{VmId: vm.VmId, ImageName: "built"}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", osccommon.DecodeError(err))
+	}
+	if imageResp.Image.State != "pending" || len(imageResp.Image.BlockDeviceMappings) != 1 {
+		t.Fatalf("bad created image: %#v", imageResp.Image)
+	}
+
+	if _, _, err := conn.VmApi.DeleteVms(context.Background(), &osc.DeleteVmsOpts{
+		DeleteVmsRequest: optional.NewInterface(osc.DeleteVmsRequest{VmIds: []string{vm.VmId}}),
+	}); err != nil {
+		t.Fatalf("shouldn't have err: %s", osccommon.DecodeError(err))
+	}
+	for _, expected := range []string{"shutting-down", "terminated"} {
+		if state := readVm(t, conn, vm.VmId).State; state != expected {
+			t.Fatalf("expected %q, got %q", expected, state)
+		}
+	}
+	if volumes := server.Volumes(); len(volumes) != 0 {
+		t.Fatalf("the root volume should be deleted with the VM, got %#v", volumes)
+	}
+}
+
+func TestServer_regions(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	conn := testClient(t, server, "us-east-2")
+	resp, _, err := conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", osccommon.DecodeError(err))
+	}
+	if len(resp.Images) != 0 {
+		t.Fatalf("the images of other regions shouldn't be read, got %#v", resp.Images)
+	}
+
+	copyResp, _, err := conn.ImageApi.CreateImage(context.Background(), &osc.CreateImageOpts{
+		CreateImageRequest: optional.NewInterface(osc.CreateImageRequest{
+			ImageName:        "source",
+			SourceImageId:    source.ImageId,
+			SourceRegionName: "eu-west-2",
+		}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", osccommon.DecodeError(err))
+	}
+	if region := server.Region(copyResp.Image.ImageId); region != "us-east-2" {
+		t.Fatalf("the copy should be in us-east-2, got %q", region)
+	}
+	snapshotID := copyResp.Image.BlockDeviceMappings[0].Bsu.SnapshotId
+	if snapshotID == source.BlockDeviceMappings[0].Bsu.SnapshotId || server.Region(snapshotID) != "us-east-2" {
+		t.Fatalf("the snapshots should be copied, got %s", snapshotID)
+	}
+}
+
+func TestServer_errors(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	conn := testClient(t, server, "eu-west-2")
+
+	_, _, err := conn.VmApi.StopVms(context.Background(), &osc.StopVmsOpts{
+		StopVmsRequest: optional.NewInterface(osc.StopVmsRequest{VmIds: []string{"i-12345678"}}),
+	})
+	if !osccommon.IsNotFoundError(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+
+	net := server.AddNet("eu-west-2", "10.0.0.0/16")
+	_, _, err = conn.SubnetApi.CreateSubnet(context.Background(), &osc.CreateSubnetOpts{
+		CreateSubnetRequest: optional.NewInterface(osc.CreateSubnetRequest{NetId: net.NetId, IpRange: "10.1.0.0/24"}),
+	})
+	if err == nil || !strings.Contains(osccommon.DecodeError(err).Error(), "InvalidParameterValue") {
+		t.Fatalf("a subnet outside of the Net should be rejected, got %v", err)
+	}
+
+	server.Fail("ReadImages", http.StatusServiceUnavailable, "RequestLimitExceeded")
+	_, _, err = conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{}),
+	})
+	if !osccommon.IsThrottledError(err) {
+		t.Fatalf("expected the injected error, got %v", err)
+	}
+	if calls := server.Calls("ReadImages"); calls != 1 {
+		t.Fatalf("expected one call, got %d", calls)
+	}
+
+	resp, err := http.Post(server.URL+"/ReadImages", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unsigned requests should be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_securityGroupInUse(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	conn := testClient(t, server, "eu-west-2")
+
+	net := server.AddNet("eu-west-2", "10.0.0.0/16")
+	subnet := server.AddSubnet("eu-west-2", net.NetId, "10.0.1.0/24", "eu-west-2a")
+	image := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	sgResp, _, err := conn.SecurityGroupApi.CreateSecurityGroup(context.Background(), &osc.CreateSecurityGroupOpts{
+		CreateSecurityGroupRequest: optional.NewInterface(osc.CreateSecurityGroupRequest{
+			SecurityGroupName: "test", Description: "test", NetId: net.NetId,
+		}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", osccommon.DecodeError(err))
+	}
+	sgID := sgResp.SecurityGroup.SecurityGroupId
+
+	vmResp, _, err := conn.VmApi.CreateVms(context.Background(), &osc.CreateVmsOpts{
+		CreateVmsRequest: optional.NewInterface(osc.CreateVmsRequest{
+			ImageId: image.ImageId, SubnetId: subnet.SubnetId, SecurityGroupIds: []string{sgID},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", osccommon.DecodeError(err))
+	}
+	if ip := vmResp.Vms[0].PrivateIp; ip != "10.0.1.4" {
+		t.Fatalf("the private IP should be in the subnet, got %s", ip)
+	}
+
+	_, _, err = conn.SecurityGroupApi.DeleteSecurityGroup(context.Background(), &osc.DeleteSecurityGroupOpts{
+		DeleteSecurityGroupRequest: optional.NewInterface(osc.DeleteSecurityGroupRequest{SecurityGroupId: sgID}),
+	})
+	if !osccommon.IsConflictError(err) {
+		t.Fatalf("a security group in use shouldn't be deleted, got %v", err)
+	}
+}
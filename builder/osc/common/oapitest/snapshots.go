@@ -0,0 +1,181 @@
+package oapitest
+
+import (
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func (s *Server) createSnapshot(region string, body []byte) (interface{}, error) {
+	var req osc.CreateSnapshotRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	var (
+		size     int32
+		volumeID string
+	)
+	switch {
+	case req.VolumeId != "":
+		volume, err := s.volume(region, req.VolumeId)
+		if err != nil {
+			return nil, err
+		}
+		size = volume.Size
+		volumeID = volume.VolumeId
+	case req.SourceSnapshotId != "":
+		sourceRegion := req.SourceRegionName
+		if sourceRegion == "" {
+			return nil, errMissing("SourceRegionName")
+		}
+		source, err := s.snapshot(sourceRegion, req.SourceSnapshotId)
+		if err != nil {
+			return nil, err
+		}
+		size = source.VolumeSize
+	case req.FileLocation != "":
+		if req.SnapshotSize == 0 {
+			return nil, errMissing("SnapshotSize")
+		}
+		// The size is given in bytes.
+		size = int32((req.SnapshotSize + 1<<30 - 1) >> 30)
+	default:
+		return nil, errMissing("VolumeId")
+	}
+
+	snapshot := s.newSnapshot(region, volumeID, size, req.Description)
+	return osc.CreateSnapshotResponse{ResponseContext: s.responseContext(), Snapshot: *snapshot}, nil
+}
+
+// newSnapshot returns a new snapshot, pending until it is read.
+func (s *Server) newSnapshot(region, volumeID string, size int32, description string) *osc.Snapshot {
+	snapshot := &osc.Snapshot{
+		SnapshotId:  s.newID("snap", region),
+		AccountId:   AccountId,
+		Description: description,
+		Progress:    0,
+		State:       "pending",
+		VolumeId:    volumeID,
+		VolumeSize:  size,
+	}
+	s.snapshots[snapshot.SnapshotId] = snapshot
+	s.transition(snapshot.SnapshotId, func() {
+		snapshot.State = "completed"
+		snapshot.Progress = 100
+	})
+	return snapshot
+}
+
+func (s *Server) readSnapshots(region string, body []byte) (interface{}, error) {
+	var req osc.ReadSnapshotsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadSnapshotsResponse{ResponseContext: s.responseContext(), Snapshots: []osc.Snapshot{}}
+	for _, id := range s.ids("snap", region) {
+		snapshot := s.snapshots[id]
+		if !matches(f.SnapshotIds, id) || !matches(f.AccountIds, snapshot.AccountId) ||
+			!matches(f.VolumeIds, snapshot.VolumeId) || !matches(f.Descriptions, snapshot.Description) ||
+			!matchesAny(f.PermissionsToCreateVolumeAccountIds, snapshot.PermissionsToCreateVolume.AccountIds) ||
+			!s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+		s.observe(id)
+		if !matches(f.States, snapshot.State) {
+			continue
+		}
+
+		v := *snapshot
+		v.Tags = s.tagsOf(id)
+		resp.Snapshots = append(resp.Snapshots, v)
+	}
+	return resp, nil
+}
+
+func (s *Server) updateSnapshot(region string, body []byte) (interface{}, error) {
+	var req osc.UpdateSnapshotRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := s.snapshot(region, req.SnapshotId)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.PermissionsToCreateVolume = updatePermissions(snapshot.PermissionsToCreateVolume, req.PermissionsToCreateVolume)
+
+	return osc.UpdateSnapshotResponse{ResponseContext: s.responseContext(), Snapshot: *snapshot}, nil
+}
+
+func (s *Server) deleteSnapshot(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteSnapshotRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := s.snapshot(region, req.SnapshotId)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range s.ids("ami", region) {
+		for _, mapping := range s.images[id].BlockDeviceMappings {
+			if mapping.Bsu.SnapshotId == snapshot.SnapshotId {
+				return nil, errState("The SnapshotId '%s' is used by the ImageId '%s'.", snapshot.SnapshotId, id)
+			}
+		}
+	}
+
+	delete(s.snapshots, snapshot.SnapshotId)
+	s.forget(snapshot.SnapshotId)
+	return osc.DeleteSnapshotResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) snapshot(region, id string) (*osc.Snapshot, error) {
+	snapshot, ok := s.snapshots[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("SnapshotId", id)
+	}
+	return snapshot, nil
+}
+
+// updatePermissions applies the additions, then the removals, to the
+// permissions.
+func updatePermissions(permissions osc.PermissionsOnResource, update osc.PermissionsOnResourceCreation) osc.PermissionsOnResource {
+	accounts := make(map[string]bool)
+	for _, id := range permissions.AccountIds {
+		accounts[id] = true
+	}
+	for _, id := range update.Additions.AccountIds {
+		accounts[id] = true
+	}
+	for _, id := range update.Removals.AccountIds {
+		delete(accounts, id)
+	}
+
+	var updated osc.PermissionsOnResource
+	ordered := append(append([]string(nil), permissions.AccountIds...), update.Additions.AccountIds...)
+	for _, id := range ordered {
+		if accounts[id] {
+			updated.AccountIds = append(updated.AccountIds, id)
+			delete(accounts, id)
+		}
+	}
+	updated.GlobalPermission = (permissions.GlobalPermission || update.Additions.GlobalPermission) &&
+		!update.Removals.GlobalPermission
+	return updated
+}
+
+// Snapshots returns the snapshots of every region.
+func (s *Server) Snapshots() []osc.Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var snapshots []osc.Snapshot
+	for _, id := range s.ids("snap", "") {
+		snapshot := *s.snapshots[id]
+		snapshot.Tags = s.tagsOf(id)
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
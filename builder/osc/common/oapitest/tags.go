@@ -0,0 +1,99 @@
+package oapitest
+
+import (
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func (s *Server) createTags(region string, body []byte) (interface{}, error) {
+	var req osc.CreateTagsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if len(req.ResourceIds) == 0 {
+		return nil, errMissing("ResourceIds")
+	}
+	if len(req.Tags) == 0 {
+		return nil, errMissing("Tags")
+	}
+
+	for _, id := range req.ResourceIds {
+		if s.regions[id] != region {
+			return nil, errNotFound("ResourceId", id)
+		}
+	}
+
+	for _, id := range req.ResourceIds {
+		for _, tag := range req.Tags {
+			s.setTag(id, tag)
+		}
+	}
+	return osc.CreateTagsResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) deleteTags(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteTagsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if len(req.ResourceIds) == 0 {
+		return nil, errMissing("ResourceIds")
+	}
+
+	for _, id := range req.ResourceIds {
+		if s.regions[id] != region {
+			return nil, errNotFound("ResourceId", id)
+		}
+	}
+
+	for _, id := range req.ResourceIds {
+		var kept []osc.ResourceTag
+		for _, tag := range s.tags[id] {
+			if !deletesTag(req.Tags, tag) {
+				kept = append(kept, tag)
+			}
+		}
+		s.tags[id] = kept
+	}
+	return osc.DeleteTagsResponse{ResponseContext: s.responseContext()}, nil
+}
+
+// deletesTag reports whether the tag is one of the deleted ones. A deleted
+// tag without value matches any value.
+func deletesTag(deleted []osc.ResourceTag, tag osc.ResourceTag) bool {
+	for _, d := range deleted {
+		if d.Key == tag.Key && (d.Value == "" || d.Value == tag.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) setTag(id string, tag osc.ResourceTag) {
+	for i, t := range s.tags[id] {
+		if t.Key == tag.Key {
+			s.tags[id][i].Value = tag.Value
+			return
+		}
+	}
+	s.tags[id] = append(s.tags[id], tag)
+}
+
+// matchesTags applies the TagKeys, TagValues and Tags ("key=value") filters
+// to the tags of the resource.
+func (s *Server) matchesTags(id string, keys, values, pairs []string) bool {
+	tags := s.tags[id]
+
+	var tagKeys, tagValues, tagPairs []string
+	for _, tag := range tags {
+		tagKeys = append(tagKeys, tag.Key)
+		tagValues = append(tagValues, tag.Value)
+		tagPairs = append(tagPairs, tag.Key+"="+tag.Value)
+	}
+
+	return matchesAny(keys, tagKeys) && matchesAny(values, tagValues) && matchesAny(pairs, tagPairs)
+}
+
+// tagsOf returns a copy of the tags of the resource.
+func (s *Server) tagsOf(id string) []osc.ResourceTag {
+	return append([]osc.ResourceTag(nil), s.tags[id]...)
+}
@@ -0,0 +1,338 @@
+package oapitest
+
+import (
+	"fmt"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func (s *Server) createVms(region string, body []byte) (interface{}, error) {
+	var req osc.CreateVmsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.ImageId == "" {
+		return nil, errMissing("ImageId")
+	}
+
+	image, err := s.image(region, req.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	if image.State != "available" {
+		return nil, errState("The ImageId '%s' is in the '%s' state.", image.ImageId, image.State)
+	}
+	if req.KeypairName != "" && s.keypair(region, req.KeypairName) == nil {
+		return nil, errNotFound("KeypairName", req.KeypairName)
+	}
+
+	count := int(req.MaxVmsCount)
+	if count < 1 {
+		count = 1
+	}
+
+	nics, subregion, err := s.vmNics(region, req, count)
+	if err != nil {
+		return nil, err
+	}
+	if subregion == "" {
+		subregion = region + "a"
+	}
+	vmType := req.VmType
+	if vmType == "" {
+		vmType = "t2.small"
+	}
+	if s.noCapacity[capacity{vmType, subregion}] || s.noCapacity[capacity{vmType, ""}] || s.noCapacity[capacity{"", subregion}] {
+		return nil, errCapacity("There is not enough capacity for the VmType '%s' in the Subregion '%s'.", vmType, subregion)
+	}
+
+	var securityGroups []osc.SecurityGroupLight
+	if len(nics) == 0 {
+		for _, id := range req.SecurityGroupIds {
+			sg := s.securityGroups[id]
+			securityGroups = append(securityGroups, osc.SecurityGroupLight{
+				SecurityGroupId:   sg.SecurityGroupId,
+				SecurityGroupName: sg.SecurityGroupName,
+			})
+		}
+	}
+
+	devices, err := s.launchDevices(region, image, req.BlockDeviceMappings)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := osc.CreateVmsResponse{ResponseContext: s.responseContext()}
+	for i := 0; i < count; i++ {
+		vm := &osc.Vm{
+			VmId:                        s.newID("i", region),
+			Architecture:                image.Architecture,
+			BsuOptimized:                req.BsuOptimized,
+			CreationDate:                now(),
+			Hypervisor:                  "xen",
+			ImageId:                     image.ImageId,
+			KeypairName:                 req.KeypairName,
+			LaunchNumber:                int32(i),
+			Performance:                 "high",
+			Placement:                   osc.Placement{SubregionName: subregion, Tenancy: "default"},
+			ProductCodes:                image.ProductCodes,
+			ReservationId:               fmt.Sprintf("r-%08x", s.lastID),
+			RootDeviceName:              image.RootDeviceName,
+			RootDeviceType:              "bsu",
+			SecurityGroups:              securityGroups,
+			State:                       "pending",
+			UserData:                    req.UserData,
+			VmInitiatedShutdownBehavior: req.VmInitiatedShutdownBehavior,
+			VmType:                      vmType,
+		}
+		if vm.VmInitiatedShutdownBehavior == "" {
+			vm.VmInitiatedShutdownBehavior = "stop"
+		}
+		if req.Performance != "" {
+			vm.Performance = req.Performance
+		}
+
+		if len(nics) > 0 {
+			var primaryIp string
+			if len(req.PrivateIps) > 0 {
+				primaryIp = req.PrivateIps[i]
+			}
+			s.attachNics(region, vm, nics, primaryIp)
+		} else {
+			vm.PrivateIp = nthHost("10.9.0.0/16", 4+s.lastID)
+		}
+		vm.PrivateDnsName = fmt.Sprintf("ip-%s.%s.compute.internal", dashed(vm.PrivateIp), region)
+
+		for _, device := range devices {
+			volume := s.newVolume(region, subregion, device.Bsu.SnapshotId, device.Bsu.VolumeSize, device.Bsu.VolumeType, device.Bsu.Iops)
+			volume.State = "in-use"
+			volume.LinkedVolumes = []osc.LinkedVolume{{
+				DeleteOnVmDeletion: device.Bsu.DeleteOnVmDeletion,
+				DeviceName:         device.DeviceName,
+				State:              "attached",
+				VmId:               vm.VmId,
+				VolumeId:           volume.VolumeId,
+			}}
+			vm.BlockDeviceMappings = append(vm.BlockDeviceMappings, osc.BlockDeviceMappingCreated{
+				DeviceName: device.DeviceName,
+				Bsu: osc.BsuCreated{
+					DeleteOnVmDeletion: device.Bsu.DeleteOnVmDeletion,
+					LinkDate:           now(),
+					State:              "attached",
+					VolumeId:           volume.VolumeId,
+				},
+			})
+		}
+
+		s.vms[vm.VmId] = vm
+		s.transition(vm.VmId, func() { vm.State = "running" })
+		resp.Vms = append(resp.Vms, *vm)
+	}
+
+	return resp, nil
+}
+
+// launchDevices returns the BSU volumes of the VM: the ones of the image,
+// updated by the block device mappings of the request.
+func (s *Server) launchDevices(region string, image *osc.Image, mappings []osc.BlockDeviceMappingVmCreation) ([]osc.BlockDeviceMappingImage, error) {
+	var devices []osc.BlockDeviceMappingImage
+	for _, mapping := range image.BlockDeviceMappings {
+		if mapping.VirtualDeviceName == "" {
+			devices = append(devices, mapping)
+		}
+	}
+
+	for _, mapping := range mappings {
+		if mapping.DeviceName == "" {
+			return nil, errMissing("BlockDeviceMappings.DeviceName")
+		}
+
+		i := 0
+		for i < len(devices) && devices[i].DeviceName != mapping.DeviceName {
+			i++
+		}
+		if mapping.NoDevice != "" || mapping.VirtualDeviceName != "" {
+			if i < len(devices) {
+				devices = append(devices[:i], devices[i+1:]...)
+			}
+			continue
+		}
+		if i == len(devices) {
+			devices = append(devices, osc.BlockDeviceMappingImage{DeviceName: mapping.DeviceName})
+		}
+
+		device := &devices[i]
+		device.Bsu.DeleteOnVmDeletion = mapping.Bsu.DeleteOnVmDeletion
+		if mapping.Bsu.SnapshotId != "" {
+			device.Bsu.SnapshotId = mapping.Bsu.SnapshotId
+		}
+		if mapping.Bsu.VolumeSize != 0 {
+			device.Bsu.VolumeSize = mapping.Bsu.VolumeSize
+		}
+		if mapping.Bsu.VolumeType != "" {
+			device.Bsu.VolumeType = mapping.Bsu.VolumeType
+		}
+		if mapping.Bsu.Iops != 0 {
+			device.Bsu.Iops = mapping.Bsu.Iops
+		}
+	}
+
+	for i, device := range devices {
+		if device.Bsu.SnapshotId != "" {
+			snapshot, err := s.snapshot(region, device.Bsu.SnapshotId)
+			if err != nil {
+				return nil, err
+			}
+			if device.Bsu.VolumeSize == 0 {
+				devices[i].Bsu.VolumeSize = snapshot.VolumeSize
+			} else if device.Bsu.VolumeSize < snapshot.VolumeSize {
+				return nil, errInvalid("The volume of %s cannot be smaller than its snapshot.", device.DeviceName)
+			}
+		} else if device.Bsu.VolumeSize == 0 {
+			return nil, errMissing("BlockDeviceMappings.Bsu.VolumeSize")
+		}
+	}
+
+	return devices, nil
+}
+
+func (s *Server) readVms(region string, body []byte) (interface{}, error) {
+	var req osc.ReadVmsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadVmsResponse{ResponseContext: s.responseContext(), Vms: []osc.Vm{}}
+	for _, id := range s.ids("i", region) {
+		if !matches(f.VmIds, id) || !s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+		s.observe(id)
+
+		vm := *s.vms[id]
+		vm.Tags = s.tagsOf(id)
+		resp.Vms = append(resp.Vms, vm)
+	}
+	return resp, nil
+}
+
+func (s *Server) stopVms(region string, body []byte) (interface{}, error) {
+	var req osc.StopVmsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if len(req.VmIds) == 0 {
+		return nil, errMissing("VmIds")
+	}
+
+	var vms []*osc.Vm
+	for _, id := range req.VmIds {
+		vm, err := s.vm(region, id)
+		if err != nil {
+			return nil, err
+		}
+		switch vm.State {
+		case "running", "stopping", "stopped":
+		default:
+			return nil, errState("The VmId '%s' cannot be stopped in the '%s' state.", id, vm.State)
+		}
+		vms = append(vms, vm)
+	}
+
+	resp := osc.StopVmsResponse{ResponseContext: s.responseContext()}
+	for _, vm := range vms {
+		previous := vm.State
+		if vm.State == "running" {
+			vm := vm
+			vm.State = "stopping"
+			s.transition(vm.VmId, func() { vm.State = "stopped" })
+		}
+		resp.Vms = append(resp.Vms, osc.VmState{VmId: vm.VmId, PreviousState: previous, CurrentState: vm.State})
+	}
+	return resp, nil
+}
+
+func (s *Server) deleteVms(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteVmsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if len(req.VmIds) == 0 {
+		return nil, errMissing("VmIds")
+	}
+
+	var vms []*osc.Vm
+	for _, id := range req.VmIds {
+		vm, err := s.vm(region, id)
+		if err != nil {
+			return nil, err
+		}
+		vms = append(vms, vm)
+	}
+
+	resp := osc.DeleteVmsResponse{ResponseContext: s.responseContext()}
+	for _, vm := range vms {
+		previous := vm.State
+		if vm.State != "shutting-down" && vm.State != "terminated" {
+			vm := vm
+			vm.State = "shutting-down"
+			s.transition(vm.VmId, func() { s.terminate(vm) })
+		}
+		resp.Vms = append(resp.Vms, osc.VmState{VmId: vm.VmId, PreviousState: previous, CurrentState: vm.State})
+	}
+	return resp, nil
+}
+
+// terminate deletes the volumes of the VM which are deleted with it, and
+// unlinks the other ones and its public IP. The VM stays readable.
+func (s *Server) terminate(vm *osc.Vm) {
+	vm.State = "terminated"
+
+	for _, mapping := range vm.BlockDeviceMappings {
+		volume, ok := s.volumes[mapping.Bsu.VolumeId]
+		if !ok {
+			continue
+		}
+		if mapping.Bsu.DeleteOnVmDeletion {
+			delete(s.volumes, volume.VolumeId)
+			s.forget(volume.VolumeId)
+			continue
+		}
+		volume.LinkedVolumes = nil
+		volume.State = "available"
+	}
+	vm.BlockDeviceMappings = nil
+
+	for _, ip := range s.publicIps {
+		if ip.VmId == vm.VmId {
+			unlinkPublicIp(ip)
+		}
+	}
+	vm.PublicIp = ""
+	vm.PublicDnsName = ""
+
+	s.releasePrivateIps(vm.VmId)
+}
+
+func (s *Server) vm(region, id string) (*osc.Vm, error) {
+	vm, ok := s.vms[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("VmId", id)
+	}
+	return vm, nil
+}
+
+// Vms returns the VMs of every region, including the terminated ones.
+func (s *Server) Vms() []osc.Vm {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var vms []osc.Vm
+	for _, id := range s.ids("i", "") {
+		vm := *s.vms[id]
+		vm.Tags = s.tagsOf(id)
+		vms = append(vms, vm)
+	}
+	return vms
+}
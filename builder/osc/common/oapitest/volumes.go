@@ -0,0 +1,219 @@
+package oapitest
+
+import (
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func (s *Server) createVolume(region string, body []byte) (interface{}, error) {
+	var req osc.CreateVolumeRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.SubregionName == "" {
+		return nil, errMissing("SubregionName")
+	}
+
+	size := req.Size
+	if req.SnapshotId != "" {
+		snapshot, err := s.snapshot(region, req.SnapshotId)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot.State != "completed" {
+			return nil, errState("The SnapshotId '%s' is in the '%s' state.", snapshot.SnapshotId, snapshot.State)
+		}
+		if size == 0 {
+			size = snapshot.VolumeSize
+		} else if size < snapshot.VolumeSize {
+			return nil, errInvalid("The volume cannot be smaller than the SnapshotId '%s'.", snapshot.SnapshotId)
+		}
+	} else if size == 0 {
+		return nil, errMissing("Size")
+	}
+
+	volume := s.newVolume(region, req.SubregionName, req.SnapshotId, size, req.VolumeType, req.Iops)
+	volume.State = "creating"
+	s.transition(volume.VolumeId, func() { volume.State = "available" })
+
+	return osc.CreateVolumeResponse{ResponseContext: s.responseContext(), Volume: *volume}, nil
+}
+
+func (s *Server) newVolume(region, subregion, snapshotID string, size int32, volumeType string, iops int32) *osc.Volume {
+	if volumeType == "" {
+		volumeType = "standard"
+	}
+	volume := &osc.Volume{
+		VolumeId:      s.newID("vol", region),
+		Iops:          iops,
+		Size:          size,
+		SnapshotId:    snapshotID,
+		SubregionName: subregion,
+		VolumeType:    volumeType,
+	}
+	s.volumes[volume.VolumeId] = volume
+	return volume
+}
+
+func (s *Server) readVolumes(region string, body []byte) (interface{}, error) {
+	var req osc.ReadVolumesRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	f := req.Filters
+
+	resp := osc.ReadVolumesResponse{ResponseContext: s.responseContext(), Volumes: []osc.Volume{}}
+	for _, id := range s.ids("vol", region) {
+		volume := s.volumes[id]
+
+		var vmIds, linkStates, deviceNames []string
+		for _, link := range volume.LinkedVolumes {
+			vmIds = append(vmIds, link.VmId)
+			linkStates = append(linkStates, link.State)
+			deviceNames = append(deviceNames, link.DeviceName)
+		}
+
+		if !matches(f.VolumeIds, id) || !matches(f.SnapshotIds, volume.SnapshotId) ||
+			!matches(f.SubregionNames, volume.SubregionName) || !matches(f.VolumeTypes, volume.VolumeType) ||
+			!matchesAny(f.LinkVolumeVmIds, vmIds) || !matchesAny(f.LinkVolumeLinkStates, linkStates) ||
+			!matchesAny(f.LinkVolumeDeviceNames, deviceNames) ||
+			!s.matchesTags(id, f.TagKeys, f.TagValues, f.Tags) {
+			continue
+		}
+		s.observe(id)
+		if !matches(f.VolumeStates, volume.State) {
+			continue
+		}
+
+		v := *volume
+		v.Tags = s.tagsOf(id)
+		resp.Volumes = append(resp.Volumes, v)
+	}
+	return resp, nil
+}
+
+func (s *Server) linkVolume(region string, body []byte) (interface{}, error) {
+	var req osc.LinkVolumeRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.DeviceName == "" {
+		return nil, errMissing("DeviceName")
+	}
+
+	volume, err := s.volume(region, req.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := s.vm(region, req.VmId)
+	if err != nil {
+		return nil, err
+	}
+	if volume.State != "available" {
+		return nil, errState("The VolumeId '%s' is in the '%s' state.", volume.VolumeId, volume.State)
+	}
+	if vm.State == "shutting-down" || vm.State == "terminated" {
+		return nil, errState("The VmId '%s' is in the '%s' state.", vm.VmId, vm.State)
+	}
+	if vm.Placement.SubregionName != volume.SubregionName {
+		return nil, errInvalid("The VolumeId '%s' and the VmId '%s' are not in the same Subregion.", volume.VolumeId, vm.VmId)
+	}
+	for _, mapping := range vm.BlockDeviceMappings {
+		if mapping.DeviceName == req.DeviceName {
+			return nil, errConflict("The device '%s' is already used on the VmId '%s'.", req.DeviceName, vm.VmId)
+		}
+	}
+
+	volume.State = "in-use"
+	volume.LinkedVolumes = []osc.LinkedVolume{{
+		DeviceName: req.DeviceName,
+		State:      "attaching",
+		VmId:       vm.VmId,
+		VolumeId:   volume.VolumeId,
+	}}
+	vm.BlockDeviceMappings = append(vm.BlockDeviceMappings, osc.BlockDeviceMappingCreated{
+		DeviceName: req.DeviceName,
+		Bsu:        osc.BsuCreated{LinkDate: now(), State: "attaching", VolumeId: volume.VolumeId},
+	})
+	s.transition(volume.VolumeId, func() {
+		volume.LinkedVolumes[0].State = "attached"
+		for i := range vm.BlockDeviceMappings {
+			if vm.BlockDeviceMappings[i].Bsu.VolumeId == volume.VolumeId {
+				vm.BlockDeviceMappings[i].Bsu.State = "attached"
+			}
+		}
+	})
+
+	return osc.LinkVolumeResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) unlinkVolume(region string, body []byte) (interface{}, error) {
+	var req osc.UnlinkVolumeRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	volume, err := s.volume(region, req.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	if len(volume.LinkedVolumes) == 0 {
+		return nil, errState("The VolumeId '%s' is not linked.", volume.VolumeId)
+	}
+
+	volume.LinkedVolumes[0].State = "detaching"
+	vm := s.vms[volume.LinkedVolumes[0].VmId]
+	s.transition(volume.VolumeId, func() {
+		volume.LinkedVolumes = nil
+		volume.State = "available"
+		var mappings []osc.BlockDeviceMappingCreated
+		for _, mapping := range vm.BlockDeviceMappings {
+			if mapping.Bsu.VolumeId != volume.VolumeId {
+				mappings = append(mappings, mapping)
+			}
+		}
+		vm.BlockDeviceMappings = mappings
+	})
+
+	return osc.UnlinkVolumeResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) deleteVolume(region string, body []byte) (interface{}, error) {
+	var req osc.DeleteVolumeRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+
+	volume, err := s.volume(region, req.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	if volume.State != "available" && volume.State != "error" {
+		return nil, errState("The VolumeId '%s' is in the '%s' state.", volume.VolumeId, volume.State)
+	}
+
+	delete(s.volumes, volume.VolumeId)
+	s.forget(volume.VolumeId)
+	return osc.DeleteVolumeResponse{ResponseContext: s.responseContext()}, nil
+}
+
+func (s *Server) volume(region, id string) (*osc.Volume, error) {
+	volume, ok := s.volumes[id]
+	if !ok || s.regions[id] != region {
+		return nil, errNotFound("VolumeId", id)
+	}
+	return volume, nil
+}
+
+// Volumes returns the volumes of every region.
+func (s *Server) Volumes() []osc.Volume {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var volumes []osc.Volume
+	for _, id := range s.ids("vol", "") {
+		volume := *s.volumes[id]
+		volume.Tags = s.tagsOf(id)
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
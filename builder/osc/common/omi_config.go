@@ -3,26 +3,84 @@ package common
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 )
 
+// The policies applied when an OMI with the same name as the one being built
+// already exists.
+const (
+	OMINameConflictFail       = "fail"
+	OMINameConflictDeregister = "deregister"
+	OMINameConflictRenameOld  = "rename-old"
+	OMINameConflictSuffix     = "suffix"
+)
+
+// OMIRetentionConfig describes the OMIs pruned after a successful build: the
+// OMIs of the account having both the name prefix and the tags, in the
+// session region and in omi_regions, that are neither among the newest ones
+// nor young enough.
+type OMIRetentionConfig struct {
+	// The name prefix of the OMIs to prune, such as `web-`. It is
+	// interpolated in each region.
+	NamePrefix string `mapstructure:"name_prefix"`
+	// The tags of the OMIs to prune. An OMI must have all of them, as well as
+	// the name prefix. They are interpolated in each region.
+	Tags TagMap `mapstructure:"tags"`
+	// How many of the newest OMIs to keep in each region, the new one
+	// included.
+	KeepLatest int `mapstructure:"keep_latest"`
+	// The age under which OMIs are kept, such as `720h`. With `keep_latest`,
+	// an OMI is kept if either of them keeps it.
+	KeepYoungerThan string `mapstructure:"keep_younger_than"`
+	// Only list the OMIs that would be deregistered.
+	DryRun bool `mapstructure:"dry_run"`
+
+	keepYoungerThan time.Duration
+}
+
+func (c *OMIRetentionConfig) Prepare() []error {
+	var errs []error
+
+	if c.NamePrefix == "" && len(c.Tags) == 0 {
+		errs = append(errs, fmt.Errorf("omi_retention: name_prefix or tags must be specified"))
+	}
+	if c.KeepLatest < 0 {
+		errs = append(errs, fmt.Errorf("omi_retention: keep_latest must be positive"))
+	}
+	if c.KeepYoungerThan != "" {
+		d, err := time.ParseDuration(c.KeepYoungerThan)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("omi_retention: keep_younger_than must be a positive duration, such as 720h: %q", c.KeepYoungerThan))
+		}
+		c.keepYoungerThan = d
+	}
+	if c.KeepLatest == 0 && c.KeepYoungerThan == "" {
+		errs = append(errs, fmt.Errorf("omi_retention: keep_latest or keep_younger_than must be specified"))
+	}
+
+	return errs
+}
+
 // OMIConfig is for common configuration related to creating OMIs.
 type OMIConfig struct {
-	OMIName                 string   `mapstructure:"omi_name"`
-	OMIDescription          string   `mapstructure:"omi_description"`
-	OMIAccountIDs           []string `mapstructure:"omi_account_ids"`
-	OMIGroups               []string `mapstructure:"omi_groups"`
-	OMIProductCodes         []string `mapstructure:"omi_product_codes"`
-	OMIRegions              []string `mapstructure:"omi_regions"`
-	OMISkipRegionValidation bool     `mapstructure:"skip_region_validation"`
-	OMITags                 TagMap   `mapstructure:"tags"`
-	OMIForceDeregister      bool     `mapstructure:"force_deregister"`
-	OMIForceDeleteSnapshot  bool     `mapstructure:"force_delete_snapshot"`
-	SnapshotTags            TagMap   `mapstructure:"snapshot_tags"`
-	SnapshotAccountIDs      []string `mapstructure:"snapshot_account_ids"`
-	SnapshotGroups          []string `mapstructure:"snapshot_groups"`
-	GlobalPermission        bool     `mapstructure:"global_permission"`
+	OMIName                 string              `mapstructure:"omi_name"`
+	OMIDescription          string              `mapstructure:"omi_description"`
+	OMIAccountIDs           []string            `mapstructure:"omi_account_ids"`
+	OMIGroups               []string            `mapstructure:"omi_groups"`
+	OMIProductCodes         []string            `mapstructure:"omi_product_codes"`
+	OMIRegions              []string            `mapstructure:"omi_regions"`
+	OMISkipRegionValidation bool                `mapstructure:"skip_region_validation"`
+	OMITags                 TagMap              `mapstructure:"tags"`
+	OMIForceDeregister      bool                `mapstructure:"force_deregister"`
+	OMIForceDeleteSnapshot  bool                `mapstructure:"force_delete_snapshot"`
+	OMINameConflict         string              `mapstructure:"omi_name_conflict"`
+	OMIRetention            *OMIRetentionConfig `mapstructure:"omi_retention"`
+	SnapshotTags            TagMap              `mapstructure:"snapshot_tags"`
+	SnapshotAccountIDs      []string            `mapstructure:"snapshot_account_ids"`
+	SnapshotGroups          []string            `mapstructure:"snapshot_groups"`
+	GlobalPermission        bool                `mapstructure:"global_permission"`
 }
 
 func (c *OMIConfig) Prepare(accessConfig *AccessConfig, ctx *interpolate.Context) []error {
@@ -46,6 +104,23 @@ func (c *OMIConfig) Prepare(accessConfig *AccessConfig, ctx *interpolate.Context
 			"filter to automatically clean your omi name."))
 	}
 
+	errs = append(errs, c.prepareNameConflict()...)
+
+	for _, group := range c.OMIGroups {
+		if group != groupAll {
+			errs = append(errs, fmt.Errorf("omi_groups only supports the %q group, got %q", groupAll, group))
+		}
+	}
+	for _, group := range c.SnapshotGroups {
+		if group != groupAll {
+			errs = append(errs, fmt.Errorf("snapshot_groups only supports the %q group, got %q", groupAll, group))
+		}
+	}
+
+	if c.OMIRetention != nil {
+		errs = append(errs, c.OMIRetention.Prepare()...)
+	}
+
 	if len(errs) > 0 {
 		return errs
 	}
@@ -53,6 +128,28 @@ func (c *OMIConfig) Prepare(accessConfig *AccessConfig, ctx *interpolate.Context
 	return nil
 }
 
+// prepareNameConflict defaults omi_name_conflict to fail, or to deregister
+// when force_deregister is set.
+func (c *OMIConfig) prepareNameConflict() []error {
+	switch c.OMINameConflict {
+	case "":
+		c.OMINameConflict = OMINameConflictFail
+		if c.OMIForceDeregister {
+			c.OMINameConflict = OMINameConflictDeregister
+		}
+	case OMINameConflictDeregister:
+		c.OMIForceDeregister = true
+	case OMINameConflictFail, OMINameConflictRenameOld, OMINameConflictSuffix:
+		if c.OMIForceDeregister {
+			return []error{fmt.Errorf("force_deregister cannot be used with omi_name_conflict %s", c.OMINameConflict)}
+		}
+	default:
+		return []error{fmt.Errorf("omi_name_conflict must be one of %s, %s, %s or %s, got %q",
+			OMINameConflictFail, OMINameConflictDeregister, OMINameConflictRenameOld, OMINameConflictSuffix, c.OMINameConflict)}
+	}
+	return nil
+}
+
 func (c *OMIConfig) prepareRegions(accessConfig *AccessConfig) (errs []error) {
 	if len(c.OMIRegions) > 0 {
 		regionSet := make(map[string]struct{})
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func testOMIConfig() *OMIConfig {
@@ -107,6 +108,80 @@ func TestOMIConfigPrepare_regions(t *testing.T) {
 
 }
 
+func TestOMIConfigPrepare_nameConflict(t *testing.T) {
+	accessConf := testAccessConfig()
+
+	c := testOMIConfig()
+	if err := c.Prepare(accessConf, nil); err != nil || c.OMINameConflict != OMINameConflictFail {
+		t.Fatalf("omi_name_conflict should default to fail, got %q: %v", c.OMINameConflict, err)
+	}
+
+	c = testOMIConfig()
+	c.OMIForceDeregister = true
+	if err := c.Prepare(accessConf, nil); err != nil || c.OMINameConflict != OMINameConflictDeregister {
+		t.Fatalf("force_deregister should be the deregister policy, got %q: %v", c.OMINameConflict, err)
+	}
+
+	c = testOMIConfig()
+	c.OMINameConflict = OMINameConflictSuffix
+	if err := c.Prepare(accessConf, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.OMIForceDeregister = true
+	if err := c.Prepare(accessConf, nil); err == nil {
+		t.Fatal("should have error when force_deregister is used with another policy")
+	}
+
+	c = testOMIConfig()
+	c.OMINameConflict = "overwrite"
+	if err := c.Prepare(accessConf, nil); err == nil {
+		t.Fatal("should have error with an unknown policy")
+	}
+}
+
+func TestOMIConfigPrepare_retention(t *testing.T) {
+	accessConf := testAccessConfig()
+
+	c := testOMIConfig()
+	c.OMIRetention = &OMIRetentionConfig{NamePrefix: "web-", KeepYoungerThan: "720h"}
+	if err := c.Prepare(accessConf, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if c.OMIRetention.keepYoungerThan != 720*time.Hour {
+		t.Fatalf("keep_younger_than should be parsed, got %s", c.OMIRetention.keepYoungerThan)
+	}
+
+	for _, retention := range []*OMIRetentionConfig{
+		{KeepLatest: 3},
+		{NamePrefix: "web-"},
+		{NamePrefix: "web-", KeepLatest: -1},
+		{Tags: TagMap{"family": "web"}, KeepYoungerThan: "30d"},
+	} {
+		c = testOMIConfig()
+		c.OMIRetention = retention
+		if err := c.Prepare(accessConf, nil); err == nil {
+			t.Fatalf("should have error with %#v", retention)
+		}
+	}
+}
+
+func TestOMIConfigPrepare_groups(t *testing.T) {
+	accessConf := testAccessConfig()
+
+	c := testOMIConfig()
+	c.OMIGroups = []string{"all"}
+	c.SnapshotGroups = []string{"all"}
+	if err := c.Prepare(accessConf, nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.SnapshotGroups = []string{"admins"}
+	if err := c.Prepare(accessConf, nil); err == nil {
+		t.Fatal("should have error with another group than all")
+	}
+}
+
 func TestOMINameValidation(t *testing.T) {
 	c := testOMIConfig()
 
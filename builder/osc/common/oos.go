@@ -0,0 +1,29 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// oosEndpoint returns the endpoint of the Outscale Object Storage (OOS) of the
+// region, on the same domain as the OAPI endpoint.
+func (c *AccessConfig) oosEndpoint(region string) string {
+	return fmt.Sprintf("https://oos.%s.%s", region, c.endpointDomain())
+}
+
+// NewOOSSessionByRegion returns a session for the S3-compatible API of the
+// Outscale Object Storage in the given region. NewOSCClient must have been
+// called first so that the credentials are resolved.
+func (c *AccessConfig) NewOOSSessionByRegion(region string) (*session.Session, error) {
+	return session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(c.AccessKey, c.SecretKey, c.Token),
+		Endpoint:         aws.String(c.oosEndpoint(region)),
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(true),
+		HTTPClient:       &http.Client{Transport: c.httpTransport()},
+	})
+}
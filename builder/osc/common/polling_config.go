@@ -0,0 +1,100 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const defaultPollDelaySeconds = 2
+
+// PollingConfig defines how Packer polls the OUTSCALE API while waiting for a
+// resource, such as a VM, a snapshot or an OMI, to reach a given state.
+//
+// The settings which are not set in the template are read from the
+// OSC_POLL_DELAY_SECONDS, OSC_MAX_ATTEMPTS and OSC_POLL_TIMEOUT environment
+// variables.
+type PollingConfig struct {
+	// The number of seconds between two polls. Defaults to 2.
+	DelaySeconds int `mapstructure:"delay_seconds"`
+	// The maximum number of polls before giving up. Defaults to 0, for no
+	// limit other than `timeout`.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// How long to wait for a resource overall, such as `30m` or `2h`.
+	// Defaults to 0, for no limit other than `max_attempts`.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func (c *PollingConfig) Prepare() []error {
+	var errs []error
+
+	if c.DelaySeconds == 0 {
+		if value, ok := getValueFromEnvVariables([]string{"OSC_POLL_DELAY_SECONDS"}); ok {
+			delay, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("OSC_POLL_DELAY_SECONDS must be an integer: %s", err))
+			}
+			c.DelaySeconds = delay
+		}
+	}
+
+	if c.MaxAttempts == 0 {
+		if value, ok := getValueFromEnvVariables([]string{"OSC_MAX_ATTEMPTS"}); ok {
+			attempts, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("OSC_MAX_ATTEMPTS must be an integer: %s", err))
+			}
+			c.MaxAttempts = attempts
+		}
+	}
+
+	if c.Timeout == 0 {
+		if value, ok := getValueFromEnvVariables([]string{"OSC_POLL_TIMEOUT"}); ok {
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("OSC_POLL_TIMEOUT must be a duration: %s", err))
+			}
+			c.Timeout = timeout
+		}
+	}
+
+	if c.DelaySeconds < 0 {
+		errs = append(errs, fmt.Errorf("delay_seconds must be positive"))
+	}
+	if c.MaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("max_attempts must be positive"))
+	}
+	if c.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout must be positive"))
+	}
+
+	if c.DelaySeconds == 0 {
+		c.DelaySeconds = defaultPollDelaySeconds
+	}
+
+	return errs
+}
+
+// The getters below fall back on the defaults so that a nil or unprepared
+// PollingConfig can still be used by the waiters.
+
+func (c *PollingConfig) delay() time.Duration {
+	if c == nil || c.DelaySeconds <= 0 {
+		return defaultPollDelaySeconds * time.Second
+	}
+	return time.Duration(c.DelaySeconds) * time.Second
+}
+
+func (c *PollingConfig) maxAttempts() int {
+	if c == nil {
+		return 0
+	}
+	return c.MaxAttempts
+}
+
+func (c *PollingConfig) timeout() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.Timeout
+}
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPollingConfigPrepare_defaults(t *testing.T) {
+	c := PollingConfig{}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("should not error: %v", errs)
+	}
+
+	if c.DelaySeconds != defaultPollDelaySeconds || c.MaxAttempts != 0 || c.Timeout != 0 {
+		t.Fatalf("bad defaults: %#v", c)
+	}
+}
+
+func TestPollingConfigPrepare_env(t *testing.T) {
+	t.Setenv("OSC_POLL_DELAY_SECONDS", "5")
+	t.Setenv("OSC_MAX_ATTEMPTS", "10")
+	t.Setenv("OSC_POLL_TIMEOUT", "30m")
+
+	c := PollingConfig{}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("should not error: %v", errs)
+	}
+	if c.DelaySeconds != 5 || c.MaxAttempts != 10 || c.Timeout != 30*time.Minute {
+		t.Fatalf("environment not taken into account: %#v", c)
+	}
+
+	c = PollingConfig{DelaySeconds: 1, MaxAttempts: 3, Timeout: time.Minute}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("should not error: %v", errs)
+	}
+	if c.DelaySeconds != 1 || c.MaxAttempts != 3 || c.Timeout != time.Minute {
+		t.Fatalf("template should take precedence over the environment: %#v", c)
+	}
+
+	t.Setenv("OSC_POLL_TIMEOUT", "forever")
+	if errs := (&PollingConfig{}).Prepare(); len(errs) != 1 {
+		t.Fatalf("should error on a bad OSC_POLL_TIMEOUT, got: %v", errs)
+	}
+}
+
+func TestPollingConfigPrepare_negative(t *testing.T) {
+	c := PollingConfig{DelaySeconds: -1, MaxAttempts: -1, Timeout: -time.Second}
+	if errs := c.Prepare(); len(errs) != 3 {
+		t.Fatalf("should error on negative values, got: %v", errs)
+	}
+}
+
+// countingRefresh returns the given states in turn, then the last one forever.
+func countingRefresh(calls *int, states ...string) stateRefreshFunc {
+	return func() (string, error) {
+		state := states[len(states)-1]
+		if *calls < len(states) {
+			state = states[*calls]
+		}
+		*calls++
+		return state, nil
+	}
+}
+
+func TestWaitForState(t *testing.T) {
+	var calls int
+	c := &PollingConfig{DelaySeconds: 1, Timeout: time.Minute}
+	if err := c.waitForState(context.Background(), "available", countingRefresh(&calls, "pending", "available")); err != nil {
+		t.Fatalf("should not error: %s", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 polls, got %d", calls)
+	}
+}
+
+func TestWaitForState_maxAttempts(t *testing.T) {
+	var calls int
+	c := &PollingConfig{MaxAttempts: 1}
+	if err := c.waitForState(context.Background(), "available", countingRefresh(&calls, "pending")); err == nil {
+		t.Fatal("should error once the attempts are exhausted")
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 poll, got %d", calls)
+	}
+}
+
+func TestWaitForState_timeout(t *testing.T) {
+	var calls int
+	c := &PollingConfig{Timeout: 10 * time.Millisecond}
+	if err := c.waitForState(context.Background(), "available", countingRefresh(&calls, "pending")); err == nil {
+		t.Fatal("should error on timeout")
+	}
+}
+
+func TestWaitForState_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var calls int
+	var c *PollingConfig
+	if err := c.waitForState(ctx, "available", countingRefresh(&calls, "pending")); err != context.Canceled {
+		t.Fatalf("should return the cancellation, got: %v", err)
+	}
+}
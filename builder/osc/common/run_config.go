@@ -1,10 +1,11 @@
-//go:generate packer-sdc mapstructure-to-hcl2 -type SecurityGroupFilterOptions,OmiFilterOptions,SubnetFilterOptions,NetFilterOptions,BlockDevice
+//go:generate packer-sdc mapstructure-to-hcl2 -type PollingConfig,SecurityGroupFilterOptions,PublicIpFilterOptions,OmiFilterOptions,SubnetFilterOptions,NetFilterOptions,TemporaryNetConfig,SecurityGroupRule,NicConfig,BlockDevice,OMIRetentionConfig
 
 package common
 
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"regexp"
 	"strings"
@@ -56,36 +57,229 @@ type SecurityGroupFilterOptions struct {
 	config.NameValueFilter `mapstructure:",squash"`
 }
 
+// docs at https://docs.outscale.com/api#tocsfilterspublicip
+type PublicIpFilterOptions struct {
+	config.NameValueFilter `mapstructure:",squash"`
+}
+
+// TemporaryNetConfig describes the Net created for the build, and deleted
+// with everything in it once the build is done, when no Net nor Subnet is
+// given.
+//
+// The VM is launched in a Subnet routed to the internet through an internet
+// service or, for private builds, through a NAT service living in another,
+// public, Subnet.
+type TemporaryNetConfig struct {
+	// The IP range of the Net. Defaults to `10.0.0.0/16`.
+	IpRange string `mapstructure:"ip_range"`
+	// The IP range of the Subnet of the VM, within `ip_range`. Defaults to
+	// `10.0.0.0/24`.
+	SubnetIpRange string `mapstructure:"subnet_ip_range"`
+	// Whether the Subnet of the VM is private, reaching the internet through a
+	// NAT service. Packer must then reach the VM through its private IP.
+	Private bool `mapstructure:"private"`
+	// The IP range of the public Subnet of the NAT service, within `ip_range`.
+	// Defaults to `10.0.1.0/24`.
+	NatSubnetIpRange string `mapstructure:"nat_subnet_ip_range"`
+	// The tags of the Net and of every resource created in it.
+	Tags TagMap `mapstructure:"tags"`
+}
+
+func (c *TemporaryNetConfig) Prepare() []error {
+	var errs []error
+
+	if c.IpRange == "" {
+		c.IpRange = "10.0.0.0/16"
+	}
+	if c.SubnetIpRange == "" {
+		c.SubnetIpRange = "10.0.0.0/24"
+	}
+	if c.Private && c.NatSubnetIpRange == "" {
+		c.NatSubnetIpRange = "10.0.1.0/24"
+	}
+
+	_, netRange, err := net.ParseCIDR(c.IpRange)
+	if err != nil {
+		return append(errs, fmt.Errorf("Error parsing temporary_net ip_range: %s", err))
+	}
+
+	ranges := []struct{ name, ipRange string }{{"subnet_ip_range", c.SubnetIpRange}}
+	if c.Private {
+		ranges = append(ranges, struct{ name, ipRange string }{"nat_subnet_ip_range", c.NatSubnetIpRange})
+	}
+	var subnets []*net.IPNet
+	for _, r := range ranges {
+		_, subnetRange, err := net.ParseCIDR(r.ipRange)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error parsing temporary_net %s: %s", r.name, err))
+			continue
+		}
+		netOnes, _ := netRange.Mask.Size()
+		if ones, _ := subnetRange.Mask.Size(); !netRange.Contains(subnetRange.IP) || ones < netOnes {
+			errs = append(errs, fmt.Errorf("temporary_net %s %s must be within ip_range %s", r.name, r.ipRange, c.IpRange))
+			continue
+		}
+		subnets = append(subnets, subnetRange)
+	}
+	if len(subnets) == 2 && (subnets[0].Contains(subnets[1].IP) || subnets[1].Contains(subnets[0].IP)) {
+		errs = append(errs, fmt.Errorf("temporary_net subnet_ip_range and nat_subnet_ip_range must not overlap"))
+	}
+
+	return errs
+}
+
+// SecurityGroupRule is an extra rule of the temporary security group.
+type SecurityGroupRule struct {
+	// The protocol of the rule: `tcp`, `udp`, `icmp` or `-1` for every
+	// protocol.
+	Protocol string `mapstructure:"protocol"`
+	// The first port of the range, or the ICMP type for `icmp`.
+	FromPort int `mapstructure:"from_port"`
+	// The last port of the range, or the ICMP code for `icmp`. Defaults to
+	// `from_port`.
+	ToPort int `mapstructure:"to_port"`
+	// The IP ranges the traffic comes from for inbound rules, or goes to for
+	// outbound rules. Inbound rules default to the source CIDRs of the
+	// temporary security group.
+	IpRanges []string `mapstructure:"ip_ranges"`
+}
+
+func (r *SecurityGroupRule) Prepare(outbound bool) []error {
+	var errs []error
+
+	switch r.Protocol {
+	case "tcp", "udp":
+		if r.ToPort == 0 {
+			r.ToPort = r.FromPort
+		}
+		if r.FromPort < 1 || r.ToPort > 65535 || r.FromPort > r.ToPort {
+			errs = append(errs, fmt.Errorf("the %s port range %d-%d is invalid", r.Protocol, r.FromPort, r.ToPort))
+		}
+	case "icmp":
+		// Leaving both unset allows every ICMP message.
+		if r.FromPort == 0 && r.ToPort == 0 {
+			r.FromPort, r.ToPort = -1, -1
+		}
+		if r.FromPort < -1 || r.FromPort > 255 || r.ToPort < -1 || r.ToPort > 255 {
+			errs = append(errs, fmt.Errorf("the ICMP type %d or code %d is invalid", r.FromPort, r.ToPort))
+		}
+	case "-1":
+		r.FromPort, r.ToPort = -1, -1
+	default:
+		errs = append(errs, fmt.Errorf("the protocol %q is invalid, it must be one of tcp, udp, icmp or -1", r.Protocol))
+	}
+
+	if outbound && len(r.IpRanges) == 0 {
+		errs = append(errs, fmt.Errorf("ip_ranges must be set for outbound rules"))
+	}
+	for _, ipRange := range r.IpRanges {
+		if _, _, err := net.ParseCIDR(ipRange); err != nil {
+			errs = append(errs, fmt.Errorf("Error parsing ip_ranges: %s", err))
+		}
+	}
+
+	return errs
+}
+
+// NicConfig is an additional NIC of the VM.
+type NicConfig struct {
+	// The ID of the Subnet of the NIC, in the Net and the Subregion of the
+	// VM.
+	SubnetId string `mapstructure:"subnet_id"`
+	// The primary private IP of the NIC. Picked by Outscale when empty.
+	PrivateIp string `mapstructure:"private_ip"`
+	// The secondary private IPs of the NIC.
+	SecondaryPrivateIps []string `mapstructure:"secondary_private_ips"`
+	// The number of secondary private IPs picked by Outscale for the NIC.
+	SecondaryPrivateIpCount int `mapstructure:"secondary_private_ip_count"`
+	// The security groups of the NIC. Defaults to the security groups of the
+	// VM.
+	SecurityGroupIds []string `mapstructure:"security_group_ids"`
+	// The description of the NIC.
+	Description string `mapstructure:"description"`
+}
+
+func (n *NicConfig) Prepare() []error {
+	var errs []error
+
+	if n.SubnetId == "" {
+		errs = append(errs, fmt.Errorf("subnet_id must be set"))
+	}
+	errs = append(errs, checkPrivateIps(n.PrivateIp, n.SecondaryPrivateIps, n.SecondaryPrivateIpCount)...)
+
+	return errs
+}
+
+// checkPrivateIps checks the primary and secondary private IPs of a NIC.
+func checkPrivateIps(primary string, secondary []string, secondaryCount int) []error {
+	var errs []error
+
+	ips := secondary
+	if primary != "" {
+		ips = append([]string{primary}, secondary...)
+	}
+	seen := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		if parsed := net.ParseIP(ip); parsed == nil || parsed.To4() == nil {
+			errs = append(errs, fmt.Errorf("the private IP %q is not an IPv4", ip))
+		} else if seen[ip] {
+			errs = append(errs, fmt.Errorf("the private IP %s is given more than once", ip))
+		}
+		seen[ip] = true
+	}
+	if secondaryCount < 0 {
+		errs = append(errs, fmt.Errorf("secondary_private_ip_count must be positive"))
+	}
+
+	return errs
+}
+
 // RunConfig contains configuration for running an vm from a source
 // AMI and details on how to access that launched image.
 type RunConfig struct {
-	AssociatePublicIpAddress    bool                       `mapstructure:"associate_public_ip_address"`
-	Subregion                   string                     `mapstructure:"subregion_name"`
-	BlockDurationMinutes        int64                      `mapstructure:"block_duration_minutes"`
-	DisableStopVm               bool                       `mapstructure:"disable_stop_vm"`
-	BsuOptimized                bool                       `mapstructure:"bsu_optimized"`
-	EnableT2Unlimited           bool                       `mapstructure:"enable_t2_unlimited"`
-	IamVmProfile                string                     `mapstructure:"iam_vm_profile"`
-	VmInitiatedShutdownBehavior string                     `mapstructure:"shutdown_behavior"`
-	VmType                      string                     `mapstructure:"vm_type"`
-	SecurityGroupFilter         SecurityGroupFilterOptions `mapstructure:"security_group_filter"`
-	RunTags                     map[string]string          `mapstructure:"run_tags"`
-	SecurityGroupId             string                     `mapstructure:"security_group_id"`
-	SecurityGroupIds            []string                   `mapstructure:"security_group_ids"`
-	SourceOmi                   string                     `mapstructure:"source_omi"`
-	SourceOmiFilter             OmiFilterOptions           `mapstructure:"source_omi_filter"`
-	SpotPrice                   string                     `mapstructure:"spot_price"`
-	SpotPriceAutoProduct        string                     `mapstructure:"spot_price_auto_product"`
-	SpotTags                    map[string]string          `mapstructure:"spot_tags"`
-	SubnetFilter                SubnetFilterOptions        `mapstructure:"subnet_filter"`
-	SubnetId                    string                     `mapstructure:"subnet_id"`
-	TemporaryKeyPairName        string                     `mapstructure:"temporary_key_pair_name"`
-	TemporarySGSourceCidr       string                     `mapstructure:"temporary_security_group_source_cidr"`
-	UserData                    string                     `mapstructure:"user_data"`
-	UserDataFile                string                     `mapstructure:"user_data_file"`
-	NetFilter                   NetFilterOptions           `mapstructure:"net_filter"`
-	NetId                       string                     `mapstructure:"net_id"`
-	WindowsPasswordTimeout      time.Duration              `mapstructure:"windows_password_timeout"`
+	AssociatePublicIpAddress     bool                       `mapstructure:"associate_public_ip_address"`
+	Subregion                    string                     `mapstructure:"subregion_name"`
+	SubregionNames               []string                   `mapstructure:"subregion_names"`
+	BlockDurationMinutes         int64                      `mapstructure:"block_duration_minutes"`
+	DisableStopVm                bool                       `mapstructure:"disable_stop_vm"`
+	BsuOptimized                 bool                       `mapstructure:"bsu_optimized"`
+	EnableT2Unlimited            bool                       `mapstructure:"enable_t2_unlimited"`
+	IamVmProfile                 string                     `mapstructure:"iam_vm_profile"`
+	VmInitiatedShutdownBehavior  string                     `mapstructure:"shutdown_behavior"`
+	VmType                       string                     `mapstructure:"vm_type"`
+	VmTypes                      []string                   `mapstructure:"vm_types"`
+	SecurityGroupFilter          SecurityGroupFilterOptions `mapstructure:"security_group_filter"`
+	RunTags                      map[string]string          `mapstructure:"run_tags"`
+	SecurityGroupId              string                     `mapstructure:"security_group_id"`
+	SecurityGroupIds             []string                   `mapstructure:"security_group_ids"`
+	SourceOmi                    string                     `mapstructure:"source_omi"`
+	SourceOmiFilter              OmiFilterOptions           `mapstructure:"source_omi_filter"`
+	SpotPrice                    string                     `mapstructure:"spot_price"`
+	SpotPriceAutoProduct         string                     `mapstructure:"spot_price_auto_product"`
+	SpotTags                     map[string]string          `mapstructure:"spot_tags"`
+	SubnetFilter                 SubnetFilterOptions        `mapstructure:"subnet_filter"`
+	SubnetId                     string                     `mapstructure:"subnet_id"`
+	SubnetIds                    []string                   `mapstructure:"subnet_ids"`
+	TemporaryNet                 *TemporaryNetConfig        `mapstructure:"temporary_net"`
+	TemporaryKeyPairName         string                     `mapstructure:"temporary_key_pair_name"`
+	TemporarySGSourceCidr        string                     `mapstructure:"temporary_security_group_source_cidr"`
+	TemporarySGSourceCidrs       []string                   `mapstructure:"temporary_security_group_source_cidrs"`
+	TemporarySGSourcePublicIp    bool                       `mapstructure:"temporary_security_group_source_public_ip"`
+	TemporarySGSourcePublicIpURL string                     `mapstructure:"temporary_security_group_source_public_ip_url"`
+	TemporarySGRules             []SecurityGroupRule        `mapstructure:"temporary_security_group_rules"`
+	TemporarySGOutboundRules     []SecurityGroupRule        `mapstructure:"temporary_security_group_outbound_rules"`
+	TemporarySGTags              TagMap                     `mapstructure:"temporary_security_group_tags"`
+	UserData                     string                     `mapstructure:"user_data"`
+	UserDataFile                 string                     `mapstructure:"user_data_file"`
+	NetFilter                    NetFilterOptions           `mapstructure:"net_filter"`
+	NetId                        string                     `mapstructure:"net_id"`
+	PublicIpId                   string                     `mapstructure:"public_ip_id"`
+	PrivateIp                    string                     `mapstructure:"private_ip"`
+	SecondaryPrivateIps          []string                   `mapstructure:"secondary_private_ips"`
+	SecondaryPrivateIpCount      int                        `mapstructure:"secondary_private_ip_count"`
+	Nics                         []NicConfig                `mapstructure:"nics"`
+	PublicIpFilter               PublicIpFilterOptions      `mapstructure:"public_ip_filter"`
+	WindowsPasswordTimeout       time.Duration              `mapstructure:"windows_password_timeout"`
 
 	// Communicator settings
 	Comm         communicator.Config `mapstructure:",squash"`
@@ -148,10 +342,38 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		errs = append(errs, fmt.Errorf("For security reasons, your source AMI filter must declare an owner."))
 	}
 
+	if len(c.VmTypes) > 0 {
+		if c.VmType != "" {
+			errs = append(errs, fmt.Errorf("Only one of vm_type or vm_types can be specified."))
+		}
+		c.VmType = c.VmTypes[0]
+	}
 	if c.VmType == "" {
 		errs = append(errs, fmt.Errorf("An vm_type must be specified"))
 	}
 
+	if len(c.SubregionNames) > 0 {
+		if c.Subregion != "" {
+			errs = append(errs, fmt.Errorf("Only one of subregion_name or subregion_names can be specified."))
+		}
+		if c.SubnetId != "" || len(c.SubnetIds) > 0 || !c.SubnetFilter.Empty() || c.TemporaryNet != nil {
+			errs = append(errs, fmt.Errorf("subregion_names cannot be used with subnet_id, subnet_ids, subnet_filter or temporary_net, as the Subnet gives the Subregion of the VM."))
+		}
+		if c.NetId != "" || !c.NetFilter.Empty() {
+			errs = append(errs, fmt.Errorf("subregion_names cannot be used with net_id or net_filter, as a VM in a Net needs a Subnet."))
+		}
+		c.Subregion = c.SubregionNames[0]
+	}
+	if len(c.SubnetIds) > 0 {
+		if c.SubnetId != "" || !c.SubnetFilter.Empty() || c.TemporaryNet != nil {
+			errs = append(errs, fmt.Errorf("subnet_ids cannot be used with subnet_id, subnet_filter or temporary_net."))
+		}
+		if c.PrivateIp != "" || len(c.SecondaryPrivateIps) > 0 || c.SecondaryPrivateIpCount != 0 || len(c.Nics) > 0 {
+			errs = append(errs, fmt.Errorf("subnet_ids cannot be used with private_ip, secondary_private_ips, secondary_private_ip_count or nics, which are bound to a Subnet."))
+		}
+		c.SubnetId = c.SubnetIds[0]
+	}
+
 	if c.BlockDurationMinutes%60 != 0 {
 		errs = append(errs, fmt.Errorf(
 			"block_duration_minutes must be multiple of 60"))
@@ -195,11 +417,71 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
-	if c.TemporarySGSourceCidr == "" {
-		c.TemporarySGSourceCidr = "0.0.0.0/0"
-	} else {
-		if _, _, err := net.ParseCIDR(c.TemporarySGSourceCidr); err != nil {
-			errs = append(errs, fmt.Errorf("Error parsing temporary_security_group_source_cidr: %s", err.Error()))
+	if c.PrivateIp != "" || len(c.SecondaryPrivateIps) > 0 || c.SecondaryPrivateIpCount != 0 || len(c.Nics) > 0 {
+		if c.SubnetId == "" && c.SubnetFilter.Empty() && c.TemporaryNet == nil {
+			errs = append(errs, fmt.Errorf("private_ip, secondary_private_ips, secondary_private_ip_count and nics require subnet_id, subnet_filter or temporary_net."))
+		}
+		errs = append(errs, checkPrivateIps(c.PrivateIp, c.SecondaryPrivateIps, c.SecondaryPrivateIpCount)...)
+		for i := range c.Nics {
+			for _, err := range c.Nics[i].Prepare() {
+				errs = append(errs, fmt.Errorf("nics[%d]: %s", i, err))
+			}
+		}
+	}
+
+	if c.PublicIpId != "" && !c.PublicIpFilter.Empty() {
+		errs = append(errs, fmt.Errorf("Only one of public_ip_id or public_ip_filter can be specified."))
+	}
+
+	if c.TemporaryNet != nil {
+		if c.NetId != "" || c.SubnetId != "" || !c.NetFilter.Empty() || !c.SubnetFilter.Empty() {
+			errs = append(errs, fmt.Errorf("temporary_net cannot be used with net_id, net_filter, subnet_id or subnet_filter."))
+		}
+		if len(c.SecurityGroupIds) > 0 || !c.SecurityGroupFilter.Empty() {
+			errs = append(errs, fmt.Errorf("temporary_net cannot be used with security_group_ids or security_group_filter, as the security groups must be in the temporary Net."))
+		}
+		errs = append(errs, c.TemporaryNet.Prepare()...)
+	}
+
+	if c.TemporarySGSourceCidr != "" {
+		if len(c.TemporarySGSourceCidrs) > 0 {
+			errs = append(errs, fmt.Errorf("Only one of temporary_security_group_source_cidr or temporary_security_group_source_cidrs can be specified."))
+		} else {
+			c.TemporarySGSourceCidrs = []string{c.TemporarySGSourceCidr}
+		}
+	}
+	if len(c.TemporarySGSourceCidrs) == 0 && !c.TemporarySGSourcePublicIp {
+		c.TemporarySGSourceCidrs = []string{"0.0.0.0/0"}
+	}
+	if c.TemporarySGSourcePublicIp {
+		if c.TemporarySGSourcePublicIpURL == "" {
+			c.TemporarySGSourcePublicIpURL = DefaultPublicIpURL
+		}
+		if u, err := url.Parse(c.TemporarySGSourcePublicIpURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("temporary_security_group_source_public_ip_url must be an http or https URL, got %q", c.TemporarySGSourcePublicIpURL))
+		}
+	} else if c.TemporarySGSourcePublicIpURL != "" {
+		errs = append(errs, fmt.Errorf("temporary_security_group_source_public_ip_url can only be used with temporary_security_group_source_public_ip."))
+	}
+	for _, cidr := range c.TemporarySGSourceCidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("Error parsing temporary_security_group_source_cidrs: %s", err.Error()))
+		}
+	}
+	for i := range c.TemporarySGRules {
+		for _, err := range c.TemporarySGRules[i].Prepare(false) {
+			errs = append(errs, fmt.Errorf("temporary_security_group_rules[%d]: %s", i, err))
+		}
+	}
+	for i := range c.TemporarySGOutboundRules {
+		for _, err := range c.TemporarySGOutboundRules[i].Prepare(true) {
+			errs = append(errs, fmt.Errorf("temporary_security_group_outbound_rules[%d]: %s", i, err))
+		}
+	}
+	if len(c.SecurityGroupIds) > 0 || !c.SecurityGroupFilter.Empty() {
+		if c.TemporarySGSourcePublicIp || len(c.TemporarySGRules) > 0 ||
+			len(c.TemporarySGOutboundRules) > 0 || len(c.TemporarySGTags) > 0 {
+			errs = append(errs, fmt.Errorf("The temporary_security_group_* options cannot be used with security_group_ids or security_group_filter, as no temporary security group is created."))
 		}
 	}
 
@@ -224,6 +506,20 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 	return errs
 }
 
+// SpotVmWarning is reported by the builders when a spot option is set, see
+// HasSpotOptions. The Outscale API has no spot market, so the source VM is
+// always launched on demand and the spot options only remain for template
+// compatibility.
+const SpotVmWarning = "spot_price or block_duration_minutes is set, but the Outscale API does not provide spot VMs: " +
+	"the source VM will be launched on demand and spot_price, spot_price_auto_product, " +
+	"spot_tags and block_duration_minutes will be ignored."
+
 func (c *RunConfig) IsSpotVm() bool {
 	return c.SpotPrice != "" && c.SpotPrice != "0"
 }
+
+// HasSpotOptions tells whether spot_price or block_duration_minutes is set.
+// spot_tags and spot_price_auto_product cannot be set without spot_price.
+func (c *RunConfig) HasSpotOptions() bool {
+	return c.IsSpotVm() || c.BlockDurationMinutes != 0
+}
@@ -70,6 +70,70 @@ func (*FlatNetFilterOptions) HCL2Spec() map[string]hcldec.Spec {
 	return s
 }
 
+// FlatNicConfig is an auto-generated flat version of NicConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatNicConfig struct {
+	SubnetId                *string  `mapstructure:"subnet_id" cty:"subnet_id" hcl:"subnet_id"`
+	PrivateIp               *string  `mapstructure:"private_ip" cty:"private_ip" hcl:"private_ip"`
+	SecondaryPrivateIps     []string `mapstructure:"secondary_private_ips" cty:"secondary_private_ips" hcl:"secondary_private_ips"`
+	SecondaryPrivateIpCount *int     `mapstructure:"secondary_private_ip_count" cty:"secondary_private_ip_count" hcl:"secondary_private_ip_count"`
+	SecurityGroupIds        []string `mapstructure:"security_group_ids" cty:"security_group_ids" hcl:"security_group_ids"`
+	Description             *string  `mapstructure:"description" cty:"description" hcl:"description"`
+}
+
+// FlatMapstructure returns a new FlatNicConfig.
+// FlatNicConfig is an auto-generated flat version of NicConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*NicConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatNicConfig)
+}
+
+// HCL2Spec returns the hcl spec of a NicConfig.
+// This spec is used by HCL to read the fields of NicConfig.
+// The decoded values from this spec will then be applied to a FlatNicConfig.
+func (*FlatNicConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"subnet_id":                  &hcldec.AttrSpec{Name: "subnet_id", Type: cty.String, Required: false},
+		"private_ip":                 &hcldec.AttrSpec{Name: "private_ip", Type: cty.String, Required: false},
+		"secondary_private_ips":      &hcldec.AttrSpec{Name: "secondary_private_ips", Type: cty.List(cty.String), Required: false},
+		"secondary_private_ip_count": &hcldec.AttrSpec{Name: "secondary_private_ip_count", Type: cty.Number, Required: false},
+		"security_group_ids":         &hcldec.AttrSpec{Name: "security_group_ids", Type: cty.List(cty.String), Required: false},
+		"description":                &hcldec.AttrSpec{Name: "description", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatOMIRetentionConfig is an auto-generated flat version of OMIRetentionConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatOMIRetentionConfig struct {
+	NamePrefix      *string `mapstructure:"name_prefix" cty:"name_prefix" hcl:"name_prefix"`
+	Tags            TagMap  `mapstructure:"tags" cty:"tags" hcl:"tags"`
+	KeepLatest      *int    `mapstructure:"keep_latest" cty:"keep_latest" hcl:"keep_latest"`
+	KeepYoungerThan *string `mapstructure:"keep_younger_than" cty:"keep_younger_than" hcl:"keep_younger_than"`
+	DryRun          *bool   `mapstructure:"dry_run" cty:"dry_run" hcl:"dry_run"`
+}
+
+// FlatMapstructure returns a new FlatOMIRetentionConfig.
+// FlatOMIRetentionConfig is an auto-generated flat version of OMIRetentionConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*OMIRetentionConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatOMIRetentionConfig)
+}
+
+// HCL2Spec returns the hcl spec of a OMIRetentionConfig.
+// This spec is used by HCL to read the fields of OMIRetentionConfig.
+// The decoded values from this spec will then be applied to a FlatOMIRetentionConfig.
+func (*FlatOMIRetentionConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name_prefix":       &hcldec.AttrSpec{Name: "name_prefix", Type: cty.String, Required: false},
+		"tags":              &hcldec.AttrSpec{Name: "tags", Type: cty.Map(cty.String), Required: false},
+		"keep_latest":       &hcldec.AttrSpec{Name: "keep_latest", Type: cty.Number, Required: false},
+		"keep_younger_than": &hcldec.AttrSpec{Name: "keep_younger_than", Type: cty.String, Required: false},
+		"dry_run":           &hcldec.AttrSpec{Name: "dry_run", Type: cty.Bool, Required: false},
+	}
+	return s
+}
+
 // FlatOmiFilterOptions is an auto-generated flat version of OmiFilterOptions.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatOmiFilterOptions struct {
@@ -99,6 +163,58 @@ func (*FlatOmiFilterOptions) HCL2Spec() map[string]hcldec.Spec {
 	return s
 }
 
+// FlatPollingConfig is an auto-generated flat version of PollingConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatPollingConfig struct {
+	DelaySeconds *int    `mapstructure:"delay_seconds" cty:"delay_seconds" hcl:"delay_seconds"`
+	MaxAttempts  *int    `mapstructure:"max_attempts" cty:"max_attempts" hcl:"max_attempts"`
+	Timeout      *string `mapstructure:"timeout" cty:"timeout" hcl:"timeout"`
+}
+
+// FlatMapstructure returns a new FlatPollingConfig.
+// FlatPollingConfig is an auto-generated flat version of PollingConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*PollingConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatPollingConfig)
+}
+
+// HCL2Spec returns the hcl spec of a PollingConfig.
+// This spec is used by HCL to read the fields of PollingConfig.
+// The decoded values from this spec will then be applied to a FlatPollingConfig.
+func (*FlatPollingConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"delay_seconds": &hcldec.AttrSpec{Name: "delay_seconds", Type: cty.Number, Required: false},
+		"max_attempts":  &hcldec.AttrSpec{Name: "max_attempts", Type: cty.Number, Required: false},
+		"timeout":       &hcldec.AttrSpec{Name: "timeout", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatPublicIpFilterOptions is an auto-generated flat version of PublicIpFilterOptions.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatPublicIpFilterOptions struct {
+	Filters map[string]string      `cty:"filters" hcl:"filters"`
+	Filter  []config.FlatNameValue `cty:"filter" hcl:"filter"`
+}
+
+// FlatMapstructure returns a new FlatPublicIpFilterOptions.
+// FlatPublicIpFilterOptions is an auto-generated flat version of PublicIpFilterOptions.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*PublicIpFilterOptions) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatPublicIpFilterOptions)
+}
+
+// HCL2Spec returns the hcl spec of a PublicIpFilterOptions.
+// This spec is used by HCL to read the fields of PublicIpFilterOptions.
+// The decoded values from this spec will then be applied to a FlatPublicIpFilterOptions.
+func (*FlatPublicIpFilterOptions) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"filters": &hcldec.AttrSpec{Name: "filters", Type: cty.Map(cty.String), Required: false},
+		"filter":  &hcldec.BlockListSpec{TypeName: "filter", Nested: hcldec.ObjectSpec((*config.FlatNameValue)(nil).HCL2Spec())},
+	}
+	return s
+}
+
 // FlatSecurityGroupFilterOptions is an auto-generated flat version of SecurityGroupFilterOptions.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatSecurityGroupFilterOptions struct {
@@ -124,6 +240,35 @@ func (*FlatSecurityGroupFilterOptions) HCL2Spec() map[string]hcldec.Spec {
 	return s
 }
 
+// FlatSecurityGroupRule is an auto-generated flat version of SecurityGroupRule.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatSecurityGroupRule struct {
+	Protocol *string  `mapstructure:"protocol" cty:"protocol" hcl:"protocol"`
+	FromPort *int     `mapstructure:"from_port" cty:"from_port" hcl:"from_port"`
+	ToPort   *int     `mapstructure:"to_port" cty:"to_port" hcl:"to_port"`
+	IpRanges []string `mapstructure:"ip_ranges" cty:"ip_ranges" hcl:"ip_ranges"`
+}
+
+// FlatMapstructure returns a new FlatSecurityGroupRule.
+// FlatSecurityGroupRule is an auto-generated flat version of SecurityGroupRule.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*SecurityGroupRule) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatSecurityGroupRule)
+}
+
+// HCL2Spec returns the hcl spec of a SecurityGroupRule.
+// This spec is used by HCL to read the fields of SecurityGroupRule.
+// The decoded values from this spec will then be applied to a FlatSecurityGroupRule.
+func (*FlatSecurityGroupRule) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"protocol":  &hcldec.AttrSpec{Name: "protocol", Type: cty.String, Required: false},
+		"from_port": &hcldec.AttrSpec{Name: "from_port", Type: cty.Number, Required: false},
+		"to_port":   &hcldec.AttrSpec{Name: "to_port", Type: cty.Number, Required: false},
+		"ip_ranges": &hcldec.AttrSpec{Name: "ip_ranges", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
+
 // FlatSubnetFilterOptions is an auto-generated flat version of SubnetFilterOptions.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatSubnetFilterOptions struct {
@@ -152,3 +297,34 @@ func (*FlatSubnetFilterOptions) HCL2Spec() map[string]hcldec.Spec {
 	}
 	return s
 }
+
+// FlatTemporaryNetConfig is an auto-generated flat version of TemporaryNetConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatTemporaryNetConfig struct {
+	IpRange          *string `mapstructure:"ip_range" cty:"ip_range" hcl:"ip_range"`
+	SubnetIpRange    *string `mapstructure:"subnet_ip_range" cty:"subnet_ip_range" hcl:"subnet_ip_range"`
+	Private          *bool   `mapstructure:"private" cty:"private" hcl:"private"`
+	NatSubnetIpRange *string `mapstructure:"nat_subnet_ip_range" cty:"nat_subnet_ip_range" hcl:"nat_subnet_ip_range"`
+	Tags             TagMap  `mapstructure:"tags" cty:"tags" hcl:"tags"`
+}
+
+// FlatMapstructure returns a new FlatTemporaryNetConfig.
+// FlatTemporaryNetConfig is an auto-generated flat version of TemporaryNetConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*TemporaryNetConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatTemporaryNetConfig)
+}
+
+// HCL2Spec returns the hcl spec of a TemporaryNetConfig.
+// This spec is used by HCL to read the fields of TemporaryNetConfig.
+// The decoded values from this spec will then be applied to a FlatTemporaryNetConfig.
+func (*FlatTemporaryNetConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"ip_range":            &hcldec.AttrSpec{Name: "ip_range", Type: cty.String, Required: false},
+		"subnet_ip_range":     &hcldec.AttrSpec{Name: "subnet_ip_range", Type: cty.String, Required: false},
+		"private":             &hcldec.AttrSpec{Name: "private", Type: cty.Bool, Required: false},
+		"nat_subnet_ip_range": &hcldec.AttrSpec{Name: "nat_subnet_ip_range", Type: cty.String, Required: false},
+		"tags":                &hcldec.AttrSpec{Name: "tags", Type: cty.Map(cty.String), Required: false},
+	}
+	return s
+}
@@ -3,6 +3,7 @@ package common
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
 	"regexp"
 	"testing"
 
@@ -232,3 +233,201 @@ func TestRunConfigPrepare_TemporaryKeyPairName(t *testing.T) {
 		t.Fatal("keypair name does not match")
 	}
 }
+
+func TestRunConfigPrepare_TemporaryNet(t *testing.T) {
+	c := testConfig()
+	c.TemporaryNet = &TemporaryNetConfig{Private: true}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.TemporaryNet.IpRange != "10.0.0.0/16" || c.TemporaryNet.SubnetIpRange != "10.0.0.0/24" ||
+		c.TemporaryNet.NatSubnetIpRange != "10.0.1.0/24" {
+		t.Fatalf("bad defaults: %#v", c.TemporaryNet)
+	}
+
+	cases := map[string]TemporaryNetConfig{
+		"bad ip_range":       {IpRange: "10.0.0.0"},
+		"outside ip_range":   {IpRange: "10.0.0.0/16", SubnetIpRange: "10.1.0.0/24"},
+		"wider than net":     {IpRange: "10.0.0.0/16", SubnetIpRange: "10.0.0.0/8"},
+		"overlapping ranges": {Private: true, SubnetIpRange: "10.0.0.0/24", NatSubnetIpRange: "10.0.0.128/25"},
+	}
+	for name, netConfig := range cases {
+		c := testConfig()
+		netConfig := netConfig
+		c.TemporaryNet = &netConfig
+		if err := c.Prepare(nil); len(err) != 1 {
+			t.Fatalf("%s: should have one error, got %v", name, err)
+		}
+	}
+
+	c = testConfig()
+	c.TemporaryNet = &TemporaryNetConfig{}
+	c.SubnetId = "subnet-12345678"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("temporary_net and subnet_id should conflict, got %v", err)
+	}
+}
+
+func TestRunConfigPrepare_TemporarySecurityGroup(t *testing.T) {
+	c := testConfig()
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(c.TemporarySGSourceCidrs, []string{"0.0.0.0/0"}) {
+		t.Fatalf("bad default source cidrs: %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c = testConfig()
+	c.TemporarySGSourceCidr = "10.0.0.0/8"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(c.TemporarySGSourceCidrs, []string{"10.0.0.0/8"}) {
+		t.Fatalf("temporary_security_group_source_cidr should be kept, got %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c = testConfig()
+	c.TemporarySGSourcePublicIp = true
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if len(c.TemporarySGSourceCidrs) != 0 {
+		t.Fatalf("the public IP should replace the default source cidr, got %#v", c.TemporarySGSourceCidrs)
+	}
+	if c.TemporarySGSourcePublicIpURL != DefaultPublicIpURL {
+		t.Fatalf("bad public IP service: %s", c.TemporarySGSourcePublicIpURL)
+	}
+
+	c = testConfig()
+	c.TemporarySGSourcePublicIp = true
+	c.TemporarySGSourcePublicIpURL = "ifconfig.me"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("should error on a public IP service that is not an URL: %v", err)
+	}
+
+	c = testConfig()
+	c.TemporarySGSourcePublicIpURL = "https://ifconfig.me/ip"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("should error on a public IP service without temporary_security_group_source_public_ip: %v", err)
+	}
+
+	c = testConfig()
+	c.TemporarySGRules = []SecurityGroupRule{
+		{Protocol: "tcp", FromPort: 8080},
+		{Protocol: "icmp", FromPort: 8, ToPort: 0},
+		{Protocol: "-1", FromPort: 1, ToPort: 2},
+	}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.TemporarySGRules[0].ToPort != 8080 || c.TemporarySGRules[1].FromPort != 8 || c.TemporarySGRules[2].FromPort != -1 {
+		t.Fatalf("bad rules: %#v", c.TemporarySGRules)
+	}
+
+	bad := map[string]func(c *RunConfig){
+		"both source cidr options": func(c *RunConfig) {
+			c.TemporarySGSourceCidr = "10.0.0.0/8"
+			c.TemporarySGSourceCidrs = []string{"10.0.0.0/8"}
+		},
+		"bad source cidr": func(c *RunConfig) { c.TemporarySGSourceCidrs = []string{"10.0.0.0"} },
+		"bad protocol":    func(c *RunConfig) { c.TemporarySGRules = []SecurityGroupRule{{Protocol: "sctp"}} },
+		"bad port range": func(c *RunConfig) {
+			c.TemporarySGRules = []SecurityGroupRule{{Protocol: "tcp", FromPort: 22, ToPort: 21}}
+		},
+		"bad icmp type":        func(c *RunConfig) { c.TemporarySGRules = []SecurityGroupRule{{Protocol: "icmp", FromPort: 256}} },
+		"outbound without ips": func(c *RunConfig) { c.TemporarySGOutboundRules = []SecurityGroupRule{{Protocol: "-1"}} },
+		"rules with existing groups": func(c *RunConfig) {
+			c.SecurityGroupIds = []string{"sg-12345678"}
+			c.TemporarySGTags = TagMap{"Name": "packer"}
+		},
+	}
+	for name, f := range bad {
+		c := testConfig()
+		f(c)
+		if err := c.Prepare(nil); len(err) != 1 {
+			t.Fatalf("%s: should have one error, got %v", name, err)
+		}
+	}
+}
+
+func TestRunConfigPrepare_PrivateIps(t *testing.T) {
+	c := testConfig()
+	c.SubnetId = "subnet-12345678"
+	c.PrivateIp = "10.0.0.10"
+	c.SecondaryPrivateIps = []string{"10.0.0.11"}
+	c.Nics = []NicConfig{{SubnetId: "subnet-87654321", SecondaryPrivateIpCount: 2}}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	bad := map[string]func(c *RunConfig){
+		"no subnet":            func(c *RunConfig) { c.SubnetId = "" },
+		"bad private ip":       func(c *RunConfig) { c.PrivateIp = "10.0.0" },
+		"duplicate ip":         func(c *RunConfig) { c.SecondaryPrivateIps = []string{"10.0.0.10"} },
+		"negative count":       func(c *RunConfig) { c.SecondaryPrivateIpCount = -1 },
+		"nic without subnet":   func(c *RunConfig) { c.Nics = []NicConfig{{PrivateIp: "10.0.1.10"}} },
+		"bad nic secondary ip": func(c *RunConfig) { c.Nics[0].SecondaryPrivateIps = []string{"::1"} },
+	}
+	for name, f := range bad {
+		c := testConfig()
+		c.SubnetId = "subnet-12345678"
+		c.PrivateIp = "10.0.0.10"
+		c.Nics = []NicConfig{{SubnetId: "subnet-87654321"}}
+		f(c)
+		if err := c.Prepare(nil); len(err) != 1 {
+			t.Fatalf("%s: should have one error, got %v", name, err)
+		}
+	}
+}
+
+func TestRunConfigPrepare_Fallback(t *testing.T) {
+	c := testConfig()
+	c.VmType = ""
+	c.VmTypes = []string{"tinav5.c4r8p1", "tinav5.c2r4p2"}
+	c.SubregionNames = []string{"eu-west-2a", "eu-west-2b"}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.VmType != "tinav5.c4r8p1" || c.Subregion != "eu-west-2a" {
+		t.Fatalf("the first candidates should be used by default, got %s in %s", c.VmType, c.Subregion)
+	}
+
+	c = testConfig()
+	c.SubnetIds = []string{"subnet-12345678", "subnet-87654321"}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.SubnetId != "subnet-12345678" {
+		t.Fatalf("the first Subnet should be used by default, got %s", c.SubnetId)
+	}
+
+	bad := map[string]func(c *RunConfig){
+		"vm_type and vm_types":               func(c *RunConfig) { c.VmTypes = []string{"tinav5.c2r4p2"} },
+		"subregion_name and subregion_names": func(c *RunConfig) { c.Subregion = "eu-west-2a"; c.SubregionNames = []string{"eu-west-2b"} },
+		"subregion_names and subnet_id":      func(c *RunConfig) { c.SubnetId = "subnet-12345678"; c.SubregionNames = []string{"eu-west-2b"} },
+		"subregion_names and net_id":         func(c *RunConfig) { c.NetId = "vpc-12345678"; c.SubregionNames = []string{"eu-west-2b"} },
+		"subregion_names and net_filter": func(c *RunConfig) {
+			c.NetFilter.Filters = map[string]string{"tag:Name": "packer"}
+			c.SubregionNames = []string{"eu-west-2b"}
+		},
+		"subnet_ids and subnet_filter": func(c *RunConfig) {
+			c.SubnetIds = []string{"subnet-12345678"}
+			c.SubnetFilter.Filters = map[string]string{"tag:Name": "packer"}
+		},
+		"subnet_ids and private_ip": func(c *RunConfig) {
+			c.SubnetIds = []string{"subnet-12345678"}
+			c.PrivateIp = "10.0.0.10"
+		},
+		"subnet_ids and secondary_private_ip_count": func(c *RunConfig) {
+			c.SubnetIds = []string{"subnet-12345678"}
+			c.SecondaryPrivateIpCount = 2
+		},
+	}
+	for name, f := range bad {
+		c := testConfig()
+		f(c)
+		if err := c.Prepare(nil); len(err) != 1 {
+			t.Fatalf("%s: should have one error, got %v", name, err)
+		}
+	}
+}
@@ -4,86 +4,108 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/antihax/optional"
 	"github.com/outscale/osc-sdk-go/osc"
-	"github.com/outscale/packer-plugin-outscale/builder/osc/common/retry"
 )
 
 type stateRefreshFunc func() (string, error)
 
-func waitUntilForOscVmRunning(conn *osc.APIClient, vmID string) error {
-	errCh := make(chan error, 1)
-	go waitForState(errCh, "running", waitUntilOscVmStateFunc(conn, vmID))
-	err := <-errCh
-	return err
+func (c *PollingConfig) waitUntilForOscVmRunning(ctx context.Context, conn *osc.APIClient, vmID string) error {
+	return c.waitForState(ctx, "running", waitUntilOscVmStateFunc(ctx, conn, vmID))
 }
 
-func waitUntilOscVmDeleted(conn *osc.APIClient, vmID string) error {
-	errCh := make(chan error, 1)
-	go waitForState(errCh, "terminated", waitUntilOscVmStateFunc(conn, vmID))
-	return <-errCh
+func (c *PollingConfig) waitUntilOscVmDeleted(ctx context.Context, conn *osc.APIClient, vmID string) error {
+	return c.waitForState(ctx, "terminated", waitUntilOscVmStateFunc(ctx, conn, vmID))
 }
 
-func waitUntilOscVmStopped(conn *osc.APIClient, vmID string) error {
-	errCh := make(chan error, 1)
-	go waitForState(errCh, "stopped", waitUntilOscVmStateFunc(conn, vmID))
-	return <-errCh
+func (c *PollingConfig) waitUntilOscVmStopped(ctx context.Context, conn *osc.APIClient, vmID string) error {
+	return c.waitForState(ctx, "stopped", waitUntilOscVmStateFunc(ctx, conn, vmID))
 }
 
-func WaitUntilOscSnapshotCompleted(conn *osc.APIClient, id string) error {
-	errCh := make(chan error, 1)
-	go waitForState(errCh, "completed", waitUntilOscSnapshotStateFunc(conn, id))
-	return <-errCh
+func (c *PollingConfig) WaitUntilOscSnapshotCompleted(ctx context.Context, conn *osc.APIClient, id string) error {
+	return c.waitForState(ctx, "completed", waitUntilOscSnapshotStateFunc(ctx, conn, id))
 }
 
-func WaitUntilOscImageAvailable(conn *osc.APIClient, imageID string) error {
-	errCh := make(chan error, 1)
-	go waitForState(errCh, "available", waitUntilOscImageStateFunc(conn, imageID))
-	return <-errCh
+func (c *PollingConfig) WaitUntilOscImageAvailable(ctx context.Context, conn *osc.APIClient, imageID string) error {
+	return c.waitForState(ctx, "available", waitUntilOscImageStateFunc(ctx, conn, imageID))
 }
 
-func WaitUntilOscVolumeAvailable(conn *osc.APIClient, volumeID string) error {
-	errCh := make(chan error, 1)
-	go waitForState(errCh, "available", volumeOscWaitFunc(conn, volumeID))
-	return <-errCh
+func (c *PollingConfig) WaitUntilOscVolumeAvailable(ctx context.Context, conn *osc.APIClient, volumeID string) error {
+	return c.waitForState(ctx, "available", volumeOscWaitFunc(ctx, conn, volumeID))
 }
 
-func WaitUntilOscVolumeIsLinked(conn *osc.APIClient, volumeID string) error {
-	errCh := make(chan error, 1)
-	go waitForState(errCh, "attached", waitUntilOscVolumeLinkedStateFunc(conn, volumeID))
-	return <-errCh
+func (c *PollingConfig) WaitUntilOscVolumeIsLinked(ctx context.Context, conn *osc.APIClient, volumeID string) error {
+	return c.waitForState(ctx, "attached", waitUntilOscVolumeLinkedStateFunc(ctx, conn, volumeID))
 }
 
-func WaitUntilOscVolumeIsUnlinked(conn *osc.APIClient, volumeID string) error {
-	errCh := make(chan error, 1)
-	go waitForState(errCh, "dettached", waitUntilOscVolumeUnLinkedStateFunc(conn, volumeID))
-	return <-errCh
+func (c *PollingConfig) WaitUntilOscVolumeIsUnlinked(ctx context.Context, conn *osc.APIClient, volumeID string) error {
+	return c.waitForState(ctx, "dettached", waitUntilOscVolumeUnLinkedStateFunc(ctx, conn, volumeID))
 }
 
-func WaitUntilOscSnapshotDone(conn *osc.APIClient, snapshotID string) error {
-	errCh := make(chan error, 1)
-	go waitForState(errCh, "completed", waitUntilOscSnapshotDoneStateFunc(conn, snapshotID))
-	return <-errCh
+func (c *PollingConfig) WaitUntilOscSnapshotDone(ctx context.Context, conn *osc.APIClient, snapshotID string) error {
+	return c.waitForState(ctx, "completed", waitUntilOscSnapshotDoneStateFunc(ctx, conn, snapshotID))
 }
 
-func waitForState(errCh chan<- error, target string, refresh stateRefreshFunc) {
-	err := retry.Run(2, 2, 0, func(_ uint) (bool, error) {
+func (c *PollingConfig) WaitUntilOscImageExportTaskCompleted(ctx context.Context, conn *osc.APIClient, taskID string) error {
+	return c.waitForState(ctx, "completed", waitUntilOscImageExportTaskStateFunc(ctx, conn, taskID))
+}
+
+func (c *PollingConfig) waitUntilOscNetAvailable(ctx context.Context, conn *osc.APIClient, netID string) error {
+	return c.waitForState(ctx, "available", waitUntilOscNetStateFunc(ctx, conn, netID))
+}
+
+func (c *PollingConfig) waitUntilOscSubnetAvailable(ctx context.Context, conn *osc.APIClient, subnetID string) error {
+	return c.waitForState(ctx, "available", waitUntilOscSubnetStateFunc(ctx, conn, subnetID))
+}
+
+func (c *PollingConfig) waitUntilOscNatServiceAvailable(ctx context.Context, conn *osc.APIClient, natServiceID string) error {
+	return c.waitForState(ctx, "available", waitUntilOscNatServiceStateFunc(ctx, conn, natServiceID))
+}
+
+func (c *PollingConfig) waitUntilOscNatServiceDeleted(ctx context.Context, conn *osc.APIClient, natServiceID string) error {
+	return c.waitForState(ctx, "deleted", waitUntilOscNatServiceStateFunc(ctx, conn, natServiceID))
+}
+
+// waitForState polls the resource until it reaches the target state. It gives
+// up once the maximum number of attempts or the timeout of the polling
+// configuration, if any, is reached, or as soon as ctx is cancelled.
+func (c *PollingConfig) waitForState(ctx context.Context, target string, refresh stateRefreshFunc) error {
+	if timeout := c.timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	maxAttempts := c.maxAttempts()
+	for attempt := 1; ; attempt++ {
 		state, err := refresh()
 		if err != nil {
-			return false, err
+			return DecodeError(err)
 		} else if state == target {
-			return true, nil
+			return nil
+		}
+
+		if maxAttempts > 0 && attempt >= maxAttempts {
+			return fmt.Errorf("Still in state %q after %d attempts, expected %q", state, attempt, target)
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("Timeout after %s waiting for state %q, still %q", c.timeout(), target, state)
+			}
+			return ctx.Err()
+		case <-time.After(c.delay()):
 		}
-		return false, nil
-	})
-	errCh <- err
+	}
 }
 
-func waitUntilOscVmStateFunc(conn *osc.APIClient, id string) stateRefreshFunc {
+func waitUntilOscVmStateFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
 	return func() (string, error) {
 		log.Printf("[Debug] Retrieving state for VM with id %s", id)
-		resp, _, err := conn.VmApi.ReadVms(context.Background(), &osc.ReadVmsOpts{
+		resp, _, err := conn.VmApi.ReadVms(ctx, &osc.ReadVmsOpts{
 			ReadVmsRequest: optional.NewInterface(osc.ReadVmsRequest{
 				Filters: osc.FiltersVm{
 					VmIds: []string{id},
@@ -108,10 +130,10 @@ func waitUntilOscVmStateFunc(conn *osc.APIClient, id string) stateRefreshFunc {
 	}
 }
 
-func waitUntilOscVolumeLinkedStateFunc(conn *osc.APIClient, id string) stateRefreshFunc {
+func waitUntilOscVolumeLinkedStateFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
 	return func() (string, error) {
 		log.Printf("[Debug] Check if volume with id %s exists", id)
-		resp, _, err := conn.VolumeApi.ReadVolumes(context.Background(), &osc.ReadVolumesOpts{
+		resp, _, err := conn.VolumeApi.ReadVolumes(ctx, &osc.ReadVolumesOpts{
 			ReadVolumesRequest: optional.NewInterface(osc.ReadVolumesRequest{
 				Filters: osc.FiltersVolume{
 					VolumeIds: []string{id},
@@ -135,10 +157,10 @@ func waitUntilOscVolumeLinkedStateFunc(conn *osc.APIClient, id string) stateRefr
 	}
 }
 
-func waitUntilOscVolumeUnLinkedStateFunc(conn *osc.APIClient, id string) stateRefreshFunc {
+func waitUntilOscVolumeUnLinkedStateFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
 	return func() (string, error) {
 		log.Printf("[Debug] Check if volume with id %s exists", id)
-		resp, _, err := conn.VolumeApi.ReadVolumes(context.Background(), &osc.ReadVolumesOpts{
+		resp, _, err := conn.VolumeApi.ReadVolumes(ctx, &osc.ReadVolumesOpts{
 			ReadVolumesRequest: optional.NewInterface(osc.ReadVolumesRequest{
 				Filters: osc.FiltersVolume{
 					VolumeIds: []string{id},
@@ -162,10 +184,10 @@ func waitUntilOscVolumeUnLinkedStateFunc(conn *osc.APIClient, id string) stateRe
 	}
 }
 
-func waitUntilOscSnapshotStateFunc(conn *osc.APIClient, id string) stateRefreshFunc {
+func waitUntilOscSnapshotStateFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
 	return func() (string, error) {
 		log.Printf("[Debug] Check if Snapshot with id %s exists", id)
-		resp, _, err := conn.SnapshotApi.ReadSnapshots(context.Background(), &osc.ReadSnapshotsOpts{
+		resp, _, err := conn.SnapshotApi.ReadSnapshots(ctx, &osc.ReadSnapshotsOpts{
 			ReadSnapshotsRequest: optional.NewInterface(osc.ReadSnapshotsRequest{
 				Filters: osc.FiltersSnapshot{
 					SnapshotIds: []string{id},
@@ -185,10 +207,10 @@ func waitUntilOscSnapshotStateFunc(conn *osc.APIClient, id string) stateRefreshF
 	}
 }
 
-func waitUntilOscImageStateFunc(conn *osc.APIClient, id string) stateRefreshFunc {
+func waitUntilOscImageStateFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
 	return func() (string, error) {
 		log.Printf("[Debug] Check if Image with id %s exists", id)
-		resp, _, err := conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		resp, _, err := conn.ImageApi.ReadImages(ctx, &osc.ReadImagesOpts{
 			ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
 				Filters: osc.FiltersImage{
 					ImageIds: []string{id},
@@ -212,10 +234,38 @@ func waitUntilOscImageStateFunc(conn *osc.APIClient, id string) stateRefreshFunc
 	}
 }
 
-func waitUntilOscSnapshotDoneStateFunc(conn *osc.APIClient, id string) stateRefreshFunc {
+func waitUntilOscImageExportTaskStateFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
+	return func() (string, error) {
+		log.Printf("[Debug] Check the state of the image export task with id %s", id)
+		resp, _, err := conn.ImageApi.ReadImageExportTasks(ctx, &osc.ReadImageExportTasksOpts{
+			ReadImageExportTasksRequest: optional.NewInterface(osc.ReadImageExportTasksRequest{
+				Filters: osc.FiltersExportTask{
+					TaskIds: []string{id},
+				},
+			}),
+		})
+
+		if err != nil {
+			return "", err
+		}
+
+		if len(resp.ImageExportTasks) == 0 {
+			return "pending", nil
+		}
+
+		task := resp.ImageExportTasks[0]
+		if task.State == "failed" || task.State == "cancelled" {
+			return task.State, fmt.Errorf("Image export task (%s) is %s: %s", id, task.State, task.Comment)
+		}
+
+		return task.State, nil
+	}
+}
+
+func waitUntilOscSnapshotDoneStateFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
 	return func() (string, error) {
 		log.Printf("[Debug] Check if Snapshot with id %s exists", id)
-		resp, _, err := conn.SnapshotApi.ReadSnapshots(context.Background(), &osc.ReadSnapshotsOpts{
+		resp, _, err := conn.SnapshotApi.ReadSnapshots(ctx, &osc.ReadSnapshotsOpts{
 			ReadSnapshotsRequest: optional.NewInterface(osc.ReadSnapshotsRequest{
 				Filters: osc.FiltersSnapshot{
 					SnapshotIds: []string{id},
@@ -239,10 +289,10 @@ func waitUntilOscSnapshotDoneStateFunc(conn *osc.APIClient, id string) stateRefr
 	}
 }
 
-func volumeOscWaitFunc(conn *osc.APIClient, id string) stateRefreshFunc {
+func volumeOscWaitFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
 	return func() (string, error) {
 		log.Printf("[Debug] Check if SvolumeG with id %s exists", id)
-		resp, _, err := conn.VolumeApi.ReadVolumes(context.Background(), &osc.ReadVolumesOpts{
+		resp, _, err := conn.VolumeApi.ReadVolumes(ctx, &osc.ReadVolumesOpts{
 			ReadVolumesRequest: optional.NewInterface(osc.ReadVolumesRequest{
 				Filters: osc.FiltersVolume{
 					VolumeIds: []string{id},
@@ -265,3 +315,77 @@ func volumeOscWaitFunc(conn *osc.APIClient, id string) stateRefreshFunc {
 		return resp.Volumes[0].State, nil
 	}
 }
+
+func waitUntilOscNetStateFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
+	return func() (string, error) {
+		log.Printf("[Debug] Retrieving state for Net with id %s", id)
+		resp, _, err := conn.NetApi.ReadNets(ctx, &osc.ReadNetsOpts{
+			ReadNetsRequest: optional.NewInterface(osc.ReadNetsRequest{
+				Filters: osc.FiltersNet{
+					NetIds: []string{id},
+				},
+			}),
+		})
+
+		if err != nil {
+			return "", err
+		}
+
+		if len(resp.Nets) == 0 {
+			return "pending", nil
+		}
+
+		return resp.Nets[0].State, nil
+	}
+}
+
+func waitUntilOscSubnetStateFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
+	return func() (string, error) {
+		log.Printf("[Debug] Retrieving state for Subnet with id %s", id)
+		resp, _, err := conn.SubnetApi.ReadSubnets(ctx, &osc.ReadSubnetsOpts{
+			ReadSubnetsRequest: optional.NewInterface(osc.ReadSubnetsRequest{
+				Filters: osc.FiltersSubnet{
+					SubnetIds: []string{id},
+				},
+			}),
+		})
+
+		if err != nil {
+			return "", err
+		}
+
+		if len(resp.Subnets) == 0 {
+			return "pending", nil
+		}
+
+		return resp.Subnets[0].State, nil
+	}
+}
+
+func waitUntilOscNatServiceStateFunc(ctx context.Context, conn *osc.APIClient, id string) stateRefreshFunc {
+	return func() (string, error) {
+		log.Printf("[Debug] Retrieving state for NAT service with id %s", id)
+		resp, _, err := conn.NatServiceApi.ReadNatServices(ctx, &osc.ReadNatServicesOpts{
+			ReadNatServicesRequest: optional.NewInterface(osc.ReadNatServicesRequest{
+				Filters: osc.FiltersNatService{
+					NatServiceIds: []string{id},
+				},
+			}),
+		})
+
+		if err != nil {
+			return "", err
+		}
+
+		// Deleted NAT services only remain visible for a while.
+		if len(resp.NatServices) == 0 {
+			return "deleted", nil
+		}
+
+		if resp.NatServices[0].State == "failed" {
+			return resp.NatServices[0].State, fmt.Errorf("NAT service (%s) creation is failed", id)
+		}
+
+		return resp.NatServices[0].State, nil
+	}
+}
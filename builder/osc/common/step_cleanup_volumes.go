@@ -62,7 +62,7 @@ func (s *StepCleanupVolumes) Cleanup(state multistep.StateBag) {
 	})
 
 	if err != nil {
-		ui.Say(fmt.Sprintf("Error describing volumes: %s", err))
+		ui.Say(fmt.Sprintf("Error describing volumes: %s", DecodeError(err)))
 		return
 	}
 
@@ -96,7 +96,7 @@ func (s *StepCleanupVolumes) Cleanup(state multistep.StateBag) {
 			DeleteVolumeRequest: optional.NewInterface(osc.DeleteVolumeRequest{VolumeId: k}),
 		})
 		if err != nil {
-			ui.Say(fmt.Sprintf("Error deleting volume: %s", err))
+			ui.Say(fmt.Sprintf("Error deleting volume: %s", DecodeError(err)))
 		}
 	}
 }
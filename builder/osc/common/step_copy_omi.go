@@ -0,0 +1,216 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/antihax/optional"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// StepCopyOMI copies the OMI built in the session region to every region
+// listed in omi_regions.
+//
+// Produces (updating them in the state bag):
+//
+//	omis map[string]string - the OMI ID per region
+//	snapshots map[string][]string - the snapshot IDs per region
+type StepCopyOMI struct {
+	AccessConfig  *AccessConfig
+	PollingConfig *PollingConfig
+	Regions       []string
+	Name          string
+
+	copiedOmis map[string]string
+	lock       sync.Mutex
+}
+
+func (s *StepCopyOMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if len(s.Regions) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	omis := state.Get("omis").(map[string]string)
+	snapshots := state.Get("snapshots").(map[string][]string)
+
+	sourceRegion := s.AccessConfig.GetRegion()
+	sourceOmi, ok := omis[sourceRegion]
+	if !ok {
+		err := fmt.Errorf("No OMI found in region %s to copy", sourceRegion)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	sourceConn := s.AccessConfig.NewOSCClientByRegion(sourceRegion)
+	imageResp, _, err := sourceConn.ImageApi.ReadImages(ctx, &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
+			Filters: osc.FiltersImage{
+				ImageIds: []string{sourceOmi},
+			},
+		}),
+	})
+	if err != nil || len(imageResp.Images) == 0 {
+		err := fmt.Errorf("Error retrieving details for OMI (%s): %v", sourceOmi, DecodeError(err))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	sourceImage := imageResp.Images[0]
+	name := OMIName(state, s.Name)
+
+	ui.Say(fmt.Sprintf("Copying OMI (%s) to other regions...", sourceOmi))
+
+	s.copiedOmis = make(map[string]string)
+
+	var (
+		errs *packersdk.MultiError
+		wg   sync.WaitGroup
+	)
+	for _, region := range s.Regions {
+		// The session region may come from the environment or a profile, in
+		// which case it is only known once the client has been created.
+		if region == sourceRegion {
+			continue
+		}
+
+		wg.Add(1)
+		ui.Message(fmt.Sprintf("Copying to: %s", region))
+
+		go func(region string) {
+			defer wg.Done()
+			id, snapshotIds, err := s.copyOMI(ctx, region, sourceRegion, name, sourceImage)
+
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			if id != "" {
+				s.copiedOmis[region] = id
+			}
+			if err != nil {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("%s: %s", region, err))
+				return
+			}
+			omis[region] = id
+			snapshots[region] = snapshotIds
+		}(region)
+	}
+
+	ui.Say("Waiting for all copies to complete...")
+	wg.Wait()
+
+	if errs != nil && len(errs.Errors) > 0 {
+		state.Put("error", errs)
+		ui.Error(errs.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("omis", omis)
+	state.Put("snapshots", snapshots)
+	return multistep.ActionContinue
+}
+
+// copyOMI copies the source image into the target region, waits for it to
+// become available and replicates its tags and launch permissions. It returns
+// the ID of the copy (even on a later failure, so it can be cleaned up) and
+// the IDs of its snapshots.
+func (s *StepCopyOMI) copyOMI(ctx context.Context, region, sourceRegion, name string, image osc.Image) (string, []string, error) {
+	regionconn := s.AccessConfig.NewOSCClientByRegion(region)
+
+	if name == "" {
+		name = image.ImageName
+	}
+
+	resp, _, err := regionconn.ImageApi.CreateImage(ctx, &osc.CreateImageOpts{
+		CreateImageRequest: optional.NewInterface(osc.CreateImageRequest{
+			ImageName:        name,
+			Description:      image.Description,
+			SourceImageId:    image.ImageId,
+			SourceRegionName: sourceRegion,
+		}),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("Error Copying OMI (%s) to region (%s): %s", image.ImageId, region, DecodeError(err))
+	}
+	id := resp.Image.ImageId
+
+	if err := s.PollingConfig.WaitUntilOscImageAvailable(ctx, regionconn, id); err != nil {
+		return id, nil, fmt.Errorf("Error waiting for OMI (%s) in region (%s): %s", id, region, err)
+	}
+
+	if len(image.Tags) > 0 {
+		if _, _, err := regionconn.TagApi.CreateTags(ctx, &osc.CreateTagsOpts{
+			CreateTagsRequest: optional.NewInterface(osc.CreateTagsRequest{
+				ResourceIds: []string{id},
+				Tags:        image.Tags,
+			}),
+		}); err != nil {
+			return id, nil, fmt.Errorf("Error copying tags to OMI (%s) in region (%s): %s", id, region, DecodeError(err))
+		}
+	}
+
+	permissions := image.PermissionsToLaunch
+	if len(permissions.AccountIds) > 0 || permissions.GlobalPermission {
+		if _, _, err := regionconn.ImageApi.UpdateImage(ctx, &osc.UpdateImageOpts{
+			UpdateImageRequest: optional.NewInterface(osc.UpdateImageRequest{
+				ImageId: id,
+				PermissionsToLaunch: osc.PermissionsOnResourceCreation{
+					Additions: permissions,
+				},
+			}),
+		}); err != nil {
+			return id, nil, fmt.Errorf("Error copying launch permissions to OMI (%s) in region (%s): %s", id, region, DecodeError(err))
+		}
+	}
+
+	imageResp, _, err := regionconn.ImageApi.ReadImages(ctx, &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
+			Filters: osc.FiltersImage{
+				ImageIds: []string{id},
+			},
+		}),
+	})
+	if err != nil || len(imageResp.Images) == 0 {
+		return id, nil, fmt.Errorf("Error retrieving details for OMI (%s) in region (%s): %v", id, region, DecodeError(err))
+	}
+
+	var snapshotIds []string
+	for _, blockDeviceMapping := range imageResp.Images[0].BlockDeviceMappings {
+		if blockDeviceMapping.Bsu.SnapshotId != "" {
+			snapshotIds = append(snapshotIds, blockDeviceMapping.Bsu.SnapshotId)
+		}
+	}
+
+	return id, snapshotIds, nil
+}
+
+func (s *StepCopyOMI) Cleanup(state multistep.StateBag) {
+	if len(s.copiedOmis) == 0 {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+
+	// Cleanup has no context and runs after the build one has been cancelled,
+	// so the calls below are not tied to it.
+	ui.Say("Deregistering the copied OMIs because cancellation or error...")
+	for region, id := range s.copiedOmis {
+		regionconn := s.AccessConfig.NewOSCClientByRegion(region)
+		image, err := readImage(regionconn, id)
+		if err == nil {
+			err = deleteOMI(regionconn, ui, image, true)
+		}
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error deregistering OMI (%s) in region (%s), may still be around: %s", id, region, err))
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+func TestStepCopyOMI_noRegions(t *testing.T) {
+	stepCopyOMI := StepCopyOMI{
+		AccessConfig: &AccessConfig{RawRegion: "us-west-2"},
+	}
+	state := tState()
+
+	action := stepCopyOMI.Run(context.Background(), state)
+	if err := state.Get("error"); err != nil {
+		t.Fatalf("should not error, but: %v", err)
+	}
+
+	if action != multistep.ActionContinue {
+		t.Fatalf("should continue, but: %v", action)
+	}
+
+	omis := state.Get("omis").(map[string]string)
+	if len(omis) != 1 || omis["us-west-2"] != "omi-12345" {
+		t.Fatalf("omis should be untouched, got: %#v", omis)
+	}
+}
+
+func TestStepCopyOMI_missingSourceOMI(t *testing.T) {
+	stepCopyOMI := StepCopyOMI{
+		AccessConfig: &AccessConfig{RawRegion: "eu-west-2"},
+		Regions:      []string{"us-east-2"},
+	}
+	state := tState()
+
+	action := stepCopyOMI.Run(context.Background(), state)
+	if action != multistep.ActionHalt {
+		t.Fatalf("should halt, but: %v", action)
+	}
+
+	if err := state.Get("error"); err == nil {
+		t.Fatal("should error when no OMI was built in the session region")
+	}
+}
+
+func TestStepCopyOMI_cleanup(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "packer"})
+
+	stepCopyOMI := StepCopyOMI{
+		AccessConfig:  testServerConfig(server),
+		PollingConfig: new(PollingConfig),
+		Regions:       []string{"us-east-2"},
+	}
+	state := testServerState(t, server)
+	state.Put("omis", map[string]string{"eu-west-2": source.ImageId})
+	state.Put("snapshots", map[string][]string{})
+
+	if action := stepCopyOMI.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+	if images := server.Images(); len(images) != 2 {
+		t.Fatalf("the OMI should be copied, got %#v", images)
+	}
+
+	state.Put(multistep.StateHalted, true)
+	stepCopyOMI.Cleanup(state)
+
+	if images := server.Images(); len(images) != 1 || images[0].ImageId != source.ImageId {
+		t.Fatalf("the copied OMI should be deregistered, got %#v", images)
+	}
+	if snapshots := server.Snapshots(); len(snapshots) != 1 || snapshots[0].SnapshotId != source.BlockDeviceMappings[0].Bsu.SnapshotId {
+		t.Fatalf("the snapshots of the copied OMI should be deleted, got %#v", snapshots)
+	}
+}
+
+func TestStepCopyOMI_skipsSessionRegion(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "packer"})
+
+	// omi_regions is prepared before the session region is resolved from the
+	// environment or a profile, so it may still hold that region.
+	stepCopyOMI := StepCopyOMI{
+		AccessConfig:  testServerConfig(server),
+		PollingConfig: new(PollingConfig),
+		Regions:       []string{"eu-west-2", "us-east-2"},
+	}
+	state := testServerState(t, server)
+	state.Put("omis", map[string]string{"eu-west-2": source.ImageId})
+	state.Put("snapshots", map[string][]string{})
+
+	if action := stepCopyOMI.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+	if images := server.Images(); len(images) != 2 {
+		t.Fatalf("the OMI should only be copied to us-east-2, got %#v", images)
+	}
+	if omis := state.Get("omis").(map[string]string); omis["eu-west-2"] != source.ImageId {
+		t.Fatalf("the source OMI should be kept, got %#v", omis)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/antihax/optional"
-	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
@@ -45,7 +44,7 @@ func (s *StepCreateTags) Run(_ context.Context, state multistep.StateBag) multis
 		})
 
 		if err != nil {
-			err := fmt.Errorf("Error retrieving details for OMI (%s): %s", ami, err)
+			err := fmt.Errorf("Error retrieving details for OMI (%s): %s", ami, DecodeError(err))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
@@ -91,20 +90,24 @@ func (s *StepCreateTags) Run(_ context.Context, state multistep.StateBag) multis
 
 		// Retry creating tags for about 2.5 minutes
 		err = retry.Run(0.2, 30, 11, func(_ uint) (bool, error) {
-			// Tag images and snapshots
-			_, _, err := regionconn.TagApi.CreateTags(context.Background(), &osc.CreateTagsOpts{
-				CreateTagsRequest: optional.NewInterface(osc.CreateTagsRequest{
-					ResourceIds: resourceIds,
-					Tags:        amiTags,
-				}),
-			})
-			if awsErr, ok := err.(awserr.Error); ok {
-				if awsErr.Code() == "InvalidOMIID.NotFound" ||
-					awsErr.Code() == "InvalidSnapshot.NotFound" {
-					return false, nil
+			// Tag images and snapshots, OAPI rejects an empty list of tags
+			if len(amiTags) > 0 {
+				_, _, err := regionconn.TagApi.CreateTags(context.Background(), &osc.CreateTagsOpts{
+					CreateTagsRequest: optional.NewInterface(osc.CreateTagsRequest{
+						ResourceIds: resourceIds,
+						Tags:        amiTags,
+					}),
+				})
+				if err != nil {
+					if IsNotFoundError(err) || IsThrottledError(err) {
+						return false, nil
+					}
+					return true, DecodeError(err)
 				}
 			}
 
+			var err error
+
 			// Override tags on snapshots
 			if len(snapshotTags) > 0 {
 				_, _, err = regionconn.TagApi.CreateTags(context.Background(), &osc.CreateTagsOpts{
@@ -117,12 +120,10 @@ func (s *StepCreateTags) Run(_ context.Context, state multistep.StateBag) multis
 			if err == nil {
 				return true, nil
 			}
-			if awsErr, ok := err.(awserr.Error); ok {
-				if awsErr.Code() == "InvalidSnapshot.NotFound" {
-					return false, nil
-				}
+			if IsNotFoundError(err) || IsThrottledError(err) {
+				return false, nil
 			}
-			return true, err
+			return true, DecodeError(err)
 		})
 
 		if err != nil {
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+func TestStepCreateTags_snapshotTagsOnly(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	image := server.AddImage("eu-west-2", osc.Image{ImageName: "packer"})
+
+	step := &StepCreateTags{SnapshotTags: TagMap{"Name": "{{ .BuildRegion }}"}}
+	state := testServerState(t, server)
+	state.Put("omis", map[string]string{"eu-west-2": image.ImageId})
+
+	// OAPI rejects CreateTags without tags, so the OMI is not tagged.
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+	if tags := server.Images()[0].Tags; len(tags) != 0 {
+		t.Fatalf("the OMI should not be tagged, got %#v", tags)
+	}
+	snapshots := server.Snapshots()
+	if len(snapshots) != 1 || len(snapshots[0].Tags) != 1 || snapshots[0].Tags[0].Value != "eu-west-2" {
+		t.Fatalf("the snapshot should be tagged, got %#v", snapshots)
+	}
+}
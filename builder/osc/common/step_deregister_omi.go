@@ -3,7 +3,9 @@ package common
 import (
 	"context"
 	"fmt"
-	"log"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/antihax/optional"
 	"github.com/outscale/osc-sdk-go/osc"
@@ -12,96 +14,446 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
+// The tags set on the OMIs renamed by the rename-old policy, holding the
+// time they were superseded and the ID of the original OMI.
+const (
+	supersededTag   = "superseded"
+	supersededIdTag = "superseded-omi-id"
+)
+
+// omiNameMaxLength is the maximum length of an OMI name.
+const omiNameMaxLength = 128
+
+// suffixBatch is the number of suffixed names looked up at once by the
+// suffix policy.
+const suffixBatch = 50
+
+// StepDeregisterOMI applies the NameConflict policy to the OMIs with the
+// same name as the one being built, in the session region and in Regions:
+// deregister deletes them, rename-old renames them and tags them as
+// superseded, and suffix picks a free name for the new OMI.
+//
+// Produces:
+//
+//	omi_name string - the name of the OMI to create
 type StepDeregisterOMI struct {
 	AccessConfig        *AccessConfig
-	ForceDeregister     bool
+	PollingConfig       *PollingConfig
+	NameConflict        string
 	ForceDeleteSnapshot bool
 	OMIName             string
 	Regions             []string
 }
 
-func (s *StepDeregisterOMI) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
-	// Check for force deregister
-	if !s.ForceDeregister {
+func (s *StepDeregisterOMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if s.NameConflict == "" || s.NameConflict == OMINameConflictFail {
+		state.Put("omi_name", s.OMIName)
 		return multistep.ActionContinue
 	}
 
-	ui := state.Get("ui").(packersdk.Ui)
+	regions := append([]string{s.AccessConfig.GetRegion()}, s.Regions...)
 
-	s.Regions = append(s.Regions, s.AccessConfig.GetRegion())
+	// Only the OMIs of the account conflict with the new one, and only them
+	// can be deregistered.
+	accountId, err := readAccountId(s.AccessConfig.NewOSCClientByRegion(regions[0]))
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	name := s.OMIName
+	switch s.NameConflict {
+	case OMINameConflictDeregister:
+		err = s.deregister(accountId, regions, ui)
+	case OMINameConflictRenameOld:
+		err = s.renameOld(ctx, accountId, regions, ui)
+	case OMINameConflictSuffix:
+		name, err = s.freeName(accountId, regions)
+		if err == nil && name != s.OMIName {
+			ui.Say(fmt.Sprintf("OMI name %s is already used, naming the OMI %s", s.OMIName, name))
+		}
+	}
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("omi_name", name)
+	return multistep.ActionContinue
+}
 
-	log.Printf("LOG_ s.Regions: %#+v\n", s.Regions)
+// readOMIs returns the OMIs of the account in the region with one of the
+// names.
+func readOMIs(conn *osc.APIClient, accountId string, names []string) ([]osc.Image, error) {
+	resp, _, err := conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
+			Filters: osc.FiltersImage{
+				AccountIds: []string{accountId},
+				ImageNames: names,
+			},
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error describing OMI: %s", DecodeError(err))
+	}
+	return resp.Images, nil
+}
 
-	for _, region := range s.Regions {
+func (s *StepDeregisterOMI) deregister(accountId string, regions []string, ui packersdk.Ui) error {
+	for _, region := range regions {
 		// get new connection for each region in which we need to deregister vms
 		conn := s.AccessConfig.NewOSCClientByRegion(region)
 
-		resp, _, err := conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
-			ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
-				Filters: osc.FiltersImage{
-					ImageNames: []string{s.OMIName},
-					//AccountAliases: []string{"self"},
-				},
+		images, err := readOMIs(conn, accountId, []string{s.OMIName})
+		if err != nil {
+			return err
+		}
+
+		// Deregister image(s) by name
+		for _, image := range images {
+			if err := deleteOMI(conn, ui, image, s.ForceDeleteSnapshot); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deleteOMI deregisters the image and, if deleteSnapshots is set, deletes
+// its snapshots.
+func deleteOMI(conn *osc.APIClient, ui packersdk.Ui, image osc.Image, deleteSnapshots bool) error {
+	//We are supposing that DeleteImage does the same action as DeregisterImage
+	_, _, err := conn.ImageApi.DeleteImage(context.Background(), &osc.DeleteImageOpts{
+		DeleteImageRequest: optional.NewInterface(osc.DeleteImageRequest{
+			ImageId: image.ImageId,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("Error deregistering existing OMI: %s", DecodeError(err))
+	}
+
+	ui.Say(fmt.Sprintf("Deregistered OMI %s, id: %s", image.ImageName, image.ImageId))
+
+	// Delete snapshot(s) by image
+	if !deleteSnapshots {
+		return nil
+	}
+	for _, b := range image.BlockDeviceMappings {
+		if b.Bsu.SnapshotId == "" {
+			continue
+		}
+
+		_, _, err := conn.SnapshotApi.DeleteSnapshot(context.Background(), &osc.DeleteSnapshotOpts{
+			DeleteSnapshotRequest: optional.NewInterface(osc.DeleteSnapshotRequest{
+				SnapshotId: b.Bsu.SnapshotId,
 			}),
 		})
-
 		if err != nil {
-			err := fmt.Errorf("Error describing OMI: %s", err)
-			state.Put("error", err)
-			ui.Error(err.Error())
-
-			return multistep.ActionHalt
+			return fmt.Errorf("Error deleting existing snapshot: %s", DecodeError(err))
 		}
 
-		log.Printf("LOG_ resp.Images: %#+v\n", resp.Images)
+		ui.Say(fmt.Sprintf("Deleted snapshot: %s", b.Bsu.SnapshotId))
+	}
+	return nil
+}
 
-		// Deregister image(s) by name
-		for i := range resp.Images {
-			//We are supposing that DeleteImage does the same action as DeregisterImage
-			_, _, err := conn.ImageApi.DeleteImage(context.Background(), &osc.DeleteImageOpts{
-				DeleteImageRequest: optional.NewInterface(osc.DeleteImageRequest{
-					ImageId: resp.Images[i].ImageId,
-				}),
-			})
+// renameOld renames the OMIs with the name of the new one. As an OMI cannot
+// be renamed and its name must be free for the new one, each one is copied
+// under the name "<name>-superseded-<id>", with its tags, its permissions and
+// the superseded tags, before being deregistered. Its snapshots are deleted
+// along with it, whatever ForceDeleteSnapshot, as the copy has its own. The
+// original OMI is left untouched if the copy fails, and the copy is deleted if
+// the original cannot be deregistered.
+func (s *StepDeregisterOMI) renameOld(ctx context.Context, accountId string, regions []string, ui packersdk.Ui) error {
+	supersededAt := time.Now().UTC().Format(time.RFC3339)
+
+	for _, region := range regions {
+		conn := s.AccessConfig.NewOSCClientByRegion(region)
+
+		images, err := readOMIs(conn, accountId, []string{s.OMIName})
+		if err != nil {
+			return err
+		}
+
+		for _, image := range images {
+			name := supersededName(image)
+			ui.Say(fmt.Sprintf("Renaming OMI %s (%s) to %s...", image.ImageName, image.ImageId, name))
 
+			copied, err := s.copySuperseded(ctx, conn, region, name, image, supersededAt)
 			if err != nil {
-				err := fmt.Errorf("Error deregistering existing OMI: %s", err)
-				state.Put("error", err)
-				ui.Error(err.Error())
+				if copied != "" {
+					s.deleteCopy(conn, ui, copied)
+				}
+				return fmt.Errorf("Error renaming OMI (%s) in region (%s): %s", image.ImageId, region, err)
+			}
+
+			if err := deleteOMI(conn, ui, image, true); err != nil {
+				// The copy is only deleted while the original is still
+				// around: the failure may come from its snapshots.
+				if _, readErr := readImage(conn, image.ImageId); readErr == nil {
+					s.deleteCopy(conn, ui, copied)
+				}
+				return err
+			}
+			ui.Message(fmt.Sprintf("Renamed OMI: %s, id: %s", name, copied))
+		}
+	}
+	return nil
+}
+
+// supersededName returns the name of the copy of the superseded image,
+// shortening the original name to fit the 128 characters of an OMI name.
+func supersededName(image osc.Image) string {
+	suffix := "-superseded-" + image.ImageId
+	name := image.ImageName
+	if len(name)+len(suffix) > omiNameMaxLength {
+		name = name[:omiNameMaxLength-len(suffix)]
+	}
+	return name + suffix
+}
 
-				return multistep.ActionHalt
+// copySuperseded copies the superseded image under the name, and gives the
+// copy the tags, the product codes and the permissions of the image. It
+// returns the ID of the copy, even on a later failure, so it can be deleted.
+func (s *StepDeregisterOMI) copySuperseded(ctx context.Context, conn *osc.APIClient, region, name string, image osc.Image, supersededAt string) (string, error) {
+	resp, _, err := conn.ImageApi.CreateImage(context.Background(), &osc.CreateImageOpts{
+		CreateImageRequest: optional.NewInterface(osc.CreateImageRequest{
+			ImageName:        name,
+			Description:      image.Description,
+			SourceImageId:    image.ImageId,
+			SourceRegionName: region,
+		}),
+	})
+	if err != nil {
+		return "", DecodeError(err)
+	}
+	id := resp.Image.ImageId
+
+	if err := s.PollingConfig.WaitUntilOscImageAvailable(ctx, conn, id); err != nil {
+		return id, err
+	}
+	copied, err := readImage(conn, id)
+	if err != nil {
+		return id, err
+	}
+	for _, code := range image.ProductCodes {
+		if !contains(copied.ProductCodes, code) {
+			return id, fmt.Errorf("the copy %s lacks the product code %s", id, code)
+		}
+	}
+	// The snapshots of the image are deleted with it, so the copy must not
+	// use them.
+	for _, mapping := range image.BlockDeviceMappings {
+		for _, copiedMapping := range copied.BlockDeviceMappings {
+			if mapping.Bsu.SnapshotId != "" && copiedMapping.Bsu.SnapshotId == mapping.Bsu.SnapshotId {
+				return id, fmt.Errorf("the copy %s uses the snapshot %s of the original", id, mapping.Bsu.SnapshotId)
 			}
+		}
+	}
+
+	tags := append([]osc.ResourceTag{
+		{Key: supersededTag, Value: supersededAt},
+		{Key: supersededIdTag, Value: image.ImageId},
+	}, image.Tags...)
+	if _, _, err := conn.TagApi.CreateTags(context.Background(), &osc.CreateTagsOpts{
+		CreateTagsRequest: optional.NewInterface(osc.CreateTagsRequest{
+			ResourceIds: []string{id},
+			Tags:        tags,
+		}),
+	}); err != nil {
+		return id, DecodeError(err)
+	}
 
-			ui.Say(fmt.Sprintf("Deregistered OMI %s, id: %s", s.OMIName, resp.Images[i].ImageId))
+	permissions := image.PermissionsToLaunch
+	if hasPermissions(permissions) {
+		if _, _, err := conn.ImageApi.UpdateImage(context.Background(), &osc.UpdateImageOpts{
+			UpdateImageRequest: optional.NewInterface(osc.UpdateImageRequest{
+				ImageId: id,
+				PermissionsToLaunch: osc.PermissionsOnResourceCreation{
+					Additions: permissions,
+				},
+			}),
+		}); err != nil {
+			return id, DecodeError(err)
+		}
+	}
+
+	return id, s.copySnapshotPermissions(conn, image, copied)
+}
+
+// copySnapshotPermissions gives the snapshots of the copy the permissions
+// to create volumes of the snapshots of the image, device by device.
+func (s *StepDeregisterOMI) copySnapshotPermissions(conn *osc.APIClient, image, copied osc.Image) error {
+	var ids []string
+	for _, mapping := range image.BlockDeviceMappings {
+		if mapping.Bsu.SnapshotId != "" {
+			ids = append(ids, mapping.Bsu.SnapshotId)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	permissions, err := readSnapshotPermissions(conn, ids)
+	if err != nil {
+		return err
+	}
 
-			// Delete snapshot(s) by image
-			if s.ForceDeleteSnapshot {
-				for _, b := range resp.Images[i].BlockDeviceMappings {
-					if b.Bsu.SnapshotId != "" {
+	for _, mapping := range image.BlockDeviceMappings {
+		wanted := permissions[mapping.Bsu.SnapshotId]
+		if mapping.Bsu.SnapshotId == "" || !hasPermissions(wanted) {
+			continue
+		}
+		for _, copiedMapping := range copied.BlockDeviceMappings {
+			if copiedMapping.DeviceName != mapping.DeviceName || copiedMapping.Bsu.SnapshotId == "" {
+				continue
+			}
+			if _, _, err := conn.SnapshotApi.UpdateSnapshot(context.Background(), &osc.UpdateSnapshotOpts{
+				UpdateSnapshotRequest: optional.NewInterface(osc.UpdateSnapshotRequest{
+					SnapshotId:                copiedMapping.Bsu.SnapshotId,
+					PermissionsToCreateVolume: osc.PermissionsOnResourceCreation{Additions: wanted},
+				}),
+			}); err != nil {
+				return DecodeError(err)
+			}
+		}
+	}
+	return nil
+}
 
-						_, _, err := conn.SnapshotApi.DeleteSnapshot(context.Background(), &osc.DeleteSnapshotOpts{
-							DeleteSnapshotRequest: optional.NewInterface(osc.DeleteSnapshotRequest{
-								SnapshotId: b.Bsu.SnapshotId,
-							}),
-						})
+// deleteCopy deletes the copy of a superseded image, with its snapshots.
+func (s *StepDeregisterOMI) deleteCopy(conn *osc.APIClient, ui packersdk.Ui, id string) {
+	copied, err := readImage(conn, id)
+	if err == nil {
+		err = deleteOMI(conn, ui, copied, true)
+	}
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error deleting the copy %s, may still be around: %s", id, err))
+	}
+}
 
-						if err != nil {
-							err := fmt.Errorf("Error deleting existing snapshot: %s", err)
-							state.Put("error", err)
-							ui.Error(err.Error())
+// freeName returns the OMI name, followed by "-<n>" when it is already used
+// in one of the regions, n being one more than the highest suffix in use.
+func (s *StepDeregisterOMI) freeName(accountId string, regions []string) (string, error) {
+	reSuffix := regexp.MustCompile(`^` + regexp.QuoteMeta(s.OMIName) + `-(\d+)$`)
 
-							return multistep.ActionHalt
-						}
+	conns := make([]*osc.APIClient, len(regions))
+	for i, region := range regions {
+		conns[i] = s.AccessConfig.NewOSCClientByRegion(region)
+	}
 
-						ui.Say(fmt.Sprintf("Deleted snapshot: %s", b.Bsu.SnapshotId))
+	// The names are looked up by batches, until one is free of OMIs in
+	// every region.
+	used := false
+	highest := 0
+	for first := 0; ; first += suffixBatch {
+		var names []string
+		for n := first; n < first+suffixBatch; n++ {
+			if n == 0 {
+				names = append(names, s.OMIName)
+			} else {
+				names = append(names, fmt.Sprintf("%s-%d", s.OMIName, n))
+			}
+		}
+
+		found := false
+		for _, conn := range conns {
+			images, err := readOMIs(conn, accountId, names)
+			if err != nil {
+				return "", err
+			}
+			for _, image := range images {
+				found = true
+				if m := reSuffix.FindStringSubmatch(image.ImageName); m != nil {
+					if n, _ := strconv.Atoi(m[1]); n > highest {
+						highest = n
 					}
 				}
 			}
 		}
+		if !found {
+			break
+		}
+		used = true
 	}
 
-	return multistep.ActionContinue
+	if !used {
+		return s.OMIName, nil
+	}
+	return fmt.Sprintf("%s-%d", s.OMIName, highest+1), nil
 }
 
 func (s *StepDeregisterOMI) Cleanup(state multistep.StateBag) {
 }
+
+// OMIName returns the name of the OMI to create: the one picked by
+// StepDeregisterOMI, or name when the step did not run.
+func OMIName(state multistep.StateBag, name string) string {
+	if picked, ok := state.GetOk("omi_name"); ok {
+		return picked.(string)
+	}
+	return name
+}
+
+// readAccountId returns the ID of the account of the credentials.
+func readAccountId(conn *osc.APIClient) (string, error) {
+	resp, _, err := conn.AccountApi.ReadAccounts(context.Background(), &osc.ReadAccountsOpts{
+		ReadAccountsRequest: optional.NewInterface(osc.ReadAccountsRequest{}),
+	})
+	if err != nil || len(resp.Accounts) == 0 {
+		return "", fmt.Errorf("Error retrieving the account: %v", DecodeError(err))
+	}
+	return resp.Accounts[0].AccountId, nil
+}
+
+func readImage(conn *osc.APIClient, id string) (osc.Image, error) {
+	resp, _, err := conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
+			Filters: osc.FiltersImage{ImageIds: []string{id}},
+		}),
+	})
+	if err != nil || len(resp.Images) == 0 {
+		return osc.Image{}, fmt.Errorf("Error retrieving details for OMI (%s): %v", id, DecodeError(err))
+	}
+	return resp.Images[0], nil
+}
+
+// readSnapshotPermissions returns the permissions to create volumes of the
+// snapshots, by ID.
+func readSnapshotPermissions(conn *osc.APIClient, ids []string) (map[string]osc.PermissionsOnResource, error) {
+	resp, _, err := conn.SnapshotApi.ReadSnapshots(context.Background(), &osc.ReadSnapshotsOpts{
+		ReadSnapshotsRequest: optional.NewInterface(osc.ReadSnapshotsRequest{
+			Filters: osc.FiltersSnapshot{SnapshotIds: ids},
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error retrieving details for snapshots %v: %s", ids, DecodeError(err))
+	}
+
+	permissions := make(map[string]osc.PermissionsOnResource)
+	for _, snapshot := range resp.Snapshots {
+		permissions[snapshot.SnapshotId] = snapshot.PermissionsToCreateVolume
+	}
+	for _, id := range ids {
+		if _, ok := permissions[id]; !ok {
+			return nil, fmt.Errorf("Error retrieving details for snapshot (%s): not found", id)
+		}
+	}
+	return permissions, nil
+}
+
+func hasPermissions(permissions osc.PermissionsOnResource) bool {
+	return len(permissions.AccountIds) > 0 || permissions.GlobalPermission
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
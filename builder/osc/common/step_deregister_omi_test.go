@@ -0,0 +1,202 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/antihax/optional"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+func testDeregisterOMI(t *testing.T, server *oapitest.Server, policy string) (*StepDeregisterOMI, multistep.StateBag) {
+	server.Transitions = 0
+	step := &StepDeregisterOMI{
+		AccessConfig:  testServerConfig(server),
+		PollingConfig: new(PollingConfig),
+		NameConflict:  policy,
+		OMIName:       "packer",
+		Regions:       []string{"us-east-2"},
+	}
+	return step, testServerState(t, server)
+}
+
+func TestStepDeregisterOMI_deregister(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	step, state := testDeregisterOMI(t, server, OMINameConflictDeregister)
+	step.ForceDeleteSnapshot = true
+	server.AddImage("eu-west-2", osc.Image{ImageName: "packer"})
+	server.AddImage("us-east-2", osc.Image{ImageName: "packer"})
+	other := server.AddImage("eu-west-2", osc.Image{ImageName: "packer-1"})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+	if images := server.Images(); len(images) != 1 || images[0].ImageId != other.ImageId {
+		t.Fatalf("the OMIs should be deleted in every region, got %#v", images)
+	}
+	if snapshots := server.Snapshots(); len(snapshots) != 1 {
+		t.Fatalf("the snapshots should be deleted, got %#v", snapshots)
+	}
+	if name := OMIName(state, "ignored"); name != "packer" {
+		t.Fatalf("the OMI name should be kept, got %s", name)
+	}
+}
+
+func TestStepDeregisterOMI_renameOld(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	step, state := testDeregisterOMI(t, server, OMINameConflictRenameOld)
+	old := server.AddImage("us-east-2", osc.Image{
+		ImageName:           "packer",
+		Tags:                []osc.ResourceTag{{Key: "release", Value: "1.0"}},
+		ProductCodes:        []string{"0001"},
+		PermissionsToLaunch: osc.PermissionsOnResource{AccountIds: []string{"222222222222"}},
+	})
+	oldSnapshot := old.BlockDeviceMappings[0].Bsu.SnapshotId
+	conn := step.AccessConfig.NewOSCClientByRegion("us-east-2")
+	if _, _, err := conn.SnapshotApi.UpdateSnapshot(context.Background(), &osc.UpdateSnapshotOpts{
+		UpdateSnapshotRequest: optional.NewInterface(osc.UpdateSnapshotRequest{
+			SnapshotId: oldSnapshot,
+			PermissionsToCreateVolume: osc.PermissionsOnResourceCreation{
+				Additions: osc.PermissionsOnResource{AccountIds: []string{"333333333333"}},
+			},
+		}),
+	}); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+
+	images := server.Images()
+	if len(images) != 1 {
+		t.Fatalf("the old OMI should be replaced by its copy, got %#v", images)
+	}
+	copied := images[0]
+	if name := "packer-superseded-" + old.ImageId; copied.ImageName != name {
+		t.Fatalf("the old OMI should be renamed %s, got %s", name, copied.ImageName)
+	}
+	tags := make(map[string]string)
+	for _, tag := range copied.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	if tags["release"] != "1.0" || tags[supersededTag] == "" || tags[supersededIdTag] != old.ImageId {
+		t.Fatalf("the renamed OMI should keep its tags and be tagged as superseded, got %#v", copied.Tags)
+	}
+	if !contains(copied.ProductCodes, "0001") || !contains(copied.PermissionsToLaunch.AccountIds, "222222222222") {
+		t.Fatalf("the renamed OMI should keep its product codes and launch permissions, got %#v", copied)
+	}
+
+	permissions, err := readSnapshotPermissions(conn, []string{copied.BlockDeviceMappings[0].Bsu.SnapshotId})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if p := permissions[copied.BlockDeviceMappings[0].Bsu.SnapshotId]; !contains(p.AccountIds, "333333333333") {
+		t.Fatalf("the snapshots of the renamed OMI should keep their permissions, got %#v", p)
+	}
+	if snapshots := server.Snapshots(); len(snapshots) != 1 || snapshots[0].SnapshotId == oldSnapshot {
+		t.Fatalf("the snapshots of the old OMI should be deleted, got %#v", snapshots)
+	}
+	if name := OMIName(state, "ignored"); name != "packer" {
+		t.Fatalf("the OMI name should be kept, got %s", name)
+	}
+}
+
+func TestStepDeregisterOMI_renameOldFailure(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	step, state := testDeregisterOMI(t, server, OMINameConflictRenameOld)
+	old := server.AddImage("eu-west-2", osc.Image{ImageName: "packer"})
+	server.Fail("CreateTags", 400, "InvalidParameterValue")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("should halt, got %v", action)
+	}
+	if images := server.Images(); len(images) != 1 || images[0].ImageId != old.ImageId {
+		t.Fatalf("the old OMI should be left untouched and its copy deleted, got %#v", images)
+	}
+	if snapshots := server.Snapshots(); len(snapshots) != 1 {
+		t.Fatalf("the snapshots of the copy should be deleted, got %#v", snapshots)
+	}
+}
+
+func TestStepDeregisterOMI_renameOldDeleteFailure(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	step, state := testDeregisterOMI(t, server, OMINameConflictRenameOld)
+	old := server.AddImage("eu-west-2", osc.Image{ImageName: "packer"})
+	server.FailOnce("DeleteImage", 409, "ResourceConflict")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("should halt, got %v", action)
+	}
+	if images := server.Images(); len(images) != 1 || images[0].ImageId != old.ImageId {
+		t.Fatalf("the copy should be deleted when the old OMI cannot be, got %#v", images)
+	}
+	if snapshots := server.Snapshots(); len(snapshots) != 1 {
+		t.Fatalf("the snapshots of the copy should be deleted, got %#v", snapshots)
+	}
+}
+
+func TestStepDeregisterOMI_otherAccounts(t *testing.T) {
+	for _, policy := range []string{OMINameConflictDeregister, OMINameConflictRenameOld, OMINameConflictSuffix} {
+		t.Run(policy, func(t *testing.T) {
+			server := oapitest.NewServer()
+			defer server.Close()
+			step, state := testDeregisterOMI(t, server, policy)
+			shared := server.AddImage("eu-west-2", osc.Image{ImageName: "packer", AccountId: "999999999999"})
+
+			if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+				t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+			}
+			if images := server.Images(); len(images) != 1 || images[0].ImageId != shared.ImageId || images[0].ImageName != "packer" {
+				t.Fatalf("the OMIs of other accounts should be left untouched, got %#v", images)
+			}
+			if name := OMIName(state, "ignored"); name != "packer" {
+				t.Fatalf("the OMIs of other accounts should not conflict, got %s", name)
+			}
+		})
+	}
+}
+
+func TestSupersededName(t *testing.T) {
+	image := osc.Image{ImageId: "ami-00000001", ImageName: strings.Repeat("a", 128)}
+	name := supersededName(image)
+	if len(name) != 128 || !strings.HasSuffix(name, "-superseded-ami-00000001") {
+		t.Fatalf("the name should be shortened to 128 characters, got %s", name)
+	}
+}
+
+func TestStepDeregisterOMI_suffix(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	step, state := testDeregisterOMI(t, server, OMINameConflictSuffix)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+	if name := OMIName(state, "ignored"); name != "packer" {
+		t.Fatalf("a free name should be kept, got %s", name)
+	}
+
+	server.AddImage("eu-west-2", osc.Image{ImageName: "packer"})
+	server.AddImage("eu-west-2", osc.Image{ImageName: "packer-2"})
+	server.AddImage("us-east-2", osc.Image{ImageName: "packer-72"})
+	server.AddImage("us-east-2", osc.Image{ImageName: "packer-final"})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+	if name := OMIName(state, "ignored"); name != "packer-73" {
+		t.Fatalf("the highest suffix of every region should be incremented, got %s", name)
+	}
+	if images := server.Images(); len(images) != 4 {
+		t.Fatalf("the existing OMIs should be left untouched, got %s", fmt.Sprint(images))
+	}
+}
@@ -121,7 +121,7 @@ func (s *StepGetPassword) waitForPassword(state multistep.StateBag, cancel <-cha
 			}),
 		})
 		if err != nil {
-			err := fmt.Errorf("Error retrieving auto-generated vm password: %s", err)
+			err := fmt.Errorf("Error retrieving auto-generated vm password: %s", DecodeError(err))
 			return "", err
 		}
 
@@ -64,7 +64,7 @@ func (s *StepKeyPair) Run(_ context.Context, state multistep.StateBag) multistep
 	})
 
 	if err != nil {
-		state.Put("error", fmt.Errorf("Error creating temporary keypair: %s", err))
+		state.Put("error", fmt.Errorf("Error creating temporary keypair: %s", DecodeError(err)))
 		return multistep.ActionHalt
 	}
 
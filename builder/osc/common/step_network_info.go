@@ -10,6 +10,7 @@ import (
 	"github.com/antihax/optional"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/outscale/osc-sdk-go/osc"
 )
 
@@ -21,6 +22,10 @@ import (
 //	vpc_id string - the NET ID
 //	subnet_id string - the Subnet ID
 //	availability_zone string - the Subregion name
+//
+// When TemporaryNet is set, the Net and the Subnet are created for the build
+// and deleted on cleanup. A Subnet found by SubnetFilter must have at least
+// RequiredIps free IPs, which StepPreValidate only checks for a subnet_id.
 type StepNetworkInfo struct {
 	NetId               string
 	NetFilter           NetFilterOptions
@@ -29,6 +34,13 @@ type StepNetworkInfo struct {
 	SubregionName       string
 	SecurityGroupIds    []string
 	SecurityGroupFilter SecurityGroupFilterOptions
+	TemporaryNet        *TemporaryNetConfig
+	RequiredIps         int
+	PollingConfig       *PollingConfig
+	Ctx                 interpolate.Context
+	RawRegion           string
+
+	temporaryNet *temporaryNet
 }
 
 type subnetsOscSort []osc.Subnet
@@ -47,86 +59,64 @@ func mostFreeOscSubnet(subnets []osc.Subnet) osc.Subnet {
 }
 
 // Run ...
-func (s *StepNetworkInfo) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+func (s *StepNetworkInfo) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	oscconn := state.Get("osc").(*osc.APIClient)
 	ui := state.Get("ui").(packersdk.Ui)
 
-	// NET
-	if s.NetId == "" && !s.NetFilter.Empty() {
-		params := osc.ReadNetsRequest{}
-		params.Filters = buildOscNetFilters(s.NetFilter.Filters)
-		s.NetFilter.Filters["state"] = "available"
-
-		log.Printf("Using NET Filters %v", params)
-
-		vpcResp, _, err := oscconn.NetApi.ReadNets(context.Background(), &osc.ReadNetsOpts{
-			ReadNetsRequest: optional.NewInterface(params),
-		})
+	if s.TemporaryNet != nil {
+		tags, err := s.TemporaryNet.Tags.OSCTags(s.Ctx, s.RawRegion, state)
 		if err != nil {
-			err := fmt.Errorf("Error querying NETs: %s", err)
+			err := fmt.Errorf("Error tagging temporary Net: %s", err)
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
+		tags.Report(ui)
 
-		if len(vpcResp.Nets) != 1 {
-			err := fmt.Errorf("Exactly one NET should match the filter, but %d NET's was found matching filters: %v", len(vpcResp.Nets), params)
+		s.temporaryNet = &temporaryNet{conn: oscconn, ui: ui, config: s.TemporaryNet, tags: tags}
+		subnet, err := s.temporaryNet.create(ctx, s.PollingConfig, s.SubregionName)
+		if err != nil {
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
 
-		s.NetId = vpcResp.Nets[0].NetId
-		ui.Message(fmt.Sprintf("Found NET ID: %s", s.NetId))
+		s.NetId = subnet.NetId
+		s.SubnetId = subnet.SubnetId
+		s.SubregionName = subnet.SubregionName
+		ui.Message(fmt.Sprintf("Created NET ID: %s", s.NetId))
+		ui.Message(fmt.Sprintf("Created Subnet ID: %s", s.SubnetId))
 	}
 
-	// Subnet
-	if s.SubnetId == "" && !s.SubnetFilter.Empty() {
-		params := osc.ReadSubnetsRequest{}
-		s.SubnetFilter.Filters["state"] = "available"
-
-		if s.NetId != "" {
-			s.SubnetFilter.Filters["vpc-id"] = s.NetId
-		}
-		if s.SubregionName != "" {
-			s.SubnetFilter.Filters["availability-zone"] = s.SubregionName
-		}
-		params.Filters = buildOscSubnetFilters(s.SubnetFilter.Filters)
-		log.Printf("Using Subnet Filters %v", params)
-
-		subnetsResp, _, err := oscconn.SubnetApi.ReadSubnets(context.Background(), &osc.ReadSubnetsOpts{
-			ReadSubnetsRequest: optional.NewInterface(params),
-		})
+	// NET
+	if s.NetId == "" && !s.NetFilter.Empty() {
+		net, err := s.NetFilter.GetFilteredNet(oscconn)
 		if err != nil {
-			err := fmt.Errorf("error querying Subnets: %s", err)
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
 
-		if len(subnetsResp.Subnets) == 0 {
-			err := fmt.Errorf("No Subnets was found matching filters: %v", params)
+		s.NetId = net.NetId
+		ui.Message(fmt.Sprintf("Found NET ID: %s", s.NetId))
+	}
+
+	// Subnet
+	if s.SubnetId == "" && !s.SubnetFilter.Empty() {
+		subnet, err := s.SubnetFilter.GetFilteredSubnet(s.NetId, s.SubregionName, oscconn)
+		if err != nil {
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
 
-		if len(subnetsResp.Subnets) > 1 && !s.SubnetFilter.Random && !s.SubnetFilter.MostFree {
-			err := fmt.Errorf("your filter matched %d Subnets. Please try a more specific search, or set random or most_free to true", len(subnetsResp.Subnets))
+		if int(subnet.AvailableIpsCount) < s.RequiredIps {
+			err := fmt.Errorf("The Subnet %s has %d free IPs, but the source VM needs %d", subnet.SubnetId, subnet.AvailableIpsCount, s.RequiredIps)
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
 
-		var subnet osc.Subnet
-		switch {
-		case s.SubnetFilter.MostFree:
-			subnet = mostFreeOscSubnet(subnetsResp.Subnets)
-		case s.SubnetFilter.Random:
-			subnet = subnetsResp.Subnets[rand.Intn(len(subnetsResp.Subnets))]
-		default:
-			subnet = subnetsResp.Subnets[0]
-		}
 		s.SubnetId = subnet.SubnetId
 		ui.Message(fmt.Sprintf("Found Subnet ID: %s", s.SubnetId))
 	}
@@ -142,7 +132,7 @@ func (s *StepNetworkInfo) Run(_ context.Context, state multistep.StateBag) multi
 			}),
 		})
 		if err != nil {
-			err := fmt.Errorf("describing the subnet: %s returned error: %s", s.SubnetId, err)
+			err := fmt.Errorf("describing the subnet: %s returned error: %s", s.SubnetId, DecodeError(err))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
@@ -163,5 +153,87 @@ func (s *StepNetworkInfo) Run(_ context.Context, state multistep.StateBag) multi
 	return multistep.ActionContinue
 }
 
-// Cleanup ...
-func (s *StepNetworkInfo) Cleanup(multistep.StateBag) {}
+// GetFilteredNet returns the only available Net matching the filters.
+func (d *NetFilterOptions) GetFilteredNet(oscconn *osc.APIClient) (*osc.Net, error) {
+	filters := make(map[string]string, len(d.Filters)+1)
+	for k, v := range d.Filters {
+		filters[k] = v
+	}
+	filters["state"] = "available"
+
+	params := osc.ReadNetsRequest{}
+	params.Filters = buildOscNetFilters(filters)
+
+	log.Printf("Using NET Filters %v", params)
+
+	vpcResp, _, err := oscconn.NetApi.ReadNets(context.Background(), &osc.ReadNetsOpts{
+		ReadNetsRequest: optional.NewInterface(params),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error querying NETs: %s", DecodeError(err))
+	}
+
+	if len(vpcResp.Nets) != 1 {
+		return nil, fmt.Errorf("Exactly one NET should match the filter, but %d NET's was found matching filters: %v", len(vpcResp.Nets), params)
+	}
+
+	return &vpcResp.Nets[0], nil
+}
+
+// GetFilteredSubnet returns the available Subnet matching the filters,
+// restricted to the given Net and Subregion when they are set. When several
+// Subnets match, most_free or random picks one of them.
+func (d *SubnetFilterOptions) GetFilteredSubnet(netID, subregionName string, oscconn *osc.APIClient) (*osc.Subnet, error) {
+	filters := make(map[string]string, len(d.Filters)+3)
+	for k, v := range d.Filters {
+		filters[k] = v
+	}
+	filters["states"] = "available"
+
+	if netID != "" {
+		filters["net-ids"] = netID
+	}
+	if subregionName != "" {
+		filters["sub-region-names"] = subregionName
+	}
+
+	params := osc.ReadSubnetsRequest{}
+	params.Filters = buildOscSubnetFilters(filters)
+	log.Printf("Using Subnet Filters %v", params)
+
+	subnetsResp, _, err := oscconn.SubnetApi.ReadSubnets(context.Background(), &osc.ReadSubnetsOpts{
+		ReadSubnetsRequest: optional.NewInterface(params),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying Subnets: %s", DecodeError(err))
+	}
+
+	if len(subnetsResp.Subnets) == 0 {
+		return nil, fmt.Errorf("No Subnets was found matching filters: %v", params)
+	}
+
+	if len(subnetsResp.Subnets) > 1 && !d.Random && !d.MostFree {
+		return nil, fmt.Errorf("your filter matched %d Subnets. Please try a more specific search, or set random or most_free to true", len(subnetsResp.Subnets))
+	}
+
+	var subnet osc.Subnet
+	switch {
+	case d.MostFree:
+		subnet = mostFreeOscSubnet(subnetsResp.Subnets)
+	case d.Random:
+		subnet = subnetsResp.Subnets[rand.Intn(len(subnetsResp.Subnets))]
+	default:
+		subnet = subnetsResp.Subnets[0]
+	}
+
+	return &subnet, nil
+}
+
+// Cleanup deletes the temporary Net, if any.
+func (s *StepNetworkInfo) Cleanup(multistep.StateBag) {
+	if s.temporaryNet == nil {
+		return
+	}
+	s.temporaryNet.delete(context.Background())
+	s.temporaryNet = nil
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+func TestStepNetworkInfo_subnetFilterFreeIps(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	n := server.AddNet("eu-west-2", "10.0.0.0/16")
+	// A /29 has 3 free IPs, the first four and the last one being reserved.
+	subnet := server.AddSubnet("eu-west-2", n.NetId, "10.0.0.0/29", "eu-west-2a")
+
+	step := &StepNetworkInfo{
+		SubnetFilter: SubnetFilterOptions{NameValueFilter: config.NameValueFilter{Filters: map[string]string{"ip-ranges": "10.0.0.0/29"}}},
+		RequiredIps:  3,
+	}
+	state := testServerState(t, server)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+	if id := state.Get("subnet_id"); id != subnet.SubnetId {
+		t.Fatalf("bad subnet: %v", id)
+	}
+
+	step = &StepNetworkInfo{
+		SubnetFilter: SubnetFilterOptions{NameValueFilter: config.NameValueFilter{Filters: map[string]string{"ip-ranges": "10.0.0.0/29"}}},
+		RequiredIps:  4,
+	}
+	state = testServerState(t, server)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("should halt when the Subnet found by the filter is too small, got %v", action)
+	}
+}
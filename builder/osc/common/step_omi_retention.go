@@ -0,0 +1,168 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// StepOMIRetention prunes the OMIs described by Retention in every region
+// the new OMI is in, deregistering them along with their snapshots. As the
+// build already succeeded, failures are only reported. The name prefix and
+// the tags are interpolated in each region, like the tags of the OMI.
+type StepOMIRetention struct {
+	AccessConfig *AccessConfig
+	Retention    *OMIRetentionConfig
+	Ctx          interpolate.Context
+}
+
+func (s *StepOMIRetention) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.Retention == nil {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	omis := state.Get("omis").(map[string]string)
+
+	regions := make([]string, 0, len(omis))
+	for region := range omis {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	if s.Retention.DryRun {
+		ui.Say("Listing the OMIs to prune (dry run)...")
+	} else {
+		ui.Say("Pruning OMIs...")
+	}
+	for _, region := range regions {
+		if err := s.prune(region, omis[region], ui, state); err != nil {
+			ui.Error(fmt.Sprintf("Error pruning OMIs in region %s: %s", region, err))
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+// prune deregisters the OMIs of the region that are not retained, keeping
+// the new one.
+func (s *StepOMIRetention) prune(region, newOmi string, ui packersdk.Ui, state multistep.StateBag) error {
+	conn := s.AccessConfig.NewOSCClientByRegion(region)
+
+	ctx := s.Ctx
+	ctx.Data = extractBuildInfo(region, state)
+	namePrefix, err := interpolate.Render(s.Retention.NamePrefix, &ctx)
+	if err != nil {
+		return fmt.Errorf("Error processing name prefix: %s", err)
+	}
+	tags, err := s.Retention.Tags.OSCTags(s.Ctx, region, state)
+	if err != nil {
+		return err
+	}
+
+	// The OMIs are looked up in the account of the new one, as only them can
+	// be deregistered.
+	resp, _, err := conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
+			Filters: osc.FiltersImage{ImageIds: []string{newOmi}},
+		}),
+	})
+	if err != nil || len(resp.Images) == 0 {
+		return fmt.Errorf("Error retrieving details for OMI (%s): %v", newOmi, DecodeError(err))
+	}
+
+	filters := osc.FiltersImage{AccountIds: []string{resp.Images[0].AccountId}}
+	for _, tag := range tags {
+		filters.Tags = append(filters.Tags, tag.Key+"="+tag.Value)
+	}
+	resp, _, err = conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
+		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{Filters: filters}),
+	})
+	if err != nil {
+		return fmt.Errorf("Error describing OMIs: %s", DecodeError(err))
+	}
+
+	var family []osc.Image
+	for _, image := range resp.Images {
+		if inFamily(image, namePrefix, tags) {
+			family = append(family, image)
+		}
+	}
+	// The newest OMIs come first.
+	sort.SliceStable(family, func(i, j int) bool {
+		return familyDate(family[i]).After(familyDate(family[j]))
+	})
+
+	var errs *packersdk.MultiError
+	for i, image := range family {
+		age := time.Since(familyDate(image))
+		if image.ImageId == newOmi || i < s.Retention.KeepLatest ||
+			(s.Retention.keepYoungerThan > 0 && age < s.Retention.keepYoungerThan) {
+			continue
+		}
+
+		if s.Retention.DryRun {
+			ui.Message(fmt.Sprintf("Would deregister OMI %s, id: %s, created %s", image.ImageName, image.ImageId, image.CreationDate))
+			continue
+		}
+		if err := deleteOMI(conn, ui, image, true); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// inFamily reports whether the image has the name prefix and every tag of
+// the retention policy.
+func inFamily(image osc.Image, namePrefix string, tags OSCTags) bool {
+	if !strings.HasPrefix(image.ImageName, namePrefix) {
+		return false
+	}
+
+	imageTags := make(map[string]string)
+	for _, tag := range image.Tags {
+		imageTags[tag.Key] = tag.Value
+	}
+	for _, tag := range tags {
+		if v, ok := imageTags[tag.Key]; !ok || v != tag.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// familyDate returns the date of the image in its family. The copies made
+// by the rename-old policy of omi_name_conflict are created afresh: they
+// are dated by the time they were superseded, so that they do not take the
+// place of the newest builds.
+func familyDate(image osc.Image) time.Time {
+	for _, tag := range image.Tags {
+		if tag.Key != supersededTag {
+			continue
+		}
+		if date, err := time.Parse(time.RFC3339, tag.Value); err == nil {
+			return date
+		}
+	}
+	return creationDate(image)
+}
+
+// creationDate returns the creation date of the image, or the zero time if
+// it cannot be parsed, so that it is pruned first.
+func creationDate(image osc.Image) time.Time {
+	date, _ := time.Parse(time.RFC3339, image.CreationDate)
+	return date
+}
+
+func (s *StepOMIRetention) Cleanup(multistep.StateBag) {}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+// testRetentionImages adds the OMIs of the web family to the region, created
+// the given number of days ago and tagged with the region, and returns their
+// IDs.
+func testRetentionImages(server *oapitest.Server, region string, days ...int) []string {
+	var ids []string
+	for _, d := range days {
+		image := server.AddImage(region, osc.Image{
+			ImageName:    fmt.Sprintf("web-%s-%d", region, d),
+			CreationDate: time.Now().UTC().AddDate(0, 0, -d).Format(time.RFC3339),
+			Tags:         []osc.ResourceTag{{Key: "family", Value: "web"}, {Key: "region", Value: region}},
+		})
+		ids = append(ids, image.ImageId)
+	}
+	return ids
+}
+
+func testOMIRetention(t *testing.T, retention *OMIRetentionConfig) (*oapitest.Server, []string, []string) {
+	server := oapitest.NewServer()
+	t.Cleanup(server.Close)
+	west := testRetentionImages(server, "eu-west-2", 0, 1, 10, 30)
+	east := testRetentionImages(server, "us-east-2", 0, 2, 40)
+	server.AddImage("eu-west-2", osc.Image{ImageName: "db", CreationDate: "2020-01-01T00:00:00Z"})
+	// The copy of an OMI renamed by the rename-old policy, superseded 20
+	// days ago.
+	server.AddImage("eu-west-2", osc.Image{
+		ImageName:    "web-eu-west-2-10-superseded-" + west[2],
+		CreationDate: time.Now().UTC().Format(time.RFC3339),
+		Tags: []osc.ResourceTag{
+			{Key: "family", Value: "web"},
+			{Key: supersededTag, Value: time.Now().UTC().AddDate(0, 0, -20).Format(time.RFC3339)},
+			{Key: supersededIdTag, Value: west[2]},
+		},
+	})
+	server.AddImage("eu-west-2", osc.Image{
+		ImageName:    "web-public",
+		AccountId:    "000000000000",
+		CreationDate: "2020-01-01T00:00:00Z",
+		Tags:         []osc.ResourceTag{{Key: "family", Value: "web"}},
+	})
+
+	if errs := retention.Prepare(); len(errs) > 0 {
+		t.Fatalf("shouldn't have err: %v", errs)
+	}
+	step := &StepOMIRetention{
+		AccessConfig: testServerConfig(server),
+		Retention:    retention,
+	}
+	state := testServerState(t, server)
+	state.Put("omis", map[string]string{"eu-west-2": west[0], "us-east-2": east[0]})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v", action)
+	}
+	return server, west, east
+}
+
+func imageIds(server *oapitest.Server) map[string]bool {
+	ids := make(map[string]bool)
+	for _, image := range server.Images() {
+		ids[image.ImageId] = true
+	}
+	return ids
+}
+
+func TestStepOMIRetention_keepLatest(t *testing.T) {
+	server, west, east := testOMIRetention(t, &OMIRetentionConfig{
+		Tags:       TagMap{"family": "web"},
+		KeepLatest: 2,
+	})
+
+	ids := imageIds(server)
+	for _, id := range []string{west[0], west[1], east[0], east[1]} {
+		if !ids[id] {
+			t.Fatalf("the newest OMIs should be kept, got %v", ids)
+		}
+	}
+	for _, id := range []string{west[2], west[3], east[2]} {
+		if ids[id] {
+			t.Fatalf("the older OMIs should be deregistered, got %v", ids)
+		}
+	}
+	// The superseded copy is dated by its superseded tag: it does not take the
+	// place of a newer build, and is pruned.
+	if len(ids) != 6 {
+		t.Fatalf("the other OMIs should be left untouched, got %v", ids)
+	}
+
+	if snapshots := server.Snapshots(); len(snapshots) != 6 {
+		t.Fatalf("the snapshots of the deregistered OMIs should be deleted, got %#v", snapshots)
+	}
+}
+
+func TestStepOMIRetention_keepYoungerThan(t *testing.T) {
+	server, west, east := testOMIRetention(t, &OMIRetentionConfig{
+		NamePrefix:      "web-",
+		KeepYoungerThan: "168h",
+	})
+
+	ids := imageIds(server)
+	if !ids[west[0]] || !ids[west[1]] || ids[west[2]] || ids[west[3]] ||
+		!ids[east[0]] || !ids[east[1]] || ids[east[2]] {
+		t.Fatalf("the OMIs older than a week should be deregistered, got %v", ids)
+	}
+	if len(ids) != 6 {
+		t.Fatalf("the superseded copy should be deregistered, got %v", ids)
+	}
+}
+
+func TestStepOMIRetention_interpolatedTags(t *testing.T) {
+	server, west, east := testOMIRetention(t, &OMIRetentionConfig{
+		Tags:       TagMap{"region": "{{ .BuildRegion }}"},
+		KeepLatest: 1,
+	})
+
+	ids := imageIds(server)
+	if !ids[west[0]] || ids[west[1]] || !ids[east[0]] || ids[east[1]] {
+		t.Fatalf("the tags should be interpolated in each region, got %v", ids)
+	}
+}
+
+func TestStepOMIRetention_dryRun(t *testing.T) {
+	server, _, _ := testOMIRetention(t, &OMIRetentionConfig{
+		NamePrefix: "web-",
+		KeepLatest: 1,
+		DryRun:     true,
+	})
+
+	if images := server.Images(); len(images) != 10 {
+		t.Fatalf("no OMI should be deregistered, got %d", len(images))
+	}
+}
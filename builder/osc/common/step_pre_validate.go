@@ -3,6 +3,10 @@ package common
 import (
 	"context"
 	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/antihax/optional"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
@@ -10,28 +14,85 @@ import (
 	"github.com/outscale/osc-sdk-go/osc"
 )
 
+// The quotas checked before launching the source VM, as named by ReadQuotas.
+const (
+	vmQuota       = "vm_limit"
+	volumeQuota   = "volume_limit"
+	publicIpQuota = "eip_limit"
+)
+
+// reTinaVmType matches the VM types built from a generation, a vCore count,
+// a memory size and a performance, such as tinav5.c2r4p2. They are not
+// listed by ReadVmTypes.
+var reTinaVmType = regexp.MustCompile(`^tinav\d+\.c\d+r\d+p[1-3]$`)
+
 // StepPreValidate provides an opportunity to pre-validate any configuration for
 // the build before actually doing any time consuming work
+//
+// Besides the OMI name, checked unless NameConflict is another policy than
+// fail, it checks the source VM against the account when VmType is set: the
+// VM type, the Subregion, the root device of the source OMI, the free IPs of
+// the Subnets given by ID and the quotas. Every problem found is reported at
+// once. The free IPs of a Subnet found by subnet_filter are checked by
+// StepNetworkInfo, once it has been resolved.
 type StepPreValidate struct {
-	DestOmiName     string
-	ForceDeregister bool
-	API             string
+	DestOmiName  string
+	NameConflict string
+	API          string
+
+	VmType                   string
+	VmTypes                  []string
+	SubregionName            string
+	SubregionNames           []string
+	SourceOmi                string
+	SourceOmiFilter          OmiFilterOptions
+	ExpectedRootDevice       string
+	BlockDevices             BlockDevices
+	SubnetId                 string
+	SubnetIds                []string
+	PrivateIp                string
+	SecondaryPrivateIps      []string
+	SecondaryPrivateIpCount  int
+	Nics                     []NicConfig
+	AssociatePublicIpAddress bool
+	PublicIpId               string
+	PublicIpFilter           PublicIpFilterOptions
 }
 
 func (s *StepPreValidate) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
-	ui := state.Get("ui").(packersdk.Ui)
-	if s.ForceDeregister {
-		ui.Say("Force Deregister flag found, skipping prevalidating OMI Name")
-		return multistep.ActionContinue
-	}
-
 	var (
-		conn   = state.Get("osc").(*osc.APIClient)
-		images []interface{}
+		ui   = state.Get("ui").(packersdk.Ui)
+		conn = state.Get("osc").(*osc.APIClient)
+		errs *packersdk.MultiError
 	)
 
-	ui.Say(fmt.Sprintf("Prevalidating OMI Name: %s", s.DestOmiName))
+	if s.NameConflict != "" && s.NameConflict != OMINameConflictFail {
+		ui.Say(fmt.Sprintf("OMI name conflicts are handled by the %s policy, skipping prevalidating OMI Name", s.NameConflict))
+	} else if s.DestOmiName != "" {
+		ui.Say(fmt.Sprintf("Prevalidating OMI Name: %s", s.DestOmiName))
+		errs = packersdk.MultiErrorAppend(errs, s.checkOmiName(conn)...)
+	}
+
+	if s.VmType != "" {
+		ui.Say("Prevalidating the source VM...")
+		errs = packersdk.MultiErrorAppend(errs, s.checkVmType(conn)...)
+		errs = packersdk.MultiErrorAppend(errs, s.checkSubregion(conn)...)
+		volumes, sourceErrs := s.checkSourceOmi(conn)
+		errs = packersdk.MultiErrorAppend(errs, sourceErrs...)
+		errs = packersdk.MultiErrorAppend(errs, s.checkSubnets(conn)...)
+		errs = packersdk.MultiErrorAppend(errs, s.checkQuotas(conn, ui, volumes)...)
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		state.Put("error", errs)
+		ui.Error(errs.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
 
+func (s *StepPreValidate) checkOmiName(conn *osc.APIClient) []error {
 	resp, _, err := conn.ImageApi.ReadImages(context.Background(), &osc.ReadImagesOpts{
 		ReadImagesRequest: optional.NewInterface(osc.ReadImagesRequest{
 			Filters: osc.FiltersImage{
@@ -39,28 +100,212 @@ func (s *StepPreValidate) Run(_ context.Context, state multistep.StateBag) multi
 			},
 		}),
 	})
-
 	if err != nil {
-		err := fmt.Errorf("Error querying OMI: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+		return []error{fmt.Errorf("Error querying OMI: %s", DecodeError(err))}
 	}
 
 	for _, omi := range resp.Images {
 		if omi.ImageName == s.DestOmiName {
-			images = append(images, omi)
+			return []error{fmt.Errorf("Error: name conflicts with an existing OMI: %s", s.DestOmiName)}
 		}
 	}
+	return nil
+}
 
-	if len(images) > 0 {
-		err := fmt.Errorf("Error: name conflicts with an existing OMI: %s", s.DestOmiName)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+func (s *StepPreValidate) checkVmType(conn *osc.APIClient) []error {
+	vmTypes := s.VmTypes
+	if len(vmTypes) == 0 {
+		vmTypes = []string{s.VmType}
 	}
 
-	return multistep.ActionContinue
+	var listed []string
+	for _, vmType := range vmTypes {
+		if !reTinaVmType.MatchString(vmType) {
+			listed = append(listed, vmType)
+		}
+	}
+	if len(listed) == 0 {
+		return nil
+	}
+
+	resp, _, err := conn.VmApi.ReadVmTypes(context.Background(), &osc.ReadVmTypesOpts{
+		ReadVmTypesRequest: optional.NewInterface(osc.ReadVmTypesRequest{
+			Filters: osc.FiltersVmType{
+				VmTypeNames: listed,
+			},
+		}),
+	})
+	if err != nil {
+		return []error{fmt.Errorf("Error querying VM types: %s", DecodeError(err))}
+	}
+
+	found := make(map[string]bool)
+	for _, vmType := range resp.VmTypes {
+		found[vmType.VmTypeName] = true
+	}
+	var errs []error
+	for _, vmType := range listed {
+		if !found[vmType] {
+			errs = append(errs, fmt.Errorf("The vm_type %s does not exist. Use a tinavX.cXrXpX type or one listed by ReadVmTypes", vmType))
+		}
+	}
+	return errs
+}
+
+func (s *StepPreValidate) checkSubregion(conn *osc.APIClient) []error {
+	subregions := s.SubregionNames
+	if len(subregions) == 0 && s.SubregionName != "" {
+		subregions = []string{s.SubregionName}
+	}
+	if len(subregions) == 0 {
+		return nil
+	}
+
+	resp, _, err := conn.SubregionApi.ReadSubregions(context.Background(), &osc.ReadSubregionsOpts{
+		ReadSubregionsRequest: optional.NewInterface(osc.ReadSubregionsRequest{}),
+	})
+	if err != nil {
+		return []error{fmt.Errorf("Error querying Subregions: %s", DecodeError(err))}
+	}
+
+	found := make(map[string]bool)
+	names := make([]string, 0, len(resp.Subregions))
+	for _, subregion := range resp.Subregions {
+		found[subregion.SubregionName] = true
+		names = append(names, subregion.SubregionName)
+	}
+	var errs []error
+	for _, subregion := range subregions {
+		if !found[subregion] {
+			errs = append(errs, fmt.Errorf("The subregion_name %s is not in the region, whose Subregions are: %s", subregion, strings.Join(names, ", ")))
+		}
+	}
+	return errs
+}
+
+// checkSourceOmi checks the root device type of the source OMI, and returns
+// the number of volumes of the source VM.
+func (s *StepPreValidate) checkSourceOmi(conn *osc.APIClient) (int, []error) {
+	params := osc.ReadImagesRequest{}
+	if s.SourceOmi != "" {
+		params.Filters.ImageIds = []string{s.SourceOmi}
+	}
+	image, err := s.SourceOmiFilter.GetFilteredImage(params, conn)
+	if err != nil {
+		return 0, []error{err}
+	}
+
+	var errs []error
+	if s.ExpectedRootDevice != "" && image.RootDeviceType != s.ExpectedRootDevice {
+		errs = append(errs, fmt.Errorf("The source OMI %s has an invalid root device type: expected '%s', got '%s'", image.ImageId, s.ExpectedRootDevice, image.RootDeviceType))
+	}
+
+	devices := make(map[string]bool)
+	for _, mapping := range image.BlockDeviceMappings {
+		devices[mapping.DeviceName] = mapping.Bsu.SnapshotId != "" || mapping.Bsu.VolumeSize != 0
+	}
+	for _, mapping := range s.BlockDevices.LaunchMappings {
+		devices[mapping.DeviceName] = !mapping.NoDevice && mapping.VirtualName == ""
+	}
+	volumes := 0
+	for _, volume := range devices {
+		if volume {
+			volumes++
+		}
+	}
+	return volumes, errs
+}
+
+// checkSubnets checks that the Subnets given by their ID have enough free
+// IPs for the NICs of the source VM. A Subnet of several subnet_ids only has
+// to exist, as the launch falls back on the next one when it is full.
+func (s *StepPreValidate) checkSubnets(conn *osc.APIClient) []error {
+	needed := make(map[string]int)
+	if len(s.SubnetIds) > 1 {
+		for _, id := range s.SubnetIds {
+			needed[id] = 0
+		}
+	} else if s.SubnetId != "" {
+		needed[s.SubnetId] += 1 + len(s.SecondaryPrivateIps) + s.SecondaryPrivateIpCount
+	}
+	for _, nic := range s.Nics {
+		needed[nic.SubnetId] += 1 + len(nic.SecondaryPrivateIps) + nic.SecondaryPrivateIpCount
+	}
+	if len(needed) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(needed))
+	for id := range needed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	resp, _, err := conn.SubnetApi.ReadSubnets(context.Background(), &osc.ReadSubnetsOpts{
+		ReadSubnetsRequest: optional.NewInterface(osc.ReadSubnetsRequest{
+			Filters: osc.FiltersSubnet{
+				SubnetIds: ids,
+			},
+		}),
+	})
+	if err != nil {
+		return []error{fmt.Errorf("Error querying Subnets: %s", DecodeError(err))}
+	}
+
+	found := make(map[string]osc.Subnet)
+	for _, subnet := range resp.Subnets {
+		found[subnet.SubnetId] = subnet
+	}
+
+	var errs []error
+	for _, id := range ids {
+		subnet, ok := found[id]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("The Subnet %s does not exist", id))
+		case int(subnet.AvailableIpsCount) < needed[id]:
+			errs = append(errs, fmt.Errorf("The Subnet %s has %d free IPs, but the source VM needs %d", id, subnet.AvailableIpsCount, needed[id]))
+		case s.SubregionName != "" && subnet.SubregionName != s.SubregionName:
+			errs = append(errs, fmt.Errorf("The Subnet %s is in the Subregion %s, not in %s", id, subnet.SubregionName, s.SubregionName))
+		}
+	}
+	return errs
+}
+
+// checkQuotas checks that the account can hold the source VM, its volumes
+// and its temporary public IP. Quotas that cannot be read are only reported
+// as a warning, as some accounts are not allowed to read them.
+func (s *StepPreValidate) checkQuotas(conn *osc.APIClient, ui packersdk.Ui, volumes int) []error {
+	needed := map[string]int{vmQuota: 1, volumeQuota: volumes}
+	if s.AssociatePublicIpAddress && s.PublicIpId == "" && s.PublicIpFilter.Empty() {
+		needed[publicIpQuota] = 1
+	}
+
+	resp, _, err := conn.QuotaApi.ReadQuotas(context.Background(), &osc.ReadQuotasOpts{
+		ReadQuotasRequest: optional.NewInterface(osc.ReadQuotasRequest{
+			Filters: osc.FiltersQuota{
+				QuotaNames: []string{vmQuota, volumeQuota, publicIpQuota},
+			},
+		}),
+	})
+	if err != nil {
+		ui.Message(fmt.Sprintf("Warning: the quotas cannot be checked: %s", DecodeError(err)))
+		return nil
+	}
+
+	var errs []error
+	for _, quotaType := range resp.QuotaTypes {
+		if quotaType.QuotaType != "global" {
+			continue
+		}
+		for _, quota := range quotaType.Quotas {
+			n := needed[quota.Name]
+			log.Printf("[DEBUG] Quota %s: %d/%d used, %d needed", quota.Name, quota.UsedValue, quota.MaxValue, n)
+			if n > 0 && int(quota.UsedValue)+n > int(quota.MaxValue) {
+				errs = append(errs, fmt.Errorf("The quota %s (%s) is exhausted: %d of %d used, %d more needed", quota.Name, quota.ShortDescription, quota.UsedValue, quota.MaxValue, n))
+			}
+		}
+	}
+	return errs
 }
 
 func (s *StepPreValidate) Cleanup(multistep.StateBag) {}
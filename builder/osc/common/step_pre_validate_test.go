@@ -0,0 +1,102 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+func TestStepPreValidate_sourceVm(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+	n := server.AddNet("eu-west-2", "10.0.0.0/16")
+	subnet := server.AddSubnet("eu-west-2", n.NetId, "10.0.0.0/29", "eu-west-2a")
+
+	step := &StepPreValidate{
+		DestOmiName:              "packer-test",
+		VmType:                   "tinav5.c2r4p2",
+		SubregionName:            "eu-west-2a",
+		SourceOmi:                source.ImageId,
+		ExpectedRootDevice:       RunSourceVmBSUExpectedRootDevice,
+		SubnetId:                 subnet.SubnetId,
+		SecondaryPrivateIpCount:  1,
+		AssociatePublicIpAddress: true,
+	}
+	state := testServerState(t, server)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+
+	// The quotas are only a warning when they cannot be read.
+	server.Fail("ReadQuotas", http.StatusForbidden, "AccessDenied")
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+}
+
+func TestStepPreValidate_reportsEveryProblem(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.AddImage("eu-west-2", osc.Image{ImageName: "packer-test"})
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source", RootDeviceType: "ebs"})
+	n := server.AddNet("eu-west-2", "10.0.0.0/16")
+	subnet := server.AddSubnet("eu-west-2", n.NetId, "10.0.0.0/29", "eu-west-2a")
+	server.SetQuota("vm_limit", 0)
+
+	step := &StepPreValidate{
+		DestOmiName:             "packer-test",
+		VmType:                  "x9.huge",
+		SubregionName:           "eu-west-2z",
+		SourceOmi:               source.ImageId,
+		ExpectedRootDevice:      RunSourceVmBSUExpectedRootDevice,
+		SubnetId:                subnet.SubnetId,
+		SecondaryPrivateIpCount: 3,
+	}
+	state := testServerState(t, server)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("should halt, got %v", action)
+	}
+
+	// The name clash, the VM type, the Subregion, the root device, the free
+	// IPs and the VM quota.
+	errs, ok := state.Get("error").(*packersdk.MultiError)
+	if !ok || len(errs.Errors) != 6 {
+		t.Fatalf("should report every problem, got %v", state.Get("error"))
+	}
+	if calls := server.Calls("CreateVms"); calls != 0 {
+		t.Fatalf("should not launch anything, got %d calls", calls)
+	}
+}
+
+func TestStepPreValidate_quotas(t *testing.T) {
+	for _, quota := range []string{vmQuota, volumeQuota, publicIpQuota} {
+		t.Run(quota, func(t *testing.T) {
+			server := oapitest.NewServer()
+			defer server.Close()
+			source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+			server.SetQuota(quota, 0)
+
+			step := &StepPreValidate{
+				VmType:                   "tinav5.c2r4p2",
+				SourceOmi:                source.ImageId,
+				AssociatePublicIpAddress: true,
+			}
+			state := testServerState(t, server)
+			if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+				t.Fatalf("should halt, got %v", action)
+			}
+
+			errs, ok := state.Get("error").(*packersdk.MultiError)
+			if !ok || len(errs.Errors) != 1 || !strings.Contains(errs.Errors[0].Error(), "The quota "+quota+" ") {
+				t.Fatalf("should report the exhausted %s quota, got %v", quota, state.Get("error"))
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package common
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/antihax/optional"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
@@ -11,13 +12,19 @@ import (
 	"github.com/outscale/osc-sdk-go/osc"
 )
 
+// StepPublicIp provides the public IP linked to the VM by StepRunSourceVm:
+// either a temporary one, released on cleanup, or an existing one given by
+// PublicIpId or PublicIpFilter, which is only unlinked on cleanup.
 type StepPublicIp struct {
 	AssociatePublicIpAddress bool
+	PublicIpId               string
+	PublicIpFilter           PublicIpFilterOptions
 	Comm                     *communicator.Config
 	publicIpId               string
 	Debug                    bool
 
 	doCleanup bool
+	reused    bool
 }
 
 func (s *StepPublicIp) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -26,6 +33,26 @@ func (s *StepPublicIp) Run(_ context.Context, state multistep.StateBag) multiste
 		conn = state.Get("osc").(*osc.APIClient)
 	)
 
+	if s.PublicIpId != "" || !s.PublicIpFilter.Empty() {
+		publicIp, err := s.existingPublicIp(conn)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		ui.Say(fmt.Sprintf("Using PublicIp %s (%s) for instance", publicIp.PublicIpId, publicIp.PublicIp))
+
+		// The Public Ip is kept, but it must be unlinked from the VM.
+		s.doCleanup = true
+		s.reused = true
+
+		s.publicIpId = publicIp.PublicIpId
+		state.Put("publicip_id", publicIp.PublicIpId)
+
+		return multistep.ActionContinue
+	}
+
 	if !s.AssociatePublicIpAddress {
 
 		// In this case, we are in the public Cloud, so we'll
@@ -40,7 +67,7 @@ func (s *StepPublicIp) Run(_ context.Context, state multistep.StateBag) multiste
 	})
 
 	if err != nil {
-		state.Put("error", fmt.Errorf("Error creating temporary PublicIp: %s", err))
+		state.Put("error", fmt.Errorf("Error creating temporary PublicIp: %s", DecodeError(err)))
 		return multistep.ActionHalt
 	}
 
@@ -54,6 +81,44 @@ func (s *StepPublicIp) Run(_ context.Context, state multistep.StateBag) multiste
 	return multistep.ActionContinue
 }
 
+// existingPublicIp returns the public IP given by PublicIpId or
+// PublicIpFilter, which must not be linked to anything.
+func (s *StepPublicIp) existingPublicIp(conn *osc.APIClient) (*osc.PublicIp, error) {
+	var filters osc.FiltersPublicIp
+	if s.PublicIpId != "" {
+		filters.PublicIpIds = []string{s.PublicIpId}
+	} else {
+		filters = buildOscPublicIpFilters(s.PublicIpFilter.Filters)
+	}
+	log.Printf("Using PublicIp Filters %v", filters)
+
+	resp, _, err := conn.PublicIpApi.ReadPublicIps(context.Background(), &osc.ReadPublicIpsOpts{
+		ReadPublicIpsRequest: optional.NewInterface(osc.ReadPublicIpsRequest{
+			Filters: filters,
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error querying PublicIps: %s", DecodeError(err))
+	}
+	if len(resp.PublicIps) != 1 {
+		return nil, fmt.Errorf("Exactly one PublicIp should match the filter, but %d PublicIps were found matching filters: %v", len(resp.PublicIps), filters)
+	}
+
+	publicIp := resp.PublicIps[0]
+	if publicIp.LinkPublicIpId != "" {
+		linkedTo := publicIp.VmId
+		if linkedTo == "" {
+			linkedTo = publicIp.NicId
+		}
+		if linkedTo == "" {
+			linkedTo = "a NAT service"
+		}
+		return nil, fmt.Errorf("The PublicIp %s (%s) is already linked to %s", publicIp.PublicIpId, publicIp.PublicIp, linkedTo)
+	}
+
+	return &publicIp, nil
+}
+
 func (s *StepPublicIp) Cleanup(state multistep.StateBag) {
 	if !s.doCleanup {
 		return
@@ -64,6 +129,11 @@ func (s *StepPublicIp) Cleanup(state multistep.StateBag) {
 		ui   = state.Get("ui").(packersdk.Ui)
 	)
 
+	if s.reused {
+		s.unlink(conn, ui, state)
+		return
+	}
+
 	// Remove the Public IP
 	ui.Say("Deleting temporary PublicIp...")
 	_, _, err := conn.PublicIpApi.DeletePublicIp(context.Background(), &osc.DeletePublicIpOpts{
@@ -76,3 +146,38 @@ func (s *StepPublicIp) Cleanup(state multistep.StateBag) {
 		ui.Error(fmt.Sprintf("Error cleaning up PublicIp. Please delete the PublicIp manually: %s", s.publicIpId))
 	}
 }
+
+// unlink unlinks the reused public IP from the VM of the build, if the VM
+// is still there to hold it.
+func (s *StepPublicIp) unlink(conn *osc.APIClient, ui packersdk.Ui, state multistep.StateBag) {
+	vmId, ok := state.Get("instance_id").(string)
+	if !ok {
+		return
+	}
+
+	resp, _, err := conn.PublicIpApi.ReadPublicIps(context.Background(), &osc.ReadPublicIpsOpts{
+		ReadPublicIpsRequest: optional.NewInterface(osc.ReadPublicIpsRequest{
+			Filters: osc.FiltersPublicIp{
+				PublicIpIds: []string{s.publicIpId},
+			},
+		}),
+	})
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error reading PublicIp %s: %s", s.publicIpId, DecodeError(err)))
+		return
+	}
+	if len(resp.PublicIps) == 0 || resp.PublicIps[0].VmId != vmId {
+		return
+	}
+
+	ui.Say(fmt.Sprintf("Unlinking PublicIp %s...", s.publicIpId))
+	_, _, err = conn.PublicIpApi.UnlinkPublicIp(context.Background(), &osc.UnlinkPublicIpOpts{
+		UnlinkPublicIpRequest: optional.NewInterface(osc.UnlinkPublicIpRequest{
+			LinkPublicIpId: resp.PublicIps[0].LinkPublicIpId,
+		}),
+	})
+
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error cleaning up PublicIp. Please unlink the PublicIp manually: %s", s.publicIpId))
+	}
+}
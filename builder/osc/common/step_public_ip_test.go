@@ -0,0 +1,92 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antihax/optional"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+// testPublicIpVm returns a state for StepPublicIp, with a running VM of the
+// fake server.
+func testPublicIpVm(t *testing.T, server *oapitest.Server) (*osc.APIClient, multistep.StateBag, string) {
+	server.Transitions = 0
+	image := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	state := testServerState(t, server)
+	conn := state.Get("osc").(*osc.APIClient)
+	resp, _, err := conn.VmApi.CreateVms(context.Background(), &osc.CreateVmsOpts{
+		CreateVmsRequest: optional.NewInterface(osc.CreateVmsRequest{ImageId: image.ImageId}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	return conn, state, resp.Vms[0].VmId
+}
+
+func TestStepPublicIp_reuse(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	conn, state, vmId := testPublicIpVm(t, server)
+	ip := server.AddPublicIp("eu-west-2", osc.ResourceTag{Key: "packer", Value: "allowlisted"})
+	server.AddPublicIp("eu-west-2")
+
+	step := &StepPublicIp{
+		PublicIpFilter: PublicIpFilterOptions{
+			NameValueFilter: config.NameValueFilter{Filters: map[string]string{"tag-keys": "packer"}},
+		},
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+	if id := state.Get("publicip_id"); id != ip.PublicIpId {
+		t.Fatalf("should use the filtered public IP, got %v", id)
+	}
+
+	// StepRunSourceVm links the public IP to the VM.
+	_, _, err := conn.PublicIpApi.LinkPublicIp(context.Background(), &osc.LinkPublicIpOpts{
+		LinkPublicIpRequest: optional.NewInterface(osc.LinkPublicIpRequest{PublicIpId: ip.PublicIpId, VmId: vmId}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	state.Put("instance_id", vmId)
+
+	step.Cleanup(state)
+	ips := server.PublicIps()
+	if len(ips) != 2 || ips[0].PublicIpId != ip.PublicIpId {
+		t.Fatalf("the public IP should not be released, got %#v", ips)
+	}
+	if ips[0].VmId != "" || ips[0].LinkPublicIpId != "" {
+		t.Fatalf("the public IP should be unlinked, got %#v", ips[0])
+	}
+}
+
+func TestStepPublicIp_alreadyLinked(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	conn, state, vmId := testPublicIpVm(t, server)
+	ip := server.AddPublicIp("eu-west-2")
+
+	_, _, err := conn.PublicIpApi.LinkPublicIp(context.Background(), &osc.LinkPublicIpOpts{
+		LinkPublicIpRequest: optional.NewInterface(osc.LinkPublicIpRequest{PublicIpId: ip.PublicIpId, VmId: vmId}),
+	})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	step := &StepPublicIp{PublicIpId: ip.PublicIpId}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("should halt when the public IP is linked elsewhere, got %v", action)
+	}
+
+	step.Cleanup(state)
+	if ips := server.PublicIps(); len(ips) != 1 || ips[0].VmId != vmId {
+		t.Fatalf("the public IP should be left untouched, got %#v", ips)
+	}
+}
@@ -3,18 +3,20 @@ package common
 import (
 	"context"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"reflect"
 
 	"github.com/antihax/optional"
-	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/outscale/osc-sdk-go/osc"
 
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/outscale/packer-plugin-outscale/builder/osc/common/retry"
 )
@@ -34,6 +36,7 @@ type StepRunSourceVm struct {
 	IamVmProfile                string
 	VmInitiatedShutdownBehavior string
 	VmType                      string
+	VmTypes                     []string
 	IsRestricted                bool
 	SourceOMI                   string
 	Tags                        TagMap
@@ -41,6 +44,14 @@ type StepRunSourceVm struct {
 	UserDataFile                string
 	VolumeTags                  TagMap
 	RawRegion                   string
+	PollingConfig               *PollingConfig
+	PrivateIp                   string
+	SecondaryPrivateIps         []string
+	SecondaryPrivateIpCount     int
+	Nics                        []NicConfig
+	SubregionNames              []string
+	SubnetIds                   []string
+	GeneratedData               *packerbuilderdata.GeneratedData
 
 	vmId string
 }
@@ -108,14 +119,11 @@ func (s *StepRunSourceVm) Run(ctx context.Context, state multistep.StateBag) mul
 		return multistep.ActionHalt
 	}
 
-	subregion := state.Get("subregion_name").(string)
 	runOpts := osc.CreateVmsRequest{
 		ImageId:             s.SourceOMI,
-		VmType:              s.VmType,
 		UserData:            userData,
 		MaxVmsCount:         1,
 		MinVmsCount:         1,
-		Placement:           osc.Placement{SubregionName: subregion},
 		BsuOptimized:        s.BsuOptimized,
 		BlockDeviceMappings: s.BlockDevices.BuildOSCLaunchDevices(),
 	}
@@ -126,23 +134,68 @@ func (s *StepRunSourceVm) Run(ctx context.Context, state multistep.StateBag) mul
 
 	subnetID := state.Get("subnet_id").(string)
 
-	runOpts.SubnetId = subnetID
-	runOpts.SecurityGroupIds = securityGroupIds
+	if s.PrivateIp != "" || len(s.SecondaryPrivateIps) > 0 || s.SecondaryPrivateIpCount > 0 || len(s.Nics) > 0 {
+		// The subnet and the security groups of the VM are then the ones
+		// of its first NIC.
+		nics, err := s.buildNics(oscconn, subnetID, securityGroupIds)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		runOpts.Nics = nics
+	} else {
+		runOpts.SubnetId = subnetID
+		runOpts.SecurityGroupIds = securityGroupIds
+	}
 
 	if s.ExpectedRootDevice == "bsu" {
 		runOpts.VmInitiatedShutdownBehavior = s.VmInitiatedShutdownBehavior
 	}
 
-	runResp, _, err := oscconn.VmApi.CreateVms(context.Background(), &osc.CreateVmsOpts{
-		CreateVmsRequest: optional.NewInterface(runOpts),
-	})
-
+	placements, err := s.placements(oscconn, state)
 	if err != nil {
-		err := fmt.Errorf("Error launching source vm: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
+	vmTypes := s.VmTypes
+	if len(vmTypes) == 0 {
+		vmTypes = []string{s.VmType}
+	}
+
+	// Each VM type is tried in every placement before falling back on the
+	// next one, as long as the launch fails for lack of capacity.
+	var runResp osc.CreateVmsResponse
+	attempts := len(vmTypes) * len(placements)
+	for i := 0; i < attempts; i++ {
+		vmType, placement := vmTypes[i/len(placements)], placements[i%len(placements)]
+		runOpts.VmType = vmType
+		runOpts.Placement = osc.Placement{SubregionName: placement.subregion}
+		if len(runOpts.Nics) == 0 {
+			runOpts.SubnetId = placement.subnetId
+		}
+
+		runResp, _, err = oscconn.VmApi.CreateVms(context.Background(), &osc.CreateVmsOpts{
+			CreateVmsRequest: optional.NewInterface(runOpts),
+		})
+		if err == nil {
+			if attempts > 1 {
+				ui.Message(fmt.Sprintf("Launched a %s vm in %s", vmType, placement))
+			}
+			state.Put("subregion_name", runResp.Vms[0].Placement.SubregionName)
+			state.Put("subnet_id", runResp.Vms[0].SubnetId)
+			break
+		}
+
+		if i == attempts-1 || !(IsCapacityError(err) || IsUnsupportedError(err)) {
+			err := fmt.Errorf("Error launching source vm: %s", DecodeError(err))
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Message(fmt.Sprintf("Cannot launch a %s vm in %s, trying the next candidate: %s", vmType, placement, DecodeError(err)))
+	}
 	vmId = runResp.Vms[0].VmId
 	volumeId := runResp.Vms[0].BlockDeviceMappings[0].Bsu.VolumeId
 
@@ -157,7 +210,7 @@ func (s *StepRunSourceVm) Run(ctx context.Context, state multistep.StateBag) mul
 			VmIds: []string{vmId},
 		},
 	}
-	if err := waitUntilForOscVmRunning(oscconn, vmId); err != nil {
+	if err := s.PollingConfig.waitUntilForOscVmRunning(ctx, oscconn, vmId); err != nil {
 		err := fmt.Errorf("Error waiting for vm (%s) to become ready: %s", vmId, err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -167,7 +220,7 @@ func (s *StepRunSourceVm) Run(ctx context.Context, state multistep.StateBag) mul
 	//Set Vm tags and vollume tags
 	if len(oscTags) > 0 {
 		if err := CreateOSCTags(oscconn, s.vmId, ui, oscTags); err != nil {
-			err := fmt.Errorf("Error creating tags for vm (%s): %s", s.vmId, err)
+			err := fmt.Errorf("Error creating tags for vm (%s): %s", s.vmId, DecodeError(err))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
@@ -176,7 +229,7 @@ func (s *StepRunSourceVm) Run(ctx context.Context, state multistep.StateBag) mul
 
 	if len(volTags) > 0 {
 		if err := CreateOSCTags(oscconn, volumeId, ui, volTags); err != nil {
-			err := fmt.Errorf("Error creating tags for volume (%s): %s", volumeId, err)
+			err := fmt.Errorf("Error creating tags for volume (%s): %s", volumeId, DecodeError(err))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
@@ -184,12 +237,24 @@ func (s *StepRunSourceVm) Run(ctx context.Context, state multistep.StateBag) mul
 	}
 
 	if publicip_id, ok := state.Get("publicip_id").(string); ok {
-		ui.Say(fmt.Sprintf("Linking temporary PublicIp %s to instance %s", publicip_id, vmId))
+		ui.Say(fmt.Sprintf("Linking PublicIp %s to instance %s", publicip_id, vmId))
+		linkRequest := osc.LinkPublicIpRequest{PublicIpId: publicip_id, VmId: vmId}
+		// In a Net, the VmId can only be used when the VM has a single NIC:
+		// the public IP is linked to its first NIC instead.
+		if len(s.Nics) > 0 {
+			nicId, err := primaryNicId(oscconn, request)
+			if err != nil {
+				state.Put("error", fmt.Errorf("Error reading the NICs of the VM: %s", DecodeError(err)))
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+			linkRequest = osc.LinkPublicIpRequest{PublicIpId: publicip_id, NicId: nicId}
+		}
 		_, _, err := oscconn.PublicIpApi.LinkPublicIp(context.Background(), &osc.LinkPublicIpOpts{
-			LinkPublicIpRequest: optional.NewInterface(osc.LinkPublicIpRequest{PublicIpId: publicip_id, VmId: vmId}),
+			LinkPublicIpRequest: optional.NewInterface(linkRequest),
 		})
 		if err != nil {
-			state.Put("error", fmt.Errorf("Error linking PublicIp to VM: %s", err))
+			state.Put("error", fmt.Errorf("Error linking PublicIp to VM: %s", DecodeError(err)))
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
@@ -227,6 +292,9 @@ func (s *StepRunSourceVm) Run(ctx context.Context, state multistep.StateBag) mul
 	// instance_id is the generic term used so that users can have access to the
 	// instance id inside of the provisioners, used in step_provision.
 	state.Put("instance_id", vmId)
+	if s.GeneratedData != nil {
+		setVmGeneratedData(s.GeneratedData, vm)
+	}
 
 	// If we're in a region that doesn't support tagging on vm creation,
 	// do that now.
@@ -244,13 +312,10 @@ func (s *StepRunSourceVm) Run(ctx context.Context, state multistep.StateBag) mul
 			if err == nil {
 				return true, nil
 			}
-			//TODO: improve error
-			if awsErr, ok := err.(awserr.Error); ok {
-				if awsErr.Code() == "InvalidVmID.NotFound" {
-					return false, nil
-				}
+			if IsNotFoundError(err) || IsThrottledError(err) {
+				return false, nil
 			}
-			return true, err
+			return true, DecodeError(err)
 		})
 
 		if err != nil {
@@ -289,7 +354,7 @@ func (s *StepRunSourceVm) Run(ctx context.Context, state multistep.StateBag) mul
 			})
 
 			if err != nil {
-				err := fmt.Errorf("Error tagging source BSU Volumes on %s: %s", vm.VmId, err)
+				err := fmt.Errorf("Error tagging source BSU Volumes on %s: %s", vm.VmId, DecodeError(err))
 				state.Put("error", err)
 				ui.Error(err.Error())
 				return multistep.ActionHalt
@@ -300,6 +365,197 @@ func (s *StepRunSourceVm) Run(ctx context.Context, state multistep.StateBag) mul
 	return multistep.ActionContinue
 }
 
+// vmPlacement is a candidate Subregion, and Subnet if the VM is in a Net, of
+// the source VM.
+type vmPlacement struct {
+	subregion string
+	subnetId  string
+}
+
+func (p vmPlacement) String() string {
+	if p.subnetId == "" {
+		return p.subregion
+	}
+	return fmt.Sprintf("%s (%s)", p.subregion, p.subnetId)
+}
+
+// placements returns the candidate placements of the source VM, in order:
+// the SubnetIds, which must be in the Net of the build, or the
+// SubregionNames, or else the Subnet and Subregion found by
+// StepNetworkInfo.
+func (s *StepRunSourceVm) placements(conn *osc.APIClient, state multistep.StateBag) ([]vmPlacement, error) {
+	subregion := state.Get("subregion_name").(string)
+	subnetId := state.Get("subnet_id").(string)
+
+	if len(s.SubnetIds) > 1 {
+		resp, _, err := conn.SubnetApi.ReadSubnets(context.Background(), &osc.ReadSubnetsOpts{
+			ReadSubnetsRequest: optional.NewInterface(osc.ReadSubnetsRequest{
+				Filters: osc.FiltersSubnet{SubnetIds: s.SubnetIds},
+			}),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the Subnets of the VM: %s", DecodeError(err))
+		}
+		subnets := make(map[string]osc.Subnet)
+		for _, subnet := range resp.Subnets {
+			subnets[subnet.SubnetId] = subnet
+		}
+
+		netId, _ := state.Get("net_id").(string)
+		placements := make([]vmPlacement, 0, len(s.SubnetIds))
+		for _, id := range s.SubnetIds {
+			subnet, ok := subnets[id]
+			if !ok {
+				return nil, fmt.Errorf("Error reading the Subnets of the VM: %s not found", id)
+			}
+			if subnet.NetId != netId {
+				return nil, fmt.Errorf("The Subnet %s is not in the Net %s of the first Subnet of subnet_ids", id, netId)
+			}
+			placements = append(placements, vmPlacement{subregion: subnet.SubregionName, subnetId: id})
+		}
+		return placements, nil
+	}
+
+	if len(s.SubregionNames) > 1 {
+		placements := make([]vmPlacement, 0, len(s.SubregionNames))
+		for _, name := range s.SubregionNames {
+			placements = append(placements, vmPlacement{subregion: name, subnetId: subnetId})
+		}
+		return placements, nil
+	}
+
+	return []vmPlacement{{subregion: subregion, subnetId: subnetId}}, nil
+}
+
+// buildNics returns the NICs of the VM, checking their private IPs against
+// the IP ranges of their Subnets.
+func (s *StepRunSourceVm) buildNics(conn *osc.APIClient, subnetID string, securityGroupIds []string) ([]osc.NicForVmCreation, error) {
+	subnetIDs := []string{subnetID}
+	for _, nic := range s.Nics {
+		subnetIDs = append(subnetIDs, nic.SubnetId)
+	}
+	resp, _, err := conn.SubnetApi.ReadSubnets(context.Background(), &osc.ReadSubnetsOpts{
+		ReadSubnetsRequest: optional.NewInterface(osc.ReadSubnetsRequest{
+			Filters: osc.FiltersSubnet{
+				SubnetIds: subnetIDs,
+			},
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the Subnets of the NICs: %s", DecodeError(err))
+	}
+	subnets := make(map[string]osc.Subnet, len(resp.Subnets))
+	for _, subnet := range resp.Subnets {
+		subnets[subnet.SubnetId] = subnet
+	}
+
+	primary, ok := subnets[subnetID]
+	if !ok {
+		return nil, fmt.Errorf("Error reading the Subnet %s of the VM: not found", subnetID)
+	}
+	nic, err := nicForVmCreation(0, primary, s.PrivateIp, s.SecondaryPrivateIps, s.SecondaryPrivateIpCount, securityGroupIds, "")
+	if err != nil {
+		return nil, err
+	}
+	nics := []osc.NicForVmCreation{nic}
+
+	for i, config := range s.Nics {
+		subnet, ok := subnets[config.SubnetId]
+		if !ok {
+			return nil, fmt.Errorf("nics[%d]: the Subnet %s is not found", i, config.SubnetId)
+		}
+		if subnet.NetId != primary.NetId || subnet.SubregionName != primary.SubregionName {
+			return nil, fmt.Errorf("nics[%d]: the Subnet %s must be in the Net %s and the Subregion %s of the VM", i, subnet.SubnetId, primary.NetId, primary.SubregionName)
+		}
+		groups := config.SecurityGroupIds
+		if len(groups) == 0 {
+			groups = securityGroupIds
+		}
+		nic, err := nicForVmCreation(int32(i+1), subnet, config.PrivateIp, config.SecondaryPrivateIps, config.SecondaryPrivateIpCount, groups, config.Description)
+		if err != nil {
+			return nil, fmt.Errorf("nics[%d]: %s", i, err)
+		}
+		nics = append(nics, nic)
+	}
+
+	// A private IP can only be given once in a Subnet.
+	seen := make(map[string]bool)
+	for _, nic := range nics {
+		for _, ip := range nic.PrivateIps {
+			if seen[nic.SubnetId+" "+ip.PrivateIp] {
+				return nil, fmt.Errorf("the private IP %s is given more than once in the Subnet %s", ip.PrivateIp, nic.SubnetId)
+			}
+			seen[nic.SubnetId+" "+ip.PrivateIp] = true
+		}
+	}
+
+	return nics, nil
+}
+
+func nicForVmCreation(deviceNumber int32, subnet osc.Subnet, primary string, secondary []string, secondaryCount int, securityGroupIds []string, description string) (osc.NicForVmCreation, error) {
+	nic := osc.NicForVmCreation{
+		DeleteOnVmDeletion:      true,
+		Description:             description,
+		DeviceNumber:            deviceNumber,
+		SecondaryPrivateIpCount: int32(secondaryCount),
+		SecurityGroupIds:        securityGroupIds,
+		SubnetId:                subnet.SubnetId,
+	}
+
+	if primary != "" {
+		if err := checkSubnetIp(subnet, primary); err != nil {
+			return nic, err
+		}
+		nic.PrivateIps = append(nic.PrivateIps, osc.PrivateIpLight{IsPrimary: true, PrivateIp: primary})
+	}
+	for _, ip := range secondary {
+		if err := checkSubnetIp(subnet, ip); err != nil {
+			return nic, err
+		}
+		nic.PrivateIps = append(nic.PrivateIps, osc.PrivateIpLight{PrivateIp: ip})
+	}
+
+	return nic, nil
+}
+
+// checkSubnetIp checks that the private IP is a host address of the Subnet,
+// whose first four addresses and last one are reserved.
+func checkSubnetIp(subnet osc.Subnet, ip string) error {
+	_, ipRange, err := net.ParseCIDR(subnet.IpRange)
+	if err != nil {
+		return fmt.Errorf("Error parsing the IP range of the Subnet %s: %s", subnet.SubnetId, err)
+	}
+	address := net.ParseIP(ip).To4()
+	if address == nil || !ipRange.Contains(address) {
+		return fmt.Errorf("the private IP %s is not in the IP range %s of the Subnet %s", ip, subnet.IpRange, subnet.SubnetId)
+	}
+
+	ones, bits := ipRange.Mask.Size()
+	n := binary.BigEndian.Uint32(address) - binary.BigEndian.Uint32(ipRange.IP.To4())
+	if n < 4 || n == 1<<(bits-ones)-1 {
+		return fmt.Errorf("the private IP %s is reserved in the Subnet %s", ip, subnet.SubnetId)
+	}
+	return nil
+}
+
+// primaryNicId returns the ID of the NIC of device number 0 of the VM.
+func primaryNicId(conn *osc.APIClient, request osc.ReadVmsRequest) (string, error) {
+	resp, _, err := conn.VmApi.ReadVms(context.Background(), &osc.ReadVmsOpts{
+		ReadVmsRequest: optional.NewInterface(request),
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, vm := range resp.Vms {
+		for _, nic := range vm.Nics {
+			if nic.LinkNic.DeviceNumber == 0 {
+				return nic.NicId, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("the VM has no NIC of device number 0")
+}
+
 func (s *StepRunSourceVm) Cleanup(state multistep.StateBag) {
 	oscconn := state.Get("osc").(*osc.APIClient)
 	ui := state.Get("ui").(packersdk.Ui)
@@ -310,11 +566,11 @@ func (s *StepRunSourceVm) Cleanup(state multistep.StateBag) {
 		if _, _, err := oscconn.VmApi.DeleteVms(context.Background(), &osc.DeleteVmsOpts{
 			DeleteVmsRequest: optional.NewInterface(osc.DeleteVmsRequest{VmIds: []string{s.vmId}}),
 		}); err != nil {
-			ui.Error(fmt.Sprintf("Error terminating vm, may still be around: %s", err))
+			ui.Error(fmt.Sprintf("Error terminating vm, may still be around: %s", DecodeError(err)))
 			return
 		}
 
-		if err := waitUntilOscVmDeleted(oscconn, s.vmId); err != nil {
+		if err := s.PollingConfig.waitUntilOscVmDeleted(context.Background(), oscconn, s.vmId); err != nil {
 			ui.Error(err.Error())
 		}
 	}
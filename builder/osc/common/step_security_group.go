@@ -3,27 +3,45 @@ package common
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/antihax/optional"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/hashicorp/packer-plugin-sdk/uuid"
 	"github.com/outscale/osc-sdk-go/osc"
 )
 
+// DefaultPublicIpURL is the service answering the public IP of the Packer
+// host, for temporary_security_group_source_public_ip, unless
+// temporary_security_group_source_public_ip_url is set.
+const DefaultPublicIpURL = "https://api.ipify.org"
+
 type StepSecurityGroup struct {
-	CommConfig            *communicator.Config
-	SecurityGroupFilter   SecurityGroupFilterOptions
-	SecurityGroupIds      []string
-	TemporarySGSourceCidr string
+	CommConfig                *communicator.Config
+	SecurityGroupFilter       SecurityGroupFilterOptions
+	SecurityGroupIds          []string
+	TemporarySGSourceCidrs    []string
+	TemporarySGSourcePublicIp bool
+	PublicIpURL               string
+	TemporarySGRules          []SecurityGroupRule
+	TemporarySGOutboundRules  []SecurityGroupRule
+	Tags                      TagMap
+	Ctx                       interpolate.Context
+	RawRegion                 string
 
 	createdGroupId string
 }
 
-func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+func (s *StepSecurityGroup) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	var (
 		ui    = state.Get("ui").(packersdk.Ui)
 		conn  = state.Get("osc").(*osc.APIClient)
@@ -40,7 +58,7 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 		})
 
 		if err != nil || len(resp.SecurityGroups) == 0 {
-			err := fmt.Errorf("Couldn't find specified security group: %s", err)
+			err := fmt.Errorf("Couldn't find specified security group: %s", DecodeError(err))
 			log.Printf("[DEBUG] %s", err.Error())
 			state.Put("error", err)
 
@@ -54,18 +72,8 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 	}
 
 	if !s.SecurityGroupFilter.Empty() {
-		filterReq := buildSecurityGroupFilters(s.SecurityGroupFilter.Filters)
-
-		log.Printf("Using SecurityGroup Filters %v", filterReq)
-
-		resp, _, err := conn.SecurityGroupApi.ReadSecurityGroups(context.Background(), &osc.ReadSecurityGroupsOpts{
-			ReadSecurityGroupsRequest: optional.NewInterface(osc.ReadSecurityGroupsRequest{
-				Filters: filterReq,
-			}),
-		})
-
-		if err != nil || len(resp.SecurityGroups) == 0 {
-			err := fmt.Errorf("Couldn't find security groups for filter: %s", err)
+		securityGroups, err := s.SecurityGroupFilter.GetFilteredSecurityGroups(conn)
+		if err != nil {
 			log.Printf("[DEBUG] %s", err.Error())
 			state.Put("error", err)
 
@@ -73,7 +81,7 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 		}
 
 		securityGroupIds := []string{}
-		for _, sg := range resp.SecurityGroups {
+		for _, sg := range securityGroups {
 			securityGroupIds = append(securityGroupIds, sg.SecurityGroupId)
 		}
 
@@ -106,6 +114,32 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 	// Set the group ID so we can delete it later
 	s.createdGroupId = resp.SecurityGroup.SecurityGroupId
 
+	if s.Tags.IsSet() {
+		tags, err := s.Tags.OSCTags(s.Ctx, s.RawRegion, state)
+		if err == nil {
+			err = CreateOSCTags(conn, s.createdGroupId, ui, tags)
+		}
+		if err != nil {
+			err := fmt.Errorf("Error tagging temporary security group: %s", DecodeError(err))
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	sourceCidrs := s.TemporarySGSourceCidrs
+	if s.TemporarySGSourcePublicIp {
+		publicIp, err := hostPublicIp(ctx, s.PublicIpURL)
+		if err != nil {
+			err := fmt.Errorf("Error detecting the public IP of the Packer host: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Message(fmt.Sprintf("Detected the public IP of the Packer host: %s", publicIp))
+		sourceCidrs = append(append([]string(nil), sourceCidrs...), publicIp+"/32")
+	}
+
 	port := s.CommConfig.Port()
 	if port == 0 {
 		if s.CommConfig.Type != "none" {
@@ -115,38 +149,146 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 	}
 
 	// Authorize the SSH access for the security group
-	createSGRReq := osc.CreateSecurityGroupRuleRequest{
-		SecurityGroupId: resp.SecurityGroup.SecurityGroupId,
-		Flow:            "Inbound",
-		Rules: []osc.SecurityGroupRule{
-			{
-				FromPortRange: int32(port),
-				ToPortRange:   int32(port),
-				IpRanges:      []string{s.TemporarySGSourceCidr},
-				IpProtocol:    "tcp",
-			},
+	inboundRules := []osc.SecurityGroupRule{
+		{
+			FromPortRange: int32(port),
+			ToPortRange:   int32(port),
+			IpRanges:      sourceCidrs,
+			IpProtocol:    "tcp",
 		},
 	}
+	ui.Say(fmt.Sprintf("Authorizing access to port %d from %s in the temporary security group...", port, strings.Join(sourceCidrs, ", ")))
 
-	ui.Say(fmt.Sprintf("Authorizing access to port %d from %s in the temporary security group...", port, s.TemporarySGSourceCidr))
+	for _, rule := range s.TemporarySGRules {
+		ipRanges := rule.IpRanges
+		if len(ipRanges) == 0 {
+			ipRanges = sourceCidrs
+		}
+		ui.Say(fmt.Sprintf("Authorizing %s from %s in the temporary security group...", rule.describe(), strings.Join(ipRanges, ", ")))
+		inboundRules = append(inboundRules, rule.oscRule(ipRanges))
+	}
 
 	_, _, err = conn.SecurityGroupRuleApi.CreateSecurityGroupRule(context.Background(), &osc.CreateSecurityGroupRuleOpts{
-		CreateSecurityGroupRuleRequest: optional.NewInterface(createSGRReq),
+		CreateSecurityGroupRuleRequest: optional.NewInterface(osc.CreateSecurityGroupRuleRequest{
+			SecurityGroupId: s.createdGroupId,
+			Flow:            "Inbound",
+			Rules:           inboundRules,
+		}),
 	})
 
 	if err != nil {
-		err := fmt.Errorf("Error authorizing temporary security group: %s", err)
+		err := fmt.Errorf("Error authorizing temporary security group: %s", DecodeError(err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
+	if len(s.TemporarySGOutboundRules) > 0 {
+		if err := s.restrictOutbound(conn, ui, resp.SecurityGroup); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	// Set some state data for use in future steps
 	state.Put("securityGroupIds", []string{s.createdGroupId})
 
 	return multistep.ActionContinue
 }
 
+// restrictOutbound replaces the default outbound rules of the group, which
+// allow all traffic, by the outbound rules of the configuration.
+func (s *StepSecurityGroup) restrictOutbound(conn *osc.APIClient, ui packersdk.Ui, group osc.SecurityGroup) error {
+	if group.NetId == "" {
+		return fmt.Errorf("temporary_security_group_outbound_rules can only be used in a Net")
+	}
+
+	var outboundRules []osc.SecurityGroupRule
+	for _, rule := range s.TemporarySGOutboundRules {
+		ui.Say(fmt.Sprintf("Authorizing %s to %s in the temporary security group...", rule.describe(), strings.Join(rule.IpRanges, ", ")))
+		outboundRules = append(outboundRules, rule.oscRule(rule.IpRanges))
+	}
+	_, _, err := conn.SecurityGroupRuleApi.CreateSecurityGroupRule(context.Background(), &osc.CreateSecurityGroupRuleOpts{
+		CreateSecurityGroupRuleRequest: optional.NewInterface(osc.CreateSecurityGroupRuleRequest{
+			SecurityGroupId: group.SecurityGroupId,
+			Flow:            "Outbound",
+			Rules:           outboundRules,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("Error authorizing outbound traffic of temporary security group: %s", DecodeError(err))
+	}
+
+	if len(group.OutboundRules) == 0 {
+		return nil
+	}
+	ui.Say("Revoking the default outbound rules of the temporary security group...")
+	_, _, err = conn.SecurityGroupRuleApi.DeleteSecurityGroupRule(context.Background(), &osc.DeleteSecurityGroupRuleOpts{
+		DeleteSecurityGroupRuleRequest: optional.NewInterface(osc.DeleteSecurityGroupRuleRequest{
+			SecurityGroupId: group.SecurityGroupId,
+			Flow:            "Outbound",
+			Rules:           group.OutboundRules,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("Error revoking the default outbound rules of temporary security group: %s", DecodeError(err))
+	}
+	return nil
+}
+
+func (r SecurityGroupRule) oscRule(ipRanges []string) osc.SecurityGroupRule {
+	return osc.SecurityGroupRule{
+		FromPortRange: int32(r.FromPort),
+		ToPortRange:   int32(r.ToPort),
+		IpRanges:      ipRanges,
+		IpProtocol:    r.Protocol,
+	}
+}
+
+func (r SecurityGroupRule) describe() string {
+	switch r.Protocol {
+	case "-1":
+		return "all traffic"
+	case "icmp":
+		return fmt.Sprintf("ICMP type %d code %d", r.FromPort, r.ToPort)
+	}
+	if r.FromPort == r.ToPort {
+		return fmt.Sprintf("%s port %d", r.Protocol, r.FromPort)
+	}
+	return fmt.Sprintf("%s ports %d-%d", r.Protocol, r.FromPort, r.ToPort)
+}
+
+// hostPublicIp returns the public IPv4 of the Packer host, as answered in
+// plain text by the service at publicIpURL.
+func hostPublicIp(ctx context.Context, publicIpURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, publicIpURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s answered %s", publicIpURL, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil || ip.To4() == nil {
+		return "", fmt.Errorf("%s answered %q, which is not an IPv4", publicIpURL, strings.TrimSpace(string(body)))
+	}
+	return ip.String(), nil
+}
+
 func (s *StepSecurityGroup) Cleanup(state multistep.StateBag) {
 	if s.createdGroupId == "" {
 		return
@@ -171,6 +313,26 @@ func (s *StepSecurityGroup) Cleanup(state multistep.StateBag) {
 	}
 }
 
+// GetFilteredSecurityGroups returns the Security Groups matching the filters.
+// Finding none is an error.
+func (d *SecurityGroupFilterOptions) GetFilteredSecurityGroups(conn *osc.APIClient) ([]osc.SecurityGroup, error) {
+	filterReq := buildSecurityGroupFilters(d.Filters)
+
+	log.Printf("Using SecurityGroup Filters %v", filterReq)
+
+	resp, _, err := conn.SecurityGroupApi.ReadSecurityGroups(context.Background(), &osc.ReadSecurityGroupsOpts{
+		ReadSecurityGroupsRequest: optional.NewInterface(osc.ReadSecurityGroupsRequest{
+			Filters: filterReq,
+		}),
+	})
+
+	if err != nil || len(resp.SecurityGroups) == 0 {
+		return nil, fmt.Errorf("Couldn't find security groups for filter: %s", DecodeError(err))
+	}
+
+	return resp.SecurityGroups, nil
+}
+
 func buildSecurityGroupFilters(input map[string]string) osc.FiltersSecurityGroup {
 	var filters osc.FiltersSecurityGroup
 
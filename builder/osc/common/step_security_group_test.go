@@ -0,0 +1,112 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+func TestStepSecurityGroup_rules(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	n := server.AddNet("eu-west-2", "10.0.0.0/16")
+
+	ipServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "203.0.113.7")
+	}))
+	defer ipServer.Close()
+
+	state := testServerState(t, server)
+	state.Put("net_id", n.NetId)
+
+	rules := []SecurityGroupRule{
+		{Protocol: "icmp"},
+		{Protocol: "udp", FromPort: 53, IpRanges: []string{"10.0.0.0/16"}},
+	}
+	outboundRules := []SecurityGroupRule{
+		{Protocol: "tcp", FromPort: 443, IpRanges: []string{"0.0.0.0/0"}},
+	}
+	for i := range rules {
+		if errs := rules[i].Prepare(false); len(errs) > 0 {
+			t.Fatalf("bad rule %#v: %v", rules[i], errs)
+		}
+	}
+	for i := range outboundRules {
+		if errs := outboundRules[i].Prepare(true); len(errs) > 0 {
+			t.Fatalf("bad rule %#v: %v", outboundRules[i], errs)
+		}
+	}
+
+	step := &StepSecurityGroup{
+		CommConfig:                &communicator.Config{Type: "ssh", SSH: communicator.SSH{SSHPort: 22}},
+		TemporarySGSourceCidrs:    []string{"192.0.2.0/24"},
+		TemporarySGSourcePublicIp: true,
+		PublicIpURL:               ipServer.URL,
+		TemporarySGRules:          rules,
+		TemporarySGOutboundRules:  outboundRules,
+		Tags:                      TagMap{"Name": "packer-{{ .BuildRegion }}"},
+		RawRegion:                 "eu-west-2",
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+
+	sgs := server.SecurityGroups()
+	if len(sgs) != 1 {
+		t.Fatalf("should have created one security group, got %#v", sgs)
+	}
+	sg := sgs[0]
+
+	expectedInbound := []osc.SecurityGroupRule{
+		{IpProtocol: "tcp", FromPortRange: 22, ToPortRange: 22, IpRanges: []string{"192.0.2.0/24", "203.0.113.7/32"}},
+		{IpProtocol: "icmp", FromPortRange: -1, ToPortRange: -1, IpRanges: []string{"192.0.2.0/24", "203.0.113.7/32"}},
+		{IpProtocol: "udp", FromPortRange: 53, ToPortRange: 53, IpRanges: []string{"10.0.0.0/16"}},
+	}
+	if !reflect.DeepEqual(sg.InboundRules, expectedInbound) {
+		t.Fatalf("bad inbound rules: %#v", sg.InboundRules)
+	}
+	expectedOutbound := []osc.SecurityGroupRule{
+		{IpProtocol: "tcp", FromPortRange: 443, ToPortRange: 443, IpRanges: []string{"0.0.0.0/0"}},
+	}
+	if !reflect.DeepEqual(sg.OutboundRules, expectedOutbound) {
+		t.Fatalf("the default outbound rule should be replaced, got %#v", sg.OutboundRules)
+	}
+	if len(sg.Tags) != 1 || sg.Tags[0].Value != "packer-eu-west-2" {
+		t.Fatalf("the security group should be tagged, got %#v", sg.Tags)
+	}
+
+	step.Cleanup(state)
+	if sgs := server.SecurityGroups(); len(sgs) != 0 {
+		t.Fatalf("the temporary security group should be deleted, got %#v", sgs)
+	}
+}
+
+func TestStepSecurityGroup_outboundRulesWithoutNet(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+
+	state := testServerState(t, server)
+	state.Put("net_id", "")
+
+	step := &StepSecurityGroup{
+		CommConfig:               &communicator.Config{Type: "ssh", SSH: communicator.SSH{SSHPort: 22}},
+		TemporarySGSourceCidrs:   []string{"0.0.0.0/0"},
+		TemporarySGOutboundRules: []SecurityGroupRule{{Protocol: "-1", FromPort: -1, ToPort: -1, IpRanges: []string{"10.0.0.0/8"}}},
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("should halt, got %v", action)
+	}
+
+	step.Cleanup(state)
+	if sgs := server.SecurityGroups(); len(sgs) != 0 {
+		t.Fatalf("the temporary security group should be deleted, got %#v", sgs)
+	}
+}
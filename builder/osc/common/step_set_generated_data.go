@@ -0,0 +1,75 @@
+package common
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// GetSourceOMIGeneratedDataList returns the generated data keys set by
+// StepSetGeneratedData.
+func GetSourceOMIGeneratedDataList() []string {
+	return []string{
+		"BuildRegion",
+		"SourceOMI",
+		"SourceOMIName",
+		"SourceOMITags",
+	}
+}
+
+// GetGeneratedDataList returns the generated data keys of the builders
+// launching a source VM: the ones of the source OMI, and the ones set by
+// StepRunSourceVm.
+func GetGeneratedDataList() []string {
+	return append(GetSourceOMIGeneratedDataList(),
+		"VmId",
+		"VmType",
+		"NetId",
+		"SubnetId",
+		"SubregionName",
+		"PrivateIp",
+		"PublicIp",
+		"RootVolumeId",
+	)
+}
+
+// StepSetGeneratedData registers the source OMI of the build as generated
+// data, for provisioners and post-processors.
+type StepSetGeneratedData struct {
+	GeneratedData *packerbuilderdata.GeneratedData
+	RawRegion     string
+}
+
+func (s *StepSetGeneratedData) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	info := extractBuildInfo(s.RawRegion, state)
+
+	s.GeneratedData.Put("BuildRegion", info.BuildRegion)
+	s.GeneratedData.Put("SourceOMI", info.SourceOMI)
+	s.GeneratedData.Put("SourceOMIName", info.SourceOMIName)
+	s.GeneratedData.Put("SourceOMITags", info.SourceOMITags)
+
+	return multistep.ActionContinue
+}
+
+func (s *StepSetGeneratedData) Cleanup(_ multistep.StateBag) {}
+
+// setVmGeneratedData registers the VM of the build as generated data.
+func setVmGeneratedData(generatedData *packerbuilderdata.GeneratedData, vm osc.Vm) {
+	var rootVolumeId string
+	for _, mapping := range vm.BlockDeviceMappings {
+		if mapping.DeviceName == vm.RootDeviceName {
+			rootVolumeId = mapping.Bsu.VolumeId
+		}
+	}
+
+	generatedData.Put("VmId", vm.VmId)
+	generatedData.Put("VmType", vm.VmType)
+	generatedData.Put("NetId", vm.NetId)
+	generatedData.Put("SubnetId", vm.SubnetId)
+	generatedData.Put("SubregionName", vm.Placement.SubregionName)
+	generatedData.Put("PrivateIp", vm.PrivateIp)
+	generatedData.Put("PublicIp", vm.PublicIp)
+	generatedData.Put("RootVolumeId", rootVolumeId)
+}
@@ -59,16 +59,33 @@ func (s *StepSourceOMIInfo) Run(_ context.Context, state multistep.StateBag) mul
 		params.Filters.ImageIds = []string{s.SourceOmi}
 	}
 
+	image, err := s.OmiFilters.GetFilteredImage(params, oscconn)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Found Image ID: %s", image.ImageId))
+
+	state.Put("source_image", *image)
+	return multistep.ActionContinue
+}
+
+// GetFilteredImage queries the OMIs matching the filters and owners, on top
+// of the given request, and returns the only match, or the most recent one
+// when most_recent is set.
+func (d *OmiFilterOptions) GetFilteredImage(params osc.ReadImagesRequest, oscconn *osc.APIClient) (*osc.Image, error) {
 	// We have filters to apply
-	if len(s.OmiFilters.Filters) > 0 {
-		params.Filters = buildOSCOMIFilters(s.OmiFilters.Filters)
+	if len(d.Filters) > 0 {
+		params.Filters = buildOSCOMIFilters(d.Filters)
 	}
 	//TODO:Check if AccountIds correspond to Owners.
-	if len(s.OmiFilters.Owners) > 0 {
+	if len(d.Owners) > 0 {
 		var oid []string
 		var oali []string
 
-		for _, o := range s.OmiFilters.Owners {
+		for _, o := range d.Owners {
 			if isNumeric(o) {
 				oid = append(oid, o)
 			} else {
@@ -84,37 +101,25 @@ func (s *StepSourceOMIInfo) Run(_ context.Context, state multistep.StateBag) mul
 		ReadImagesRequest: optional.NewInterface(params),
 	})
 	if err != nil {
-		err := fmt.Errorf("Error querying OMI: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+		return nil, fmt.Errorf("Error querying OMI: %s", DecodeError(err))
 	}
 
 	if len(imageResp.Images) == 0 {
-		err := fmt.Errorf("No OMI was found matching filters: %#v", params)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+		return nil, fmt.Errorf("No OMI was found matching filters: %#v", params)
 	}
 
-	if len(imageResp.Images) > 1 && !s.OmiFilters.MostRecent {
-		err := fmt.Errorf("your query returned more than one result. Please try a more specific search, or set most_recent to true")
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+	if len(imageResp.Images) > 1 && !d.MostRecent {
+		return nil, fmt.Errorf("your query returned more than one result. Please try a more specific search, or set most_recent to true")
 	}
 
 	var image osc.Image
-	if s.OmiFilters.MostRecent {
+	if d.MostRecent {
 		image = mostRecentOscOmi(imageResp.Images)
 	} else {
 		image = imageResp.Images[0]
 	}
 
-	ui.Message(fmt.Sprintf("Found Image ID: %s", image.ImageId))
-
-	state.Put("source_image", image)
-	return multistep.ActionContinue
+	return &image, nil
 }
 
 func (s *StepSourceOMIInfo) Cleanup(multistep.StateBag) {}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/antihax/optional"
-	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/outscale/osc-sdk-go/osc"
@@ -15,6 +14,7 @@ import (
 type StepStopBSUBackedVm struct {
 	Skip          bool
 	DisableStopVm bool
+	PollingConfig *PollingConfig
 }
 
 func (s *StepStopBSUBackedVm) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -55,17 +55,15 @@ func (s *StepStopBSUBackedVm) Run(ctx context.Context, state multistep.StateBag)
 				return true, nil
 			}
 
-			if awsErr, ok := err.(awserr.Error); ok {
-				if awsErr.Code() == "InvalidVmID.NotFound" {
-					ui.Message(fmt.Sprintf(
-						"Error stopping vm; will retry ..."+
-							"Error: %s", err))
-					// retry
-					return false, nil
-				}
+			if IsNotFoundError(err) || IsThrottledError(err) {
+				ui.Message(fmt.Sprintf(
+					"Error stopping vm; will retry ..."+
+						"Error: %s", DecodeError(err)))
+				// retry
+				return false, nil
 			}
 			// errored, but not in expected way. Don't want to retry
-			return true, err
+			return true, DecodeError(err)
 		})
 
 		if err != nil {
@@ -83,9 +81,9 @@ func (s *StepStopBSUBackedVm) Run(ctx context.Context, state multistep.StateBag)
 	ui.Say("Waiting for the vm to stop...")
 	switch vm.VmInitiatedShutdownBehavior {
 	case StopShutdownBehavior:
-		err = waitUntilOscVmStopped(oscconn, vm.VmId)
+		err = s.PollingConfig.waitUntilOscVmStopped(ctx, oscconn, vm.VmId)
 	case TerminateShutdownBehavior:
-		err = waitUntilOscVmDeleted(oscconn, vm.VmId)
+		err = s.PollingConfig.waitUntilOscVmDeleted(ctx, oscconn, vm.VmId)
 	default:
 		err := fmt.Errorf("Wrong value for the shutdown behavior")
 		state.Put("error", err)
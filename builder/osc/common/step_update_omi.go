@@ -3,6 +3,7 @@ package common
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/antihax/optional"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
@@ -12,9 +13,25 @@ import (
 	osc "github.com/outscale/osc-sdk-go/osc"
 )
 
+// groupAll is the group of every account, making a resource public.
+const groupAll = "all"
+
+// StepUpdateOMIAttributes sets the permissions of the OMI and of its
+// snapshots in every region, once copied: the launch permissions of the OMI
+// are given to AccountIds, and the permissions to create volumes from the
+// snapshots to SnapshotAccountIds. GlobalPermission, or the all group, makes
+// them public. Once any permission is configured, the other ones are
+// removed, and the permissions are read back to check that they were
+// applied.
+//
+// As product codes cannot be added to an OMI, the step only checks that the
+// OMI inherited ProductCodes from its source.
 type StepUpdateOMIAttributes struct {
 	AccountIds         []string
 	SnapshotAccountIds []string
+	Groups             []string
+	SnapshotGroups     []string
+	ProductCodes       []string
 	RawRegion          string
 	GlobalPermission   bool
 	Ctx                interpolate.Context
@@ -32,76 +49,159 @@ func (s *StepUpdateOMIAttributes) Run(_ context.Context, state multistep.StateBa
 	omis := state.Get("omis").(map[string]string)
 	snapshots := state.Get("snapshots").(map[string][]string)
 
-	// Determine if there is any work to do.
-	valid := false
-	valid = valid || (s.AccountIds != nil && len(s.AccountIds) > 0)
-	valid = valid || (s.SnapshotAccountIds != nil && len(s.SnapshotAccountIds) > 0)
+	omiPermissions := osc.PermissionsOnResource{
+		AccountIds:       s.AccountIds,
+		GlobalPermission: s.GlobalPermission || contains(s.Groups, groupAll),
+	}
+	snapshotPermissions := osc.PermissionsOnResource{
+		AccountIds:       s.SnapshotAccountIds,
+		GlobalPermission: s.GlobalPermission || contains(s.SnapshotGroups, groupAll),
+	}
 
-	if !valid {
+	// Determine if there is any work to do.
+	if !hasPermissions(omiPermissions) && !hasPermissions(snapshotPermissions) && len(s.ProductCodes) == 0 {
 		return multistep.ActionContinue
 	}
 
 	s.Ctx.Data = extractBuildInfo(s.RawRegion, state)
 
-	updateSnapshoptRequest := osc.UpdateSnapshotRequest{
-		PermissionsToCreateVolume: osc.PermissionsOnResourceCreation{
-			Additions: osc.PermissionsOnResource{
-				AccountIds:       s.AccountIds,
-				GlobalPermission: s.GlobalPermission,
-			},
-		},
-	}
-
-	updateImageRequest := osc.UpdateImageRequest{
-		PermissionsToLaunch: osc.PermissionsOnResourceCreation{
-			Additions: osc.PermissionsOnResource{
-				AccountIds:       s.AccountIds,
-				GlobalPermission: s.GlobalPermission,
-			},
-		},
+	regions := make([]string, 0, len(omis))
+	for region := range omis {
+		regions = append(regions, region)
 	}
+	sort.Strings(regions)
 
-	// Updating image attributes
-	for region, omi := range omis {
-		ui.Say(fmt.Sprintf("Updating attributes on OMI (%s)...", omi))
+	for _, region := range regions {
 		regionconn := config.NewOSCClientByRegion(region)
 
-		ui.Message(fmt.Sprintf("Updating: %s", omi))
-		updateImageRequest.ImageId = omi
-		_, _, err := regionconn.ImageApi.UpdateImage(context.Background(), &osc.UpdateImageOpts{
-			UpdateImageRequest: optional.NewInterface(updateImageRequest),
-		})
-
+		err := s.updateOMI(regionconn, ui, omis[region], omiPermissions)
+		if err == nil {
+			err = s.updateSnapshots(regionconn, ui, snapshots[region], snapshotPermissions)
+		}
 		if err != nil {
-			err := fmt.Errorf("Error updating OMI: %s", err)
+			err := fmt.Errorf("%s: %s", region, err)
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
 	}
 
-	// Updating snapshot attributes
-	for region, region_snapshots := range snapshots {
-		for _, snapshot := range region_snapshots {
-			ui.Say(fmt.Sprintf("Updating attributes on snapshot (%s)...", snapshot))
-			regionconn := config.NewOSCClientByRegion(region)
-
-			ui.Message(fmt.Sprintf("Updating: %s", snapshot))
-			updateSnapshoptRequest.SnapshotId = snapshot
-			_, _, err := regionconn.SnapshotApi.UpdateSnapshot(context.Background(), &osc.UpdateSnapshotOpts{
-				UpdateSnapshotRequest: optional.NewInterface(updateSnapshoptRequest),
-			})
-			if err != nil {
-				err := fmt.Errorf("Error updating snapshot: %s", err)
-				state.Put("error", err)
-				ui.Error(err.Error())
-				return multistep.ActionHalt
-			}
+	return multistep.ActionContinue
+}
+
+// updateOMI checks the product codes of the OMI, and sets its launch
+// permissions.
+func (s *StepUpdateOMIAttributes) updateOMI(conn *osc.APIClient, ui packersdk.Ui, omi string, permissions osc.PermissionsOnResource) error {
+	ui.Say(fmt.Sprintf("Updating attributes on OMI (%s)...", omi))
 
+	image, err := readImage(conn, omi)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, code := range s.ProductCodes {
+		if !contains(image.ProductCodes, code) {
+			missing = append(missing, code)
 		}
 	}
+	if len(missing) > 0 {
+		return fmt.Errorf("The OMI %s lacks the product codes %v: product codes cannot be added to an OMI, they must come from the source OMI", omi, missing)
+	}
 
-	return multistep.ActionContinue
+	update, changed := permissionsUpdate(image.PermissionsToLaunch, permissions)
+	if !changed {
+		return nil
+	}
+
+	ui.Message(fmt.Sprintf("Updating: %s", omi))
+	_, _, err = conn.ImageApi.UpdateImage(context.Background(), &osc.UpdateImageOpts{
+		UpdateImageRequest: optional.NewInterface(osc.UpdateImageRequest{
+			ImageId:             omi,
+			PermissionsToLaunch: update,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("Error updating OMI: %s", DecodeError(err))
+	}
+
+	image, err = readImage(conn, omi)
+	if err != nil {
+		return err
+	}
+	if _, changed := permissionsUpdate(image.PermissionsToLaunch, permissions); changed {
+		return fmt.Errorf("The launch permissions of the OMI %s are %+v, not %+v", omi, image.PermissionsToLaunch, permissions)
+	}
+	return nil
+}
+
+// updateSnapshots sets the permissions to create volumes from the
+// snapshots.
+func (s *StepUpdateOMIAttributes) updateSnapshots(conn *osc.APIClient, ui packersdk.Ui, ids []string, permissions osc.PermissionsOnResource) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	current, err := readSnapshotPermissions(conn, ids)
+	if err != nil {
+		return err
+	}
+
+	var updated []string
+	for _, snapshot := range ids {
+		update, changed := permissionsUpdate(current[snapshot], permissions)
+		if !changed {
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Updating attributes on snapshot (%s)...", snapshot))
+		ui.Message(fmt.Sprintf("Updating: %s", snapshot))
+		_, _, err := conn.SnapshotApi.UpdateSnapshot(context.Background(), &osc.UpdateSnapshotOpts{
+			UpdateSnapshotRequest: optional.NewInterface(osc.UpdateSnapshotRequest{
+				SnapshotId:                snapshot,
+				PermissionsToCreateVolume: update,
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("Error updating snapshot: %s", DecodeError(err))
+		}
+		updated = append(updated, snapshot)
+	}
+	if len(updated) == 0 {
+		return nil
+	}
+
+	current, err = readSnapshotPermissions(conn, updated)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range updated {
+		if _, changed := permissionsUpdate(current[snapshot], permissions); changed {
+			return fmt.Errorf("The permissions to create volumes from the snapshot %s are %+v, not %+v", snapshot, current[snapshot], permissions)
+		}
+	}
+	return nil
+}
+
+// permissionsUpdate returns the additions and removals turning the current
+// permissions into the wanted ones, and whether there are any.
+func permissionsUpdate(current, wanted osc.PermissionsOnResource) (osc.PermissionsOnResourceCreation, bool) {
+	var update osc.PermissionsOnResourceCreation
+	for _, id := range wanted.AccountIds {
+		if !contains(current.AccountIds, id) && !contains(update.Additions.AccountIds, id) {
+			update.Additions.AccountIds = append(update.Additions.AccountIds, id)
+		}
+	}
+	for _, id := range current.AccountIds {
+		if !contains(wanted.AccountIds, id) {
+			update.Removals.AccountIds = append(update.Removals.AccountIds, id)
+		}
+	}
+	update.Additions.GlobalPermission = wanted.GlobalPermission && !current.GlobalPermission
+	update.Removals.GlobalPermission = current.GlobalPermission && !wanted.GlobalPermission
+
+	changed := hasPermissions(update.Additions) || hasPermissions(update.Removals)
+	return update, changed
 }
 
 func (s *StepUpdateOMIAttributes) Cleanup(state multistep.StateBag) {
@@ -1,9 +1,12 @@
 package common
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
 
 	"bytes"
 	"context"
@@ -27,14 +30,20 @@ func tState() multistep.StateBag {
 }
 
 func TestUpdateOmi(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	image := server.AddImage("eu-west-2", osc.Image{ImageName: "packer"})
+	snapshot := image.BlockDeviceMappings[0].Bsu.SnapshotId
 
 	stepUpdateOMIAttributes := StepUpdateOMIAttributes{
 		AccountIds:         []string{},
 		SnapshotAccountIds: []string{},
-		RawRegion:          "us-west-2",
+		RawRegion:          "eu-west-2",
 		GlobalPermission:   true,
 	}
-	state := tState()
+	state := testServerState(t, server)
+	state.Put("omis", map[string]string{"eu-west-2": image.ImageId})
+	state.Put("snapshots", map[string][]string{"eu-west-2": {snapshot}})
 
 	action := stepUpdateOMIAttributes.Run(context.Background(), state)
 	if err := state.Get("error"); err != nil {
@@ -45,4 +54,70 @@ func TestUpdateOmi(t *testing.T) {
 		t.Fatalf("shoul continue, but: %v", action)
 	}
 
+	if images := server.Images(); !images[0].PermissionsToLaunch.GlobalPermission {
+		t.Fatalf("global_permission alone should make the OMI public, got %#v", images[0].PermissionsToLaunch)
+	}
+	if snapshots := server.Snapshots(); !snapshots[0].PermissionsToCreateVolume.GlobalPermission {
+		t.Fatalf("global_permission alone should make the snapshot public, got %#v", snapshots[0].PermissionsToCreateVolume)
+	}
+}
+
+func TestUpdateOmi_noPermissions(t *testing.T) {
+	stepUpdateOMIAttributes := StepUpdateOMIAttributes{RawRegion: "us-west-2"}
+	state := tState()
+
+	if action := stepUpdateOMIAttributes.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should skip without any permission, but: %v", action)
+	}
+}
+
+func TestUpdateOmi_permissions(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	image := server.AddImage("eu-west-2", osc.Image{
+		ImageName:           "packer",
+		ProductCodes:        []string{"0001"},
+		PermissionsToLaunch: osc.PermissionsOnResource{AccountIds: []string{"111111111111"}, GlobalPermission: true},
+	})
+	snapshot := image.BlockDeviceMappings[0].Bsu.SnapshotId
+
+	stepUpdateOMIAttributes := StepUpdateOMIAttributes{
+		AccountIds:         []string{"222222222222"},
+		SnapshotAccountIds: []string{"333333333333"},
+		SnapshotGroups:     []string{"all"},
+		ProductCodes:       []string{"0001"},
+		RawRegion:          "eu-west-2",
+	}
+	state := testServerState(t, server)
+	state.Put("omis", map[string]string{"eu-west-2": image.ImageId})
+	state.Put("snapshots", map[string][]string{"eu-west-2": {snapshot}})
+
+	if action := stepUpdateOMIAttributes.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, but: %v: %v", action, state.Get("error"))
+	}
+
+	expected := osc.PermissionsOnResource{AccountIds: []string{"222222222222"}}
+	if permissions := server.Images()[0].PermissionsToLaunch; !reflect.DeepEqual(permissions, expected) {
+		t.Fatalf("the launch permissions should be replaced, got %#v", permissions)
+	}
+	expected = osc.PermissionsOnResource{AccountIds: []string{"333333333333"}, GlobalPermission: true}
+	if permissions := server.Snapshots()[0].PermissionsToCreateVolume; !reflect.DeepEqual(permissions, expected) {
+		t.Fatalf("the snapshot permissions should use snapshot_account_ids and snapshot_groups, got %#v", permissions)
+	}
+	if calls := server.Calls("UpdateImage"); calls != 1 {
+		t.Fatalf("the OMI should be updated once, got %d calls", calls)
+	}
+
+	// Permissions already set are left alone.
+	if action := stepUpdateOMIAttributes.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, but: %v: %v", action, state.Get("error"))
+	}
+	if calls := server.Calls("UpdateImage") + server.Calls("UpdateSnapshot"); calls != 2 {
+		t.Fatalf("nothing should be updated again, got %d calls", calls)
+	}
+
+	stepUpdateOMIAttributes.ProductCodes = []string{"0002"}
+	if action := stepUpdateOMIAttributes.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("should halt when the OMI lacks a product code, but: %v", action)
+	}
 }
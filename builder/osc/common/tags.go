@@ -58,3 +58,12 @@ func CreateOSCTags(conn *osc.APIClient, resourceID string, ui packersdk.Ui, tags
 
 	return err
 }
+
+// TagMapFromOSCTags converts the tags of a resource into a TagMap.
+func TagMapFromOSCTags(tags []osc.ResourceTag) TagMap {
+	tagMap := make(TagMap, len(tags))
+	for _, tag := range tags {
+		tagMap[tag.Key] = tag.Value
+	}
+	return tagMap
+}
@@ -0,0 +1,283 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/antihax/optional"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+// temporaryNet creates the Net of temporary_net and the resources giving it
+// internet access, remembering how to delete each of them.
+type temporaryNet struct {
+	conn   *osc.APIClient
+	ui     packersdk.Ui
+	config *TemporaryNetConfig
+	tags   OSCTags
+
+	// undo holds the functions deleting the created resources, in creation
+	// order.
+	undo []func(ctx context.Context) error
+}
+
+// create creates the Net, with an internet service and a public Subnet routed
+// to it. When the Net is private, the VM Subnet is routed through a NAT
+// service of the public Subnet. It returns the Subnet of the VM.
+func (t *temporaryNet) create(ctx context.Context, polling *PollingConfig, subregionName string) (*osc.Subnet, error) {
+	t.ui.Say(fmt.Sprintf("Creating temporary Net %s", t.config.IpRange))
+	netResp, _, err := t.conn.NetApi.CreateNet(ctx, &osc.CreateNetOpts{
+		CreateNetRequest: optional.NewInterface(osc.CreateNetRequest{
+			IpRange: t.config.IpRange,
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error creating temporary Net: %s", DecodeError(err))
+	}
+	netID := netResp.Net.NetId
+	t.push(func(ctx context.Context) error {
+		t.ui.Say(fmt.Sprintf("Deleting temporary Net: %s", netID))
+		_, _, err := t.conn.NetApi.DeleteNet(ctx, &osc.DeleteNetOpts{
+			DeleteNetRequest: optional.NewInterface(osc.DeleteNetRequest{NetId: netID}),
+		})
+		return cleanupError("Net", netID, err)
+	})
+	if err := polling.waitUntilOscNetAvailable(ctx, t.conn, netID); err != nil {
+		return nil, fmt.Errorf("Error waiting for temporary Net %s: %s", netID, err)
+	}
+	if err := t.tag(ctx, netID); err != nil {
+		return nil, err
+	}
+
+	serviceResp, _, err := t.conn.InternetServiceApi.CreateInternetService(ctx, &osc.CreateInternetServiceOpts{
+		CreateInternetServiceRequest: optional.NewInterface(osc.CreateInternetServiceRequest{}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error creating temporary internet service: %s", DecodeError(err))
+	}
+	serviceID := serviceResp.InternetService.InternetServiceId
+	log.Printf("[INFO] Created internet service %s", serviceID)
+	t.push(func(ctx context.Context) error {
+		t.ui.Say(fmt.Sprintf("Deleting temporary internet service: %s", serviceID))
+		_, _, err := t.conn.InternetServiceApi.DeleteInternetService(ctx, &osc.DeleteInternetServiceOpts{
+			DeleteInternetServiceRequest: optional.NewInterface(osc.DeleteInternetServiceRequest{InternetServiceId: serviceID}),
+		})
+		return cleanupError("internet service", serviceID, err)
+	})
+	if err := t.tag(ctx, serviceID); err != nil {
+		return nil, err
+	}
+
+	_, _, err = t.conn.InternetServiceApi.LinkInternetService(ctx, &osc.LinkInternetServiceOpts{
+		LinkInternetServiceRequest: optional.NewInterface(osc.LinkInternetServiceRequest{
+			InternetServiceId: serviceID,
+			NetId:             netID,
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error linking internet service %s to Net %s: %s", serviceID, netID, DecodeError(err))
+	}
+	t.push(func(ctx context.Context) error {
+		_, _, err := t.conn.InternetServiceApi.UnlinkInternetService(ctx, &osc.UnlinkInternetServiceOpts{
+			UnlinkInternetServiceRequest: optional.NewInterface(osc.UnlinkInternetServiceRequest{
+				InternetServiceId: serviceID,
+				NetId:             netID,
+			}),
+		})
+		return cleanupError("internet service link", serviceID, err)
+	})
+
+	publicIpRange := t.config.SubnetIpRange
+	if t.config.Private {
+		publicIpRange = t.config.NatSubnetIpRange
+	}
+	publicSubnet, err := t.createSubnet(ctx, polling, netID, publicIpRange, subregionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.createRoute(ctx, netID, publicSubnet.SubnetId, osc.CreateRouteRequest{GatewayId: serviceID}); err != nil {
+		return nil, err
+	}
+	if !t.config.Private {
+		return publicSubnet, nil
+	}
+
+	ipResp, _, err := t.conn.PublicIpApi.CreatePublicIp(ctx, &osc.CreatePublicIpOpts{
+		CreatePublicIpRequest: optional.NewInterface(osc.CreatePublicIpRequest{}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error creating the public IP of the NAT service: %s", DecodeError(err))
+	}
+	publicIpID := ipResp.PublicIp.PublicIpId
+	log.Printf("[INFO] Created public IP %s", publicIpID)
+	t.push(func(ctx context.Context) error {
+		t.ui.Say(fmt.Sprintf("Deleting the public IP of the NAT service: %s", publicIpID))
+		_, _, err := t.conn.PublicIpApi.DeletePublicIp(ctx, &osc.DeletePublicIpOpts{
+			DeletePublicIpRequest: optional.NewInterface(osc.DeletePublicIpRequest{PublicIpId: publicIpID}),
+		})
+		return cleanupError("public IP", publicIpID, err)
+	})
+	if err := t.tag(ctx, publicIpID); err != nil {
+		return nil, err
+	}
+
+	t.ui.Say(fmt.Sprintf("Creating temporary NAT service in Subnet %s", publicSubnet.SubnetId))
+	natResp, _, err := t.conn.NatServiceApi.CreateNatService(ctx, &osc.CreateNatServiceOpts{
+		CreateNatServiceRequest: optional.NewInterface(osc.CreateNatServiceRequest{
+			PublicIpId: publicIpID,
+			SubnetId:   publicSubnet.SubnetId,
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error creating temporary NAT service: %s", DecodeError(err))
+	}
+	natServiceID := natResp.NatService.NatServiceId
+	t.push(func(ctx context.Context) error {
+		t.ui.Say(fmt.Sprintf("Deleting temporary NAT service: %s", natServiceID))
+		_, _, err := t.conn.NatServiceApi.DeleteNatService(ctx, &osc.DeleteNatServiceOpts{
+			DeleteNatServiceRequest: optional.NewInterface(osc.DeleteNatServiceRequest{NatServiceId: natServiceID}),
+		})
+		if err != nil {
+			return cleanupError("NAT service", natServiceID, err)
+		}
+		// The public IP and the Subnet are released once the NAT service
+		// is deleted.
+		if err := polling.waitUntilOscNatServiceDeleted(ctx, t.conn, natServiceID); err != nil {
+			return fmt.Errorf("Error waiting for the deletion of NAT service %s: %s", natServiceID, err)
+		}
+		return nil
+	})
+	if err := polling.waitUntilOscNatServiceAvailable(ctx, t.conn, natServiceID); err != nil {
+		return nil, fmt.Errorf("Error waiting for temporary NAT service %s: %s", natServiceID, err)
+	}
+	if err := t.tag(ctx, natServiceID); err != nil {
+		return nil, err
+	}
+
+	privateSubnet, err := t.createSubnet(ctx, polling, netID, t.config.SubnetIpRange, publicSubnet.SubregionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.createRoute(ctx, netID, privateSubnet.SubnetId, osc.CreateRouteRequest{NatServiceId: natServiceID}); err != nil {
+		return nil, err
+	}
+	return privateSubnet, nil
+}
+
+func (t *temporaryNet) createSubnet(ctx context.Context, polling *PollingConfig, netID, ipRange, subregionName string) (*osc.Subnet, error) {
+	t.ui.Say(fmt.Sprintf("Creating temporary Subnet %s", ipRange))
+	resp, _, err := t.conn.SubnetApi.CreateSubnet(ctx, &osc.CreateSubnetOpts{
+		CreateSubnetRequest: optional.NewInterface(osc.CreateSubnetRequest{
+			IpRange:       ipRange,
+			NetId:         netID,
+			SubregionName: subregionName,
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error creating temporary Subnet %s: %s", ipRange, DecodeError(err))
+	}
+	subnet := resp.Subnet
+	t.push(func(ctx context.Context) error {
+		t.ui.Say(fmt.Sprintf("Deleting temporary Subnet: %s", subnet.SubnetId))
+		_, _, err := t.conn.SubnetApi.DeleteSubnet(ctx, &osc.DeleteSubnetOpts{
+			DeleteSubnetRequest: optional.NewInterface(osc.DeleteSubnetRequest{SubnetId: subnet.SubnetId}),
+		})
+		return cleanupError("Subnet", subnet.SubnetId, err)
+	})
+	if err := polling.waitUntilOscSubnetAvailable(ctx, t.conn, subnet.SubnetId); err != nil {
+		return nil, fmt.Errorf("Error waiting for temporary Subnet %s: %s", subnet.SubnetId, err)
+	}
+	if err := t.tag(ctx, subnet.SubnetId); err != nil {
+		return nil, err
+	}
+	return &subnet, nil
+}
+
+// createRoute creates a route table linked to the Subnet, whose default
+// route goes through the target of the route request.
+func (t *temporaryNet) createRoute(ctx context.Context, netID, subnetID string, route osc.CreateRouteRequest) error {
+	tableResp, _, err := t.conn.RouteTableApi.CreateRouteTable(ctx, &osc.CreateRouteTableOpts{
+		CreateRouteTableRequest: optional.NewInterface(osc.CreateRouteTableRequest{NetId: netID}),
+	})
+	if err != nil {
+		return fmt.Errorf("Error creating temporary route table: %s", DecodeError(err))
+	}
+	tableID := tableResp.RouteTable.RouteTableId
+	log.Printf("[INFO] Created route table %s", tableID)
+	t.push(func(ctx context.Context) error {
+		_, _, err := t.conn.RouteTableApi.DeleteRouteTable(ctx, &osc.DeleteRouteTableOpts{
+			DeleteRouteTableRequest: optional.NewInterface(osc.DeleteRouteTableRequest{RouteTableId: tableID}),
+		})
+		return cleanupError("route table", tableID, err)
+	})
+	if err := t.tag(ctx, tableID); err != nil {
+		return err
+	}
+
+	route.DestinationIpRange = "0.0.0.0/0"
+	route.RouteTableId = tableID
+	_, _, err = t.conn.RouteApi.CreateRoute(ctx, &osc.CreateRouteOpts{
+		CreateRouteRequest: optional.NewInterface(route),
+	})
+	if err != nil {
+		return fmt.Errorf("Error creating the default route of route table %s: %s", tableID, DecodeError(err))
+	}
+
+	linkResp, _, err := t.conn.RouteTableApi.LinkRouteTable(ctx, &osc.LinkRouteTableOpts{
+		LinkRouteTableRequest: optional.NewInterface(osc.LinkRouteTableRequest{
+			RouteTableId: tableID,
+			SubnetId:     subnetID,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("Error linking route table %s to Subnet %s: %s", tableID, subnetID, DecodeError(err))
+	}
+	linkID := linkResp.LinkRouteTableId
+	t.push(func(ctx context.Context) error {
+		_, _, err := t.conn.RouteTableApi.UnlinkRouteTable(ctx, &osc.UnlinkRouteTableOpts{
+			UnlinkRouteTableRequest: optional.NewInterface(osc.UnlinkRouteTableRequest{LinkRouteTableId: linkID}),
+		})
+		return cleanupError("route table link", linkID, err)
+	})
+	return nil
+}
+
+func (t *temporaryNet) tag(ctx context.Context, resourceID string) error {
+	if len(t.tags) == 0 {
+		return nil
+	}
+	_, _, err := t.conn.TagApi.CreateTags(ctx, &osc.CreateTagsOpts{
+		CreateTagsRequest: optional.NewInterface(osc.CreateTagsRequest{
+			ResourceIds: []string{resourceID},
+			Tags:        t.tags,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("Error tagging %s: %s", resourceID, DecodeError(err))
+	}
+	return nil
+}
+
+func (t *temporaryNet) push(undo func(ctx context.Context) error) {
+	t.undo = append(t.undo, undo)
+}
+
+// delete deletes the created resources in reverse order, going on after an
+// error so that as much as possible is cleaned up.
+func (t *temporaryNet) delete(ctx context.Context) {
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		if err := t.undo[i](ctx); err != nil {
+			t.ui.Error(err.Error())
+		}
+	}
+	t.undo = nil
+}
+
+func cleanupError(kind, id string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("Error cleaning up temporary %s. Please delete it manually: %s: %s", kind, id, DecodeError(err))
+}
@@ -1,20 +1,46 @@
 package common
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"io/ioutil"
+	"log"
+	"math"
+	"math/rand"
 	"net/http"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/outscale/osc-sdk-go/osc"
+	"golang.org/x/time/rate"
+)
+
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
 )
 
 // Transport is used to sing the user for each request
+//
+// Throttled requests, and idempotent requests failing because of the network
+// or a server error, are retried up to maxRetries times with a jittered
+// exponential backoff, or after the delay asked for by Retry-After. Every
+// attempt first waits for the rate limiter, if any.
+//
+// With a cassette, the outcome of each request is recorded to it or, when
+// replaying, read from it without sending the request.
 type Transport struct {
-	transport http.RoundTripper
-	signer    *v4.Signer
-	region    string
+	transport  http.RoundTripper
+	signer     *v4.Signer
+	region     string
+	limiter    *rate.Limiter
+	maxRetries int
+	cassette   *cassette
 }
 
 func (t *Transport) sign(req *http.Request, body []byte) error {
@@ -26,35 +52,156 @@ func (t *Transport) sign(req *http.Request, body []byte) error {
 
 // RoundTrip is implemented according with the interface RoundTrip to sing for each request
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.cassette != nil && t.cassette.mode == cassetteReplay {
+		return t.cassette.replay(t.region, req)
+	}
+
 	//Get the body
-	getBody := req.GetBody
-	copyBody, err := getBody()
-	if err != nil {
-		return nil, err
+	var body []byte
+	if req.GetBody != nil {
+		copyBody, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+
+		body, err = ioutil.ReadAll(copyBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := t.roundTrip(req, body)
+	if err == nil && t.cassette != nil {
+		if err := t.cassette.record(t.region, req, body, resp); err != nil {
+			log.Printf("[WARN] Cannot record %s to the cassette: %s", path.Base(req.URL.Path), err)
+		}
 	}
+	return resp, err
+}
+
+// roundTrip sends the request, signed, until it succeeds or it is not worth
+// another attempt.
+func (t *Transport) roundTrip(req *http.Request, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if t.limiter != nil {
+			if err := t.limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+
+		// Each attempt is signed again, as the signature is only valid for
+		// a few minutes.
+		attemptReq := req.Clone(req.Context())
+		if body != nil {
+			attemptReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		if err := t.sign(attemptReq, body); err != nil {
+			return nil, err
+		}
 
-	body, err := ioutil.ReadAll(copyBody)
+		resp, err := t.transport.RoundTrip(attemptReq)
+		if attempt >= t.maxRetries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay := retryDelay(attempt, resp)
+		if err != nil {
+			log.Printf("[DEBUG] Retrying %s in %s (attempt %d/%d): %s", path.Base(req.URL.Path), delay, attempt+1, t.maxRetries, err)
+		} else {
+			log.Printf("[DEBUG] Retrying %s in %s (attempt %d/%d): %s", path.Base(req.URL.Path), delay, attempt+1, t.maxRetries, http.StatusText(resp.StatusCode))
+			// Drain the body so that the connection can be reused.
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// isIdempotent reports whether the request can be sent again without side
+// effects. OAPI calls are all POST requests, so only the Read* calls are.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return strings.HasPrefix(path.Base(req.URL.Path), "Read")
+}
+
+// shouldRetry reports whether the outcome of the request is worth another
+// attempt. Throttled requests have not been processed and can always be
+// retried.
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
 	if err != nil {
-		return nil, err
+		return req.Context().Err() == nil && isIdempotent(req)
 	}
 
-	if err := t.sign(req, body); err != nil {
-		return nil, err
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		// A 503 may also be returned after a request has been processed:
+		// a non-idempotent call is only sent again when it was throttled.
+		return isIdempotent(req) || isThrottledResponse(resp)
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return isIdempotent(req)
 	}
+	return false
+}
 
-	resp, err := t.transport.RoundTrip(req)
+// isThrottledResponse reports whether the response asks for the request to be
+// sent again later, with a Retry-After header or a throttling error. The body
+// is left readable.
+func isThrottledResponse(resp *http.Response) bool {
+	if resp.Header.Get("Retry-After") != "" {
+		return true
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
 	if err != nil {
-		return resp, err
+		return false
+	}
+
+	var response osc.ErrorResponse
+	if err := json.Unmarshal(body, &response); err != nil || len(response.Errors) == 0 {
+		return false
+	}
+	return isThrottlingType(response.Errors[0].Type)
+}
+
+// retryDelay returns the delay before the next attempt: the one asked for by
+// the Retry-After header, or an exponential backoff with full jitter.
+func retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+				return time.Duration(seconds) * time.Second
+			}
+			if date, err := http.ParseTime(retryAfter); err == nil {
+				if delay := time.Until(date); delay > 0 {
+					return delay
+				}
+				return 0
+			}
+		}
 	}
 
-	return resp, nil
+	backoff := math.Min(float64(retryBaseDelay)*math.Pow(2, float64(attempt)), float64(retryMaxDelay))
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
 }
 
-// NewTransport returns the transport signing with the given credentials
-func NewTransport(accessKey, accessSecret, region string, t http.RoundTripper) *Transport {
+// NewTransport returns the transport signing with the given credentials. The
+// token is only set when using temporary credentials.
+func NewTransport(accessKey, accessSecret, token, region string, t http.RoundTripper) *Transport {
 	s := &v4.Signer{
 		Credentials: credentials.NewStaticCredentials(accessKey,
-			accessSecret, ""),
+			accessSecret, token),
 	}
-	return &Transport{t, s, region}
+	return &Transport{transport: t, signer: s, region: region}
 }
@@ -0,0 +1,202 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type recordingTransport struct {
+	req *http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.req = req
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(new(bytes.Buffer))}, nil
+}
+
+func testSignedRequest(t *testing.T, token string) *http.Request {
+	recorder := &recordingTransport{}
+	transport := NewTransport("AK", "SK", token, "eu-west-2", recorder)
+
+	req, err := http.NewRequest("POST", "https://api.eu-west-2.outscale.com/api/v1/ReadVms", bytes.NewBufferString("{}"))
+	if err != nil {
+		t.Fatalf("cannot create request: %s", err)
+	}
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	return recorder.req
+}
+
+func TestTransport_signsSessionToken(t *testing.T) {
+	req := testSignedRequest(t, "TOKEN")
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "TOKEN" {
+		t.Fatalf("session token should be sent, got %q", got)
+	}
+	if req.Header.Get("Authorization") == "" {
+		t.Fatal("request should be signed")
+	}
+}
+
+func TestTransport_noSessionToken(t *testing.T) {
+	req := testSignedRequest(t, "")
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "" {
+		t.Fatalf("no session token should be sent, got %q", got)
+	}
+}
+
+type scriptedTransport struct {
+	responses []int
+	err       error
+	reqs      []*http.Request
+	bodies    []string
+	// noRetryAfter leaves the Retry-After header out of the 503 responses,
+	// which then have the given body.
+	noRetryAfter bool
+	body         string
+}
+
+func (t *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := ioutil.ReadAll(req.Body)
+	t.reqs = append(t.reqs, req)
+	t.bodies = append(t.bodies, string(body))
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	status := t.responses[0]
+	if len(t.responses) > 1 {
+		t.responses = t.responses[1:]
+	}
+	resp := &http.Response{StatusCode: status, Header: http.Header{}, Body: ioutil.NopCloser(new(bytes.Buffer))}
+	if status == http.StatusServiceUnavailable {
+		if t.noRetryAfter {
+			resp.Body = ioutil.NopCloser(bytes.NewBufferString(t.body))
+		} else {
+			resp.Header.Set("Retry-After", "0")
+		}
+	}
+	return resp, nil
+}
+
+func testRetriedRequest(t *testing.T, scripted *scriptedTransport, action string) (*http.Response, error) {
+	transport := NewTransport("AK", "SK", "", "eu-west-2", scripted)
+	transport.maxRetries = 3
+
+	req, err := http.NewRequest("POST", "https://api.eu-west-2.outscale.com/api/v1/"+action, bytes.NewBufferString("{}"))
+	if err != nil {
+		t.Fatalf("cannot create request: %s", err)
+	}
+	return transport.RoundTrip(req)
+}
+
+func TestTransport_retriesThrottled(t *testing.T) {
+	scripted := &scriptedTransport{responses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}}
+	resp, err := testRetriedRequest(t, scripted, "CreateVms")
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("bad status: %d", resp.StatusCode)
+	}
+	if len(scripted.reqs) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(scripted.reqs))
+	}
+	for i, body := range scripted.bodies {
+		if body != "{}" {
+			t.Fatalf("attempt %d sent body %q", i, body)
+		}
+		if scripted.reqs[i].Header.Get("Authorization") == "" {
+			t.Fatalf("attempt %d should be signed", i)
+		}
+	}
+}
+
+func TestTransport_maxRetries(t *testing.T) {
+	scripted := &scriptedTransport{responses: []int{http.StatusServiceUnavailable}}
+	resp, err := testRetriedRequest(t, scripted, "ReadVms")
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("bad status: %d", resp.StatusCode)
+	}
+	if len(scripted.reqs) != 4 {
+		t.Fatalf("expected 4 attempts, got %d", len(scripted.reqs))
+	}
+}
+
+func TestTransport_serverErrors(t *testing.T) {
+	scripted := &scriptedTransport{responses: []int{http.StatusInternalServerError}}
+	if _, err := testRetriedRequest(t, scripted, "CreateVms"); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if len(scripted.reqs) != 1 {
+		t.Fatalf("CreateVms shouldn't be retried, got %d attempts", len(scripted.reqs))
+	}
+
+	scripted = &scriptedTransport{err: errors.New("connection reset")}
+	if _, err := testRetriedRequest(t, scripted, "CreateVms"); err == nil {
+		t.Fatal("should have err")
+	}
+	if len(scripted.reqs) != 1 {
+		t.Fatalf("CreateVms shouldn't be retried, got %d attempts", len(scripted.reqs))
+	}
+}
+
+func TestTransport_unavailable(t *testing.T) {
+	scripted := &scriptedTransport{responses: []int{http.StatusServiceUnavailable}, noRetryAfter: true, body: "<html>Service Unavailable</html>"}
+	resp, err := testRetriedRequest(t, scripted, "CreateVms")
+	if err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if len(scripted.reqs) != 1 {
+		t.Fatalf("CreateVms shouldn't be retried on a bare 503, got %d attempts", len(scripted.reqs))
+	}
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != scripted.body {
+		t.Fatalf("the body should be left readable, got %q", body)
+	}
+
+	scripted = &scriptedTransport{responses: []int{http.StatusServiceUnavailable}, noRetryAfter: true}
+	if _, err := testRetriedRequest(t, scripted, "ReadVms"); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if len(scripted.reqs) != 4 {
+		t.Fatalf("ReadVms should be retried on a bare 503, got %d attempts", len(scripted.reqs))
+	}
+
+	scripted = &scriptedTransport{
+		responses:    []int{http.StatusServiceUnavailable, http.StatusOK},
+		noRetryAfter: true,
+		body:         `{"Errors":[{"Type":"RequestLimitExceeded","Code":"1","Details":""}]}`,
+	}
+	if _, err := testRetriedRequest(t, scripted, "CreateVms"); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if len(scripted.reqs) != 2 {
+		t.Fatalf("a throttled CreateVms should be retried, got %d attempts", len(scripted.reqs))
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", "7")
+	if delay := retryDelay(0, resp); delay != 7*time.Second {
+		t.Fatalf("Retry-After should be honored, got %s", delay)
+	}
+
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	if delay := retryDelay(0, resp); delay != 0 {
+		t.Fatalf("Retry-After in the past should not wait, got %s", delay)
+	}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		if delay := retryDelay(attempt, nil); delay < 0 || delay > retryMaxDelay {
+			t.Fatalf("attempt %d: bad backoff %s", attempt, delay)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,Config
+
+// Package keypair contains a packersdk.Datasource implementation that checks
+// that an Outscale keypair exists, and optionally that it matches a local
+// private key.
+package keypair
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/antihax/optional"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	"github.com/hashicorp/packer-plugin-sdk/pathing"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	osccommon.AccessConfig `mapstructure:",squash"`
+	// The name of the keypair registered in Outscale.
+	KeypairName string `mapstructure:"keypair_name"`
+	// The path of a local private key which must match the keypair.
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	Name        string `mapstructure:"name"`
+	Fingerprint string `mapstructure:"fingerprint"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, d.config.AccessConfig.Prepare(nil)...)
+
+	if d.config.KeypairName == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("The `keypair_name` must be specified"))
+	}
+
+	if d.config.PrivateKeyFile != "" {
+		path, err := pathing.ExpandUser(d.config.PrivateKeyFile)
+		if err == nil {
+			d.config.PrivateKeyFile = path
+			_, err = os.Stat(path)
+		}
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("private_key_file is invalid: %s", err))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.AccessKey, d.config.SecretKey, d.config.Token)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	oscConn, err := d.config.NewOSCClient()
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	resp, _, err := oscConn.KeypairApi.ReadKeypairs(context.Background(), &osc.ReadKeypairsOpts{
+		ReadKeypairsRequest: optional.NewInterface(osc.ReadKeypairsRequest{
+			Filters: osc.FiltersKeypair{
+				KeypairNames: []string{d.config.KeypairName},
+			},
+		}),
+	})
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Error querying keypair: %s", osccommon.DecodeError(err))
+	}
+
+	if len(resp.Keypairs) == 0 {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Keypair %s not found", d.config.KeypairName)
+	}
+	keypair := resp.Keypairs[0]
+
+	if d.config.PrivateKeyFile != "" {
+		privateKey, err := os.ReadFile(d.config.PrivateKeyFile)
+		if err != nil {
+			return cty.NullVal(cty.EmptyObject), fmt.Errorf("Error reading private_key_file: %s", err)
+		}
+
+		if err := checkFingerprint(privateKey, keypair.KeypairFingerprint); err != nil {
+			return cty.NullVal(cty.EmptyObject), fmt.Errorf("%s does not match keypair %s: %s",
+				d.config.PrivateKeyFile, d.config.KeypairName, err)
+		}
+	}
+
+	output := DatasourceOutput{
+		Name:        keypair.KeypairName,
+		Fingerprint: keypair.KeypairFingerprint,
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
@@ -0,0 +1,95 @@
+package keypair
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+)
+
+func TestDatasourceConfigure_NoKeypairName(t *testing.T) {
+	datasource := Datasource{}
+	if err := datasource.Configure(map[string]interface{}{}); err == nil {
+		t.Fatalf("Should error if keypair_name is not specified")
+	}
+}
+
+func TestDatasourceConfigure_MissingPrivateKeyFile(t *testing.T) {
+	datasource := Datasource{}
+	err := datasource.Configure(map[string]interface{}{
+		"keypair_name":     "packer",
+		"private_key_file": "/does/not/exist",
+	})
+	if err == nil {
+		t.Fatalf("Should error if private_key_file does not exist")
+	}
+}
+
+func TestDatasourceConfigure_PrivateKeyFileInHome(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Skipf("cannot find the home directory: %s", err)
+	}
+	file, err := os.CreateTemp(u.HomeDir, "packer-keypair-*.pem")
+	if err != nil {
+		t.Skipf("cannot write in the home directory: %s", err)
+	}
+	file.Close()
+	t.Cleanup(func() { os.Remove(file.Name()) })
+
+	datasource := Datasource{}
+	err = datasource.Configure(map[string]interface{}{
+		"keypair_name":     "packer",
+		"private_key_file": "~/" + filepath.Base(file.Name()),
+	})
+	if err != nil {
+		t.Fatalf("should expand ~ in private_key_file: %s", err)
+	}
+	if datasource.config.PrivateKeyFile != file.Name() {
+		t.Fatalf("the expanded path should be kept, got %s", datasource.config.PrivateKeyFile)
+	}
+}
+
+func testPrivateKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate key: %s", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
+func TestCheckFingerprint(t *testing.T) {
+	key, privateKey := testPrivateKey(t)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("cannot marshal key: %s", err)
+	}
+	created := sha1.Sum(pkcs8)
+
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("cannot marshal key: %s", err)
+	}
+	imported := md5.Sum(pkix)
+
+	for _, fingerprint := range []string{colonHex(created[:]), colonHex(imported[:])} {
+		if err := checkFingerprint(privateKey, fingerprint); err != nil {
+			t.Fatalf("fingerprint %s should match: %s", fingerprint, err)
+		}
+	}
+
+	_, otherKey := testPrivateKey(t)
+	if err := checkFingerprint(otherKey, colonHex(created[:])); err == nil {
+		t.Fatal("fingerprint of another key should not match")
+	}
+}
@@ -0,0 +1,71 @@
+package keypair
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fingerprints returns the fingerprints the Outscale API may report for the
+// keypair of the given private key: the SHA-1 of the PKCS#8 private key for
+// keypairs created by Outscale, the MD5 of the DER public key for imported
+// keypairs, and the MD5 of the SSH public key as printed by ssh-keygen.
+func fingerprints(privateKey []byte) ([]string, error) {
+	rawKey, err := ssh.ParseRawPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse private key: %s", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse private key: %s", err)
+	}
+
+	var fingerprints []string
+
+	if der, err := x509.MarshalPKCS8PrivateKey(rawKey); err == nil {
+		sum := sha1.Sum(der)
+		fingerprints = append(fingerprints, colonHex(sum[:]))
+	}
+
+	if cryptoPublicKey, ok := signer.PublicKey().(ssh.CryptoPublicKey); ok {
+		if der, err := x509.MarshalPKIXPublicKey(cryptoPublicKey.CryptoPublicKey()); err == nil {
+			sum := md5.Sum(der)
+			fingerprints = append(fingerprints, colonHex(sum[:]))
+		}
+	}
+
+	fingerprints = append(fingerprints, ssh.FingerprintLegacyMD5(signer.PublicKey()))
+
+	return fingerprints, nil
+}
+
+// checkFingerprint returns an error unless one of the fingerprints of the
+// private key is the expected one.
+func checkFingerprint(privateKey []byte, expected string) error {
+	candidates, err := fingerprints(privateKey)
+	if err != nil {
+		return err
+	}
+
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	for _, candidate := range candidates {
+		if candidate == expected {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("fingerprint mismatch, expected %s", expected)
+}
+
+func colonHex(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,93 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,Config
+
+// Package net contains a packersdk.Datasource implementation that looks up
+// an Outscale Net with the same filters as `net_filter`.
+package net
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig        `mapstructure:",squash"`
+	osccommon.AccessConfig     `mapstructure:",squash"`
+	osccommon.NetFilterOptions `mapstructure:",squash"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	ID               string            `mapstructure:"id"`
+	IpRange          string            `mapstructure:"ip_range"`
+	DhcpOptionsSetId string            `mapstructure:"dhcp_options_set_id"`
+	State            string            `mapstructure:"state"`
+	Tenancy          string            `mapstructure:"tenancy"`
+	Tags             map[string]string `mapstructure:"tags"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, d.config.AccessConfig.Prepare(nil)...)
+	errs = packersdk.MultiErrorAppend(errs, d.config.NameValueFilter.Prepare()...)
+
+	if d.config.Empty() {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("The `filters` must be specified"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.AccessKey, d.config.SecretKey, d.config.Token)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	oscConn, err := d.config.NewOSCClient()
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	net, err := d.config.NetFilterOptions.GetFilteredNet(oscConn)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := outputFromNet(net)
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func outputFromNet(net *osc.Net) DatasourceOutput {
+	return DatasourceOutput{
+		ID:               net.NetId,
+		IpRange:          net.IpRange,
+		DhcpOptionsSetId: net.DhcpOptionsSetId,
+		State:            net.State,
+		Tenancy:          net.Tenancy,
+		Tags:             osccommon.TagMapFromOSCTags(net.Tags),
+	}
+}
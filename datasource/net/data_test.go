@@ -0,0 +1,28 @@
+package net
+
+import (
+	"testing"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func TestDatasourceConfigure_FilterBlank(t *testing.T) {
+	datasource := Datasource{
+		config: Config{},
+	}
+	if err := datasource.Configure(nil); err == nil {
+		t.Fatalf("Should error if filters map is empty or not specified")
+	}
+}
+
+func TestDatasourceOutput(t *testing.T) {
+	output := outputFromNet(&osc.Net{
+		NetId:   "vpc-12345678",
+		IpRange: "10.0.0.0/16",
+		Tags:    []osc.ResourceTag{{Key: "env", Value: "ci"}},
+	})
+
+	if output.ID != "vpc-12345678" || output.IpRange != "10.0.0.0/16" || output.Tags["env"] != "ci" {
+		t.Fatalf("bad output: %#v", output)
+	}
+}
@@ -0,0 +1,132 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,BlockDeviceMappingOutput,Config
+
+// Package omi contains a packersdk.Datasource implementation that looks up
+// an Outscale OMI with the same filters as `source_omi_filter`.
+package omi
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig        `mapstructure:",squash"`
+	osccommon.AccessConfig     `mapstructure:",squash"`
+	osccommon.OmiFilterOptions `mapstructure:",squash"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+// BlockDeviceMappingOutput describes a block device of the OMI.
+type BlockDeviceMappingOutput struct {
+	DeviceName         string `mapstructure:"device_name"`
+	VirtualDeviceName  string `mapstructure:"virtual_device_name"`
+	SnapshotId         string `mapstructure:"snapshot_id"`
+	VolumeSize         int64  `mapstructure:"volume_size"`
+	VolumeType         string `mapstructure:"volume_type"`
+	Iops               int64  `mapstructure:"iops"`
+	DeleteOnVmDeletion bool   `mapstructure:"delete_on_vm_deletion"`
+}
+
+type DatasourceOutput struct {
+	ID                  string                     `mapstructure:"id"`
+	Name                string                     `mapstructure:"name"`
+	Description         string                     `mapstructure:"description"`
+	CreationDate        string                     `mapstructure:"creation_date"`
+	AccountId           string                     `mapstructure:"account_id"`
+	AccountAlias        string                     `mapstructure:"account_alias"`
+	Architecture        string                     `mapstructure:"architecture"`
+	State               string                     `mapstructure:"state"`
+	RootDeviceName      string                     `mapstructure:"root_device_name"`
+	RootDeviceType      string                     `mapstructure:"root_device_type"`
+	Tags                map[string]string          `mapstructure:"tags"`
+	BlockDeviceMappings []BlockDeviceMappingOutput `mapstructure:"block_device_mappings"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, d.config.AccessConfig.Prepare(nil)...)
+	errs = packersdk.MultiErrorAppend(errs, d.config.NameValueFilter.Prepare()...)
+
+	if d.config.Empty() {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("The `filters` must be specified"))
+	}
+	if d.config.NoOwner() {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("For security reasons, you must declare an owner."))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.AccessKey, d.config.SecretKey, d.config.Token)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	oscConn, err := d.config.NewOSCClient()
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	image, err := d.config.OmiFilterOptions.GetFilteredImage(osc.ReadImagesRequest{}, oscConn)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := outputFromImage(image)
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func outputFromImage(image *osc.Image) DatasourceOutput {
+	blockDeviceMappings := make([]BlockDeviceMappingOutput, 0, len(image.BlockDeviceMappings))
+	for _, mapping := range image.BlockDeviceMappings {
+		blockDeviceMappings = append(blockDeviceMappings, BlockDeviceMappingOutput{
+			DeviceName:         mapping.DeviceName,
+			VirtualDeviceName:  mapping.VirtualDeviceName,
+			SnapshotId:         mapping.Bsu.SnapshotId,
+			VolumeSize:         int64(mapping.Bsu.VolumeSize),
+			VolumeType:         mapping.Bsu.VolumeType,
+			Iops:               int64(mapping.Bsu.Iops),
+			DeleteOnVmDeletion: mapping.Bsu.DeleteOnVmDeletion,
+		})
+	}
+
+	return DatasourceOutput{
+		ID:                  image.ImageId,
+		Name:                image.ImageName,
+		Description:         image.Description,
+		CreationDate:        image.CreationDate,
+		AccountId:           image.AccountId,
+		AccountAlias:        image.AccountAlias,
+		Architecture:        image.Architecture,
+		State:               image.State,
+		RootDeviceName:      image.RootDeviceName,
+		RootDeviceType:      image.RootDeviceType,
+		Tags:                osccommon.TagMapFromOSCTags(image.Tags),
+		BlockDeviceMappings: blockDeviceMappings,
+	}
+}
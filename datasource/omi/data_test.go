@@ -0,0 +1,81 @@
+package omi
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	"github.com/outscale/osc-sdk-go/osc"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDatasourceConfigure_FilterBlank(t *testing.T) {
+	datasource := Datasource{
+		config: Config{},
+	}
+	if err := datasource.Configure(nil); err == nil {
+		t.Fatalf("Should error if filters map is empty or not specified")
+	}
+}
+
+func TestDatasourceConfigure_NoOwner(t *testing.T) {
+	datasource := Datasource{}
+	err := datasource.Configure(map[string]interface{}{
+		"filters": map[string]string{
+			"image-name": "ubuntu",
+		},
+	})
+	if err == nil {
+		t.Fatalf("Should error if owners are not specified")
+	}
+}
+
+func TestDatasourceConfigure(t *testing.T) {
+	datasource := Datasource{}
+	err := datasource.Configure(map[string]interface{}{
+		"filters": map[string]string{
+			"image-name": "ubuntu",
+		},
+		"owners":      []string{"Outscale"},
+		"most_recent": true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !datasource.config.MostRecent {
+		t.Fatal("most_recent should be set")
+	}
+}
+
+func TestDatasourceOutput(t *testing.T) {
+	datasource := Datasource{}
+	output := outputFromImage(&osc.Image{
+		ImageId:        "ami-12345678",
+		ImageName:      "ubuntu",
+		CreationDate:   "2021-01-01T00:00:00.000Z",
+		RootDeviceName: "/dev/sda1",
+		RootDeviceType: "bsu",
+		Tags:           []osc.ResourceTag{{Key: "os", Value: "ubuntu"}},
+		BlockDeviceMappings: []osc.BlockDeviceMappingImage{
+			{
+				DeviceName: "/dev/sda1",
+				Bsu: osc.BsuToCreate{
+					SnapshotId: "snap-12345678",
+					VolumeSize: 10,
+					VolumeType: "gp2",
+				},
+			},
+		},
+	})
+
+	value := hcl2helper.HCL2ValueFromConfig(output, datasource.OutputSpec())
+	if got := value.GetAttr("id").AsString(); got != "ami-12345678" {
+		t.Fatalf("bad id: %s", got)
+	}
+	if got := value.GetAttr("tags").Index(cty.StringVal("os")).AsString(); got != "ubuntu" {
+		t.Fatalf("bad tags: %s", got)
+	}
+	mappings := value.GetAttr("block_device_mappings").AsValueSlice()
+	if len(mappings) != 1 || mappings[0].GetAttr("snapshot_id").AsString() != "snap-12345678" {
+		t.Fatalf("bad block device mappings: %#v", mappings)
+	}
+}
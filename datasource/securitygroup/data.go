@@ -0,0 +1,110 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,SecurityGroupOutput,Config
+
+// Package securitygroup contains a packersdk.Datasource implementation that
+// looks up Outscale Security Groups with the same filters as
+// `security_group_filter`.
+package securitygroup
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig                  `mapstructure:",squash"`
+	osccommon.AccessConfig               `mapstructure:",squash"`
+	osccommon.SecurityGroupFilterOptions `mapstructure:",squash"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+// SecurityGroupOutput describes one of the matching Security Groups.
+type SecurityGroupOutput struct {
+	ID          string            `mapstructure:"id"`
+	Name        string            `mapstructure:"name"`
+	Description string            `mapstructure:"description"`
+	NetId       string            `mapstructure:"net_id"`
+	AccountId   string            `mapstructure:"account_id"`
+	Tags        map[string]string `mapstructure:"tags"`
+}
+
+type DatasourceOutput struct {
+	IDs            []string              `mapstructure:"ids"`
+	SecurityGroups []SecurityGroupOutput `mapstructure:"security_groups"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, d.config.AccessConfig.Prepare(nil)...)
+	errs = packersdk.MultiErrorAppend(errs, d.config.NameValueFilter.Prepare()...)
+
+	if d.config.Empty() {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("The `filters` must be specified"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.AccessKey, d.config.SecretKey, d.config.Token)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	oscConn, err := d.config.NewOSCClient()
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	securityGroups, err := d.config.SecurityGroupFilterOptions.GetFilteredSecurityGroups(oscConn)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := outputFromSecurityGroups(securityGroups)
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func outputFromSecurityGroups(securityGroups []osc.SecurityGroup) DatasourceOutput {
+	output := DatasourceOutput{
+		IDs:            make([]string, 0, len(securityGroups)),
+		SecurityGroups: make([]SecurityGroupOutput, 0, len(securityGroups)),
+	}
+
+	for _, sg := range securityGroups {
+		output.IDs = append(output.IDs, sg.SecurityGroupId)
+		output.SecurityGroups = append(output.SecurityGroups, SecurityGroupOutput{
+			ID:          sg.SecurityGroupId,
+			Name:        sg.SecurityGroupName,
+			Description: sg.Description,
+			NetId:       sg.NetId,
+			AccountId:   sg.AccountId,
+			Tags:        osccommon.TagMapFromOSCTags(sg.Tags),
+		})
+	}
+
+	return output
+}
@@ -0,0 +1,27 @@
+package securitygroup
+
+import (
+	"testing"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func TestDatasourceConfigure_FilterBlank(t *testing.T) {
+	datasource := Datasource{
+		config: Config{},
+	}
+	if err := datasource.Configure(nil); err == nil {
+		t.Fatalf("Should error if filters map is empty or not specified")
+	}
+}
+
+func TestDatasourceOutput(t *testing.T) {
+	output := outputFromSecurityGroups([]osc.SecurityGroup{
+		{SecurityGroupId: "sg-1", SecurityGroupName: "ssh"},
+		{SecurityGroupId: "sg-2", SecurityGroupName: "web"},
+	})
+
+	if len(output.IDs) != 2 || output.IDs[1] != "sg-2" || output.SecurityGroups[0].Name != "ssh" {
+		t.Fatalf("bad output: %#v", output)
+	}
+}
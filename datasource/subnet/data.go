@@ -0,0 +1,103 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,Config
+
+// Package subnet contains a packersdk.Datasource implementation that looks up
+// an Outscale Subnet with the same filters as `subnet_filter`.
+package subnet
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig           `mapstructure:",squash"`
+	osccommon.AccessConfig        `mapstructure:",squash"`
+	osccommon.SubnetFilterOptions `mapstructure:",squash"`
+	NetId                         string `mapstructure:"net_id"`
+	SubregionName                 string `mapstructure:"subregion_name"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	ID                  string            `mapstructure:"id"`
+	NetId               string            `mapstructure:"net_id"`
+	IpRange             string            `mapstructure:"ip_range"`
+	SubregionName       string            `mapstructure:"subregion_name"`
+	AvailableIpsCount   int64             `mapstructure:"available_ips_count"`
+	MapPublicIpOnLaunch bool              `mapstructure:"map_public_ip_on_launch"`
+	State               string            `mapstructure:"state"`
+	Tags                map[string]string `mapstructure:"tags"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, d.config.AccessConfig.Prepare(nil)...)
+	errs = packersdk.MultiErrorAppend(errs, d.config.NameValueFilter.Prepare()...)
+
+	if d.config.Empty() && d.config.NetId == "" && d.config.SubregionName == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("One of `filters`, `net_id` or `subregion_name` must be specified"))
+	}
+
+	if d.config.MostFree && d.config.Random {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Only one of `most_free` or `random` can be specified"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(d.config.AccessKey, d.config.SecretKey, d.config.Token)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	oscConn, err := d.config.NewOSCClient()
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	subnet, err := d.config.SubnetFilterOptions.GetFilteredSubnet(d.config.NetId, d.config.SubregionName, oscConn)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := outputFromSubnet(subnet)
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func outputFromSubnet(subnet *osc.Subnet) DatasourceOutput {
+	return DatasourceOutput{
+		ID:                  subnet.SubnetId,
+		NetId:               subnet.NetId,
+		IpRange:             subnet.IpRange,
+		SubregionName:       subnet.SubregionName,
+		AvailableIpsCount:   int64(subnet.AvailableIpsCount),
+		MapPublicIpOnLaunch: subnet.MapPublicIpOnLaunch,
+		State:               subnet.State,
+		Tags:                osccommon.TagMapFromOSCTags(subnet.Tags),
+	}
+}
@@ -0,0 +1,42 @@
+package subnet
+
+import (
+	"testing"
+
+	"github.com/outscale/osc-sdk-go/osc"
+)
+
+func TestDatasourceConfigure_FilterBlank(t *testing.T) {
+	datasource := Datasource{
+		config: Config{},
+	}
+	if err := datasource.Configure(nil); err == nil {
+		t.Fatalf("Should error if filters map is empty or not specified")
+	}
+}
+
+func TestDatasourceConfigure_MostFreeAndRandom(t *testing.T) {
+	datasource := Datasource{}
+	err := datasource.Configure(map[string]interface{}{
+		"net_id":    "vpc-12345678",
+		"most_free": true,
+		"random":    true,
+	})
+	if err == nil {
+		t.Fatalf("Should error if both most_free and random are set")
+	}
+}
+
+func TestDatasourceOutput(t *testing.T) {
+	output := outputFromSubnet(&osc.Subnet{
+		SubnetId:          "subnet-12345678",
+		NetId:             "vpc-12345678",
+		IpRange:           "10.0.1.0/24",
+		SubregionName:     "eu-west-2a",
+		AvailableIpsCount: 250,
+	})
+
+	if output.ID != "subnet-12345678" || output.SubregionName != "eu-west-2a" || output.AvailableIpsCount != 250 {
+		t.Fatalf("bad output: %#v", output)
+	}
+}
@@ -10,6 +10,13 @@ import (
 	"github.com/outscale/packer-plugin-outscale/builder/osc/bsusurrogate"
 	"github.com/outscale/packer-plugin-outscale/builder/osc/bsuvolume"
 	"github.com/outscale/packer-plugin-outscale/builder/osc/chroot"
+	"github.com/outscale/packer-plugin-outscale/datasource/keypair"
+	"github.com/outscale/packer-plugin-outscale/datasource/net"
+	"github.com/outscale/packer-plugin-outscale/datasource/omi"
+	"github.com/outscale/packer-plugin-outscale/datasource/securitygroup"
+	"github.com/outscale/packer-plugin-outscale/datasource/subnet"
+	oscexport "github.com/outscale/packer-plugin-outscale/post-processor/osc-export"
+	oscimport "github.com/outscale/packer-plugin-outscale/post-processor/osc-import"
 	"github.com/outscale/packer-plugin-outscale/version"
 )
 
@@ -20,6 +27,13 @@ func main() {
 	pps.RegisterBuilder("chroot", new(chroot.Builder))
 	pps.RegisterBuilder("bsusurrogate", new(bsusurrogate.Builder))
 	pps.RegisterBuilder("bsuvolume", new(bsuvolume.Builder))
+	pps.RegisterDatasource("omi", new(omi.Datasource))
+	pps.RegisterDatasource("net", new(net.Datasource))
+	pps.RegisterDatasource("subnet", new(subnet.Datasource))
+	pps.RegisterDatasource("security-group", new(securitygroup.Datasource))
+	pps.RegisterDatasource("keypair", new(keypair.Datasource))
+	pps.RegisterPostProcessor("import", new(oscimport.PostProcessor))
+	pps.RegisterPostProcessor("export", new(oscexport.PostProcessor))
 	err := pps.Run()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
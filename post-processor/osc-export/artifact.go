@@ -0,0 +1,90 @@
+package oscexport
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+)
+
+// Artifact is an artifact implementation that contains the objects of the
+// exported OMIs.
+type Artifact struct {
+	// A map of regions to the bucket the OMI was exported to.
+	Buckets map[string]string
+
+	// A map of regions to the keys of the exported objects.
+	Keys map[string][]string
+
+	accessConfig *osccommon.AccessConfig
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	// The objects are not local files
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	parts := make([]string, 0, len(a.Keys))
+	for region, keys := range a.Keys {
+		for _, key := range keys {
+			parts = append(parts, fmt.Sprintf("%s:%s/%s", region, a.Buckets[region], key))
+		}
+	}
+
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (a *Artifact) String() string {
+	objects := make([]string, 0, len(a.Keys))
+	for region, keys := range a.Keys {
+		for _, key := range keys {
+			objects = append(objects, fmt.Sprintf("%s: s3://%s/%s", region, a.Buckets[region], key))
+		}
+	}
+
+	sort.Strings(objects)
+	return fmt.Sprintf("OMIs were exported:\n%s\n", strings.Join(objects, "\n"))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return nil
+}
+
+// Destroy deletes the exported objects.
+func (a *Artifact) Destroy() error {
+	var errs *packersdk.MultiError
+	for region, keys := range a.Keys {
+		sess, err := a.accessConfig.NewOOSSessionByRegion(region)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+			continue
+		}
+		conn := s3.New(sess)
+
+		for _, key := range keys {
+			log.Printf("Deleting s3://%s/%s from region (%s)", a.Buckets[region], key, region)
+			if _, err := conn.DeleteObject(&s3.DeleteObjectInput{
+				Bucket: aws.String(a.Buckets[region]),
+				Key:    aws.String(key),
+			}); err != nil {
+				errs = packersdk.MultiErrorAppend(errs, err)
+			}
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package oscexport
+
+import (
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestArtifact_Impl(t *testing.T) {
+	var _ packersdk.Artifact = new(Artifact)
+}
+
+func TestArtifactId(t *testing.T) {
+	a := &Artifact{
+		Buckets: map[string]string{
+			"eu-west-2": "archive-eu",
+			"us-east-2": "archive-us",
+		},
+		Keys: map[string][]string{
+			"us-east-2": {"ami-67890.qcow2"},
+			"eu-west-2": {"ami-12345.qcow2"},
+		},
+	}
+
+	expected := "eu-west-2:archive-eu/ami-12345.qcow2,us-east-2:archive-us/ami-67890.qcow2"
+	if result := a.Id(); result != expected {
+		t.Fatalf("bad: %s", result)
+	}
+}
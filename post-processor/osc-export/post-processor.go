@@ -0,0 +1,183 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+// Package oscexport contains a packersdk.PostProcessor implementation that
+// exports the OMIs built by the Outscale builders to OOS buckets as disk
+// images.
+package oscexport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/bsu"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/bsusurrogate"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/chroot"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	oscimport "github.com/outscale/packer-plugin-outscale/post-processor/osc-import"
+)
+
+// The unique ID for this post-processor
+const BuilderId = "oapi.outscale.export"
+
+var supportedFormats = []string{"qcow2", "raw"}
+
+// Config is the configuration of the post-processor.
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	osccommon.AccessConfig `mapstructure:",squash"`
+
+	S3Bucket  string            `mapstructure:"s3_bucket_name"`
+	S3Buckets map[string]string `mapstructure:"s3_bucket_names"`
+	S3Prefix  string            `mapstructure:"s3_prefix"`
+	Format    string            `mapstructure:"format"`
+
+	PollingConfig osccommon.PollingConfig `mapstructure:"osc_polling"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+	runner multistep.Runner
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	p.config.ctx.Funcs = osccommon.TemplateFuncs
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.Format == "" {
+		p.config.Format = "qcow2"
+	}
+
+	// Accumulate any errors
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, p.config.AccessConfig.Prepare(&p.config.ctx)...)
+	errs = packersdk.MultiErrorAppend(errs, p.config.PollingConfig.Prepare()...)
+
+	// The export task writes to the bucket with an access and a secret key,
+	// without a session token.
+	if p.config.Token != "" || p.config.MFACode != "" {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("token and mfa_code cannot be used: the export task needs an access key and a secret key"))
+	}
+
+	if p.config.S3Bucket == "" && len(p.config.S3Buckets) == 0 {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("s3_bucket_name or s3_bucket_names must be specified"))
+	}
+
+	valid := false
+	for _, format := range supportedFormats {
+		valid = valid || format == p.config.Format
+	}
+	if !valid {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("format must be one of %v, got %q", supportedFormats, p.config.Format))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.AccessKey, p.config.SecretKey, p.config.Token)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	omis, err := omisFromArtifact(artifact)
+	if err != nil {
+		return nil, true, false, err
+	}
+
+	buckets := make(map[string]string, len(omis))
+	for region := range omis {
+		bucket, ok := p.config.S3Buckets[region]
+		if !ok {
+			bucket = p.config.S3Bucket
+		}
+		if bucket == "" {
+			return nil, true, false, fmt.Errorf("No bucket set in s3_bucket_names for region %s", region)
+		}
+		buckets[region] = bucket
+	}
+
+	oscConn, err := p.config.NewOSCClient()
+	if err != nil {
+		return nil, true, false, err
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("osc", oscConn)
+	state.Put("accessConfig", &p.config.AccessConfig)
+	state.Put("ui", ui)
+
+	steps := []multistep.Step{
+		&stepExportOMI{
+			AccessConfig:  &p.config.AccessConfig,
+			Omis:          omis,
+			Buckets:       buckets,
+			Prefix:        p.config.S3Prefix,
+			Format:        p.config.Format,
+			PollingConfig: &p.config.PollingConfig,
+		},
+	}
+
+	p.runner = commonsteps.NewRunner(steps, p.config.PackerConfig, ui)
+	p.runner.Run(ctx, state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, true, false, rawErr.(error)
+	}
+
+	// The exported OMIs are always kept: exporting them is not a reason to
+	// deregister them.
+	return &Artifact{
+		Buckets:      buckets,
+		Keys:         state.Get("export_keys").(map[string][]string),
+		accessConfig: &p.config.AccessConfig,
+	}, true, true, nil
+}
+
+// omisFromArtifact returns the OMI ID per region of an artifact produced by
+// one of the builders of this plugin, or by the import post-processor.
+func omisFromArtifact(artifact packersdk.Artifact) (map[string]string, error) {
+	switch artifact.BuilderId() {
+	case bsu.BuilderId, bsusurrogate.BuilderId, chroot.BuilderId, oscimport.BuilderId:
+	default:
+		return nil, fmt.Errorf("Unknown artifact type: %s\nCan only export OMIs built by the Outscale builders.", artifact.BuilderId())
+	}
+
+	// Artifacts reach post-processors over RPC, so they usually are not
+	// *osccommon.Artifact: the OMIs are read back from the artifact ID.
+	if a, ok := artifact.(*osccommon.Artifact); ok {
+		return a.Omis, nil
+	}
+
+	omis := make(map[string]string)
+	for _, part := range strings.Split(artifact.Id(), ",") {
+		region, omi, ok := strings.Cut(part, ":")
+		if !ok || region == "" || omi == "" {
+			return nil, fmt.Errorf("Malformed artifact ID: %s", artifact.Id())
+		}
+		omis[region] = omi
+	}
+	return omis, nil
+}
@@ -0,0 +1,89 @@
+package oscexport
+
+import (
+	"reflect"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/bsu"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"access_key":     "foo",
+		"secret_key":     "bar",
+		"region":         "eu-west-2",
+		"s3_bucket_name": "packer-export",
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.Format != "qcow2" {
+		t.Fatalf("bad default format: %s", p.config.Format)
+	}
+
+	config := testConfig()
+	delete(config, "s3_bucket_name")
+	if err := new(PostProcessor).Configure(config); err == nil {
+		t.Fatal("should error without a bucket")
+	}
+
+	config["s3_bucket_names"] = map[string]string{"eu-west-2": "packer-export"}
+	if err := new(PostProcessor).Configure(config); err != nil {
+		t.Fatalf("s3_bucket_names should be enough, got: %s", err)
+	}
+
+	config = testConfig()
+	config["format"] = "vmdk"
+	if err := new(PostProcessor).Configure(config); err == nil {
+		t.Fatal("should error with an unsupported format")
+	}
+
+	config = testConfig()
+	config["token"] = "TOKEN"
+	if err := new(PostProcessor).Configure(config); err == nil {
+		t.Fatal("should error with temporary credentials")
+	}
+}
+
+func TestOmisFromArtifact(t *testing.T) {
+	expected := map[string]string{"eu-west-2": "ami-12345", "us-east-2": "ami-67890"}
+
+	omis, err := omisFromArtifact(&osccommon.Artifact{
+		Omis:           expected,
+		BuilderIdValue: bsu.BuilderId,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(omis, expected) {
+		t.Fatalf("bad omis: %#v", omis)
+	}
+
+	omis, err = omisFromArtifact(&packersdk.MockArtifact{
+		BuilderIdValue: bsu.BuilderId,
+		IdValue:        "eu-west-2:ami-12345,us-east-2:ami-67890",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(omis, expected) {
+		t.Fatalf("bad omis: %#v", omis)
+	}
+
+	if _, err := omisFromArtifact(&packersdk.MockArtifact{
+		BuilderIdValue: "oapi.outscale.bsuvolume",
+		IdValue:        "eu-west-2:vol-12345",
+	}); err == nil {
+		t.Fatal("should error on an artifact without OMIs")
+	}
+}
@@ -0,0 +1,176 @@
+package oscexport
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+)
+
+// exportClockSkew is the margin allowed between the local clock and the one of
+// OOS when telling the objects of this export from older ones.
+const exportClockSkew = time.Minute
+
+// stepExportOMI exports the OMI of every region to the bucket of that region
+// and waits for the export tasks to complete.
+//
+// Produces:
+//
+//	export_keys map[string][]string - the keys of the exported objects per region
+type stepExportOMI struct {
+	AccessConfig  *osccommon.AccessConfig
+	Omis          map[string]string
+	Buckets       map[string]string
+	Prefix        string
+	Format        string
+	PollingConfig *osccommon.PollingConfig
+
+	lock sync.Mutex
+}
+
+func (s *stepExportOMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+
+	var (
+		errs *packersdk.MultiError
+		wg   sync.WaitGroup
+	)
+	// The buckets are checked before any export starts, so that no OMI is
+	// exported when the export of another one cannot be.
+	for region := range s.Omis {
+		if s.Buckets[region] == "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("%s: No bucket set for the region", region))
+		}
+	}
+	if errs != nil && len(errs.Errors) > 0 {
+		state.Put("error", errs)
+		ui.Error(errs.Error())
+		return multistep.ActionHalt
+	}
+
+	keys := make(map[string][]string)
+	for region, omi := range s.Omis {
+		bucket := s.Buckets[region]
+
+		wg.Add(1)
+		ui.Say(fmt.Sprintf("Exporting OMI (%s) from %s to bucket %s...", omi, region, bucket))
+
+		go func(region, omi, bucket string) {
+			defer wg.Done()
+			regionKeys, err := s.exportOMI(ctx, region, omi, bucket)
+
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			if err != nil {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("%s: %s", region, err))
+				return
+			}
+			keys[region] = regionKeys
+		}(region, omi, bucket)
+	}
+
+	ui.Say("Waiting for all exports to complete...")
+	wg.Wait()
+
+	if errs != nil && len(errs.Errors) > 0 {
+		state.Put("error", errs)
+		ui.Error(errs.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("export_keys", keys)
+	return multistep.ActionContinue
+}
+
+// exportOMI runs the export task of the OMI to the bucket and returns the keys
+// of the objects it wrote.
+func (s *stepExportOMI) exportOMI(ctx context.Context, region, omi, bucket string) ([]string, error) {
+	regionconn := s.AccessConfig.NewOSCClientByRegion(region)
+
+	// Allow for the clock skew with OOS, the dates of its objects being
+	// compared with this one.
+	started := time.Now().Add(-exportClockSkew)
+	resp, _, err := regionconn.ImageApi.CreateImageExportTask(ctx, &osc.CreateImageExportTaskOpts{
+		CreateImageExportTaskRequest: optional.NewInterface(osc.CreateImageExportTaskRequest{
+			ImageId: omi,
+			OsuExport: osc.OsuExportToCreate{
+				DiskImageFormat: s.Format,
+				OsuBucket:       bucket,
+				OsuPrefix:       s.Prefix,
+				OsuApiKey: osc.OsuApiKey{
+					ApiKeyId:  s.AccessConfig.AccessKey,
+					SecretKey: s.AccessConfig.SecretKey,
+				},
+			},
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error exporting OMI (%s): %s", omi, osccommon.DecodeError(err))
+	}
+	taskId := resp.ImageExportTask.TaskId
+
+	if err := s.PollingConfig.WaitUntilOscImageExportTaskCompleted(ctx, regionconn, taskId); err != nil {
+		return nil, fmt.Errorf("Error waiting for the export of OMI (%s): %s", omi, err)
+	}
+
+	sess, err := s.AccessConfig.NewOOSSessionByRegion(region)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating the OOS session: %s", err)
+	}
+
+	// The export task does not return the keys it wrote; they are the
+	// objects under the prefix named after the OMI.
+	word := omiWord(omi)
+	var keys []string
+	err = s3.New(sess).ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(s.Prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			if isExportedObject(object, s.Prefix, word, started) {
+				keys = append(keys, aws.StringValue(object.Key))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing the objects of bucket %s: %s", bucket, err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("No object exported for OMI (%s) found in bucket %s", omi, bucket)
+	}
+
+	return keys, nil
+}
+
+// omiWord returns the expression matching the OMI ID as a whole word, so that
+// `ami-1234` does not match the exports of `ami-12345`.
+func omiWord(omi string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|[^a-z0-9])` + regexp.QuoteMeta(omi) + `([^a-z0-9]|$)`)
+}
+
+// isExportedObject tells whether an object listed under the prefix has been
+// written by the export of the OMI started at the given time. Its key must
+// hold the OMI ID, as matched by omiWord, and it must not predate the export,
+// which rules out the objects left by earlier exports under a shared prefix.
+func isExportedObject(object *s3.Object, prefix string, word *regexp.Regexp, started time.Time) bool {
+	key := aws.StringValue(object.Key)
+	if len(key) < len(prefix) || aws.TimeValue(object.LastModified).Before(started) {
+		return false
+	}
+
+	return word.MatchString(key[len(prefix):])
+}
+
+func (s *stepExportOMI) Cleanup(state multistep.StateBag) {
+	// No cleanup...
+}
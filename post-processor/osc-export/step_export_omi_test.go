@@ -0,0 +1,54 @@
+package oscexport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepExportOMI_missingBucket(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packersdk.TestUi(t))
+
+	step := &stepExportOMI{
+		Omis:    map[string]string{"us-east-2": "ami-12345", "eu-west-2": "ami-67890"},
+		Buckets: map[string]string{"eu-west-2": "packer-export"},
+	}
+	// No export is started, the step would otherwise need an access config.
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("should halt, got %v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should error without a bucket for the region")
+	}
+}
+
+func TestIsExportedObject(t *testing.T) {
+	started := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	object := func(key string, modified time.Time) *s3.Object {
+		return &s3.Object{Key: aws.String(key), LastModified: aws.Time(modified)}
+	}
+
+	cases := []struct {
+		object   *s3.Object
+		expected bool
+	}{
+		{object("golden/ami-12345.qcow2", started.Add(time.Hour)), true},
+		{object("golden/export/ami-12345", started.Add(time.Hour)), true},
+		{object("golden/ami-123456.qcow2", started.Add(time.Hour)), false},
+		{object("golden/xami-12345.qcow2", started.Add(time.Hour)), false},
+		{object("golden/ami-12345.qcow2", started.Add(-time.Hour)), false},
+		{object("golden/ami-67890.qcow2", started.Add(time.Hour)), false},
+	}
+	word := omiWord("ami-12345")
+	for _, c := range cases {
+		if got := isExportedObject(c.object, "golden/", word, started); got != c.expected {
+			t.Errorf("%s, modified %s: expected %t, got %t", *c.object.Key, *c.object.LastModified, c.expected, got)
+		}
+	}
+}
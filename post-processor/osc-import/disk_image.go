@@ -0,0 +1,96 @@
+package oscimport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var supportedFormats = []string{"qcow2", "raw", "vmdk"}
+
+// formatExtensions maps the file extensions to the disk image formats.
+var formatExtensions = map[string]string{
+	".qcow2": "qcow2",
+	".raw":   "raw",
+	".img":   "raw",
+	".vmdk":  "vmdk",
+}
+
+func isSupportedFormat(format string) bool {
+	for _, f := range supportedFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// diskImage is the local disk image to import.
+type diskImage struct {
+	Path   string
+	Format string
+}
+
+// findDiskImage returns the disk image to import among the files of an
+// artifact. When the format is set, a single file is taken as is; otherwise
+// the format is inferred from the file extension.
+func findDiskImage(files []string, format string) (*diskImage, error) {
+	if format != "" && len(files) == 1 {
+		return &diskImage{Path: files[0], Format: format}, nil
+	}
+
+	for _, path := range files {
+		f, ok := formatExtensions[strings.ToLower(filepath.Ext(path))]
+		if !ok || (format != "" && f != format) {
+			continue
+		}
+		return &diskImage{Path: path, Format: f}, nil
+	}
+
+	return nil, fmt.Errorf("No disk image in one of the %v formats found in the artifact files: %v", supportedFormats, files)
+}
+
+// virtualSize returns the size of the disk once imported, in bytes, which is
+// the minimum size of the snapshot.
+func (d *diskImage) virtualSize() (int64, error) {
+	f, err := os.Open(d.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	switch d.Format {
+	case "qcow2":
+		// QCOW2 header: magic (4), version (4), backing file offset (8),
+		// backing file size (4), cluster bits (4), size (8), big-endian.
+		header := make([]byte, 32)
+		if _, err := io.ReadFull(f, header); err != nil {
+			return 0, fmt.Errorf("Error reading the qcow2 header of %s: %s", d.Path, err)
+		}
+		if !bytes.Equal(header[:4], []byte{'Q', 'F', 'I', 0xfb}) {
+			return 0, fmt.Errorf("%s is not a qcow2 image", d.Path)
+		}
+		return int64(binary.BigEndian.Uint64(header[24:32])), nil
+	case "vmdk":
+		// Sparse extent header: magic (4), version (4), flags (4), capacity
+		// in sectors (8), little-endian.
+		header := make([]byte, 20)
+		if _, err := io.ReadFull(f, header); err != nil {
+			return 0, fmt.Errorf("Error reading the vmdk header of %s: %s", d.Path, err)
+		}
+		if !bytes.Equal(header[:4], []byte("KDMV")) {
+			return 0, fmt.Errorf("%s is not a sparse or stream-optimized vmdk image", d.Path)
+		}
+		return int64(binary.LittleEndian.Uint64(header[12:20])) * 512, nil
+	default:
+		info, err := f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return info.Size(), nil
+	}
+}
@@ -0,0 +1,82 @@
+package oscimport
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindDiskImage(t *testing.T) {
+	cases := []struct {
+		files  []string
+		format string
+		path   string
+		want   string
+	}{
+		{[]string{"output/disk.qcow2"}, "", "output/disk.qcow2", "qcow2"},
+		{[]string{"output/disk.IMG"}, "", "output/disk.IMG", "raw"},
+		{[]string{"output/disk.ovf", "output/disk-1.vmdk"}, "", "output/disk-1.vmdk", "vmdk"},
+		{[]string{"output/disk"}, "raw", "output/disk", "raw"},
+		{[]string{"output/a.raw", "output/b.qcow2"}, "qcow2", "output/b.qcow2", "qcow2"},
+	}
+
+	for _, tc := range cases {
+		image, err := findDiskImage(tc.files, tc.format)
+		if err != nil {
+			t.Fatalf("%v: err: %s", tc.files, err)
+		}
+		if image.Path != tc.path || image.Format != tc.want {
+			t.Fatalf("%v: got %#v", tc.files, image)
+		}
+	}
+
+	if _, err := findDiskImage([]string{"output/disk.ova"}, ""); err == nil {
+		t.Fatal("should error without a supported disk image")
+	}
+}
+
+func TestDiskImage_virtualSize(t *testing.T) {
+	dir := t.TempDir()
+
+	qcow2 := make([]byte, 512)
+	copy(qcow2, []byte{'Q', 'F', 'I', 0xfb})
+	binary.BigEndian.PutUint64(qcow2[24:], 10<<30)
+
+	vmdk := make([]byte, 512)
+	copy(vmdk, []byte("KDMV"))
+	binary.LittleEndian.PutUint64(vmdk[12:], 2097152)
+
+	cases := []struct {
+		format  string
+		content []byte
+		want    int64
+	}{
+		{"qcow2", qcow2, 10 << 30},
+		{"vmdk", vmdk, 1 << 30},
+		{"raw", make([]byte, 4096), 4096},
+	}
+
+	for _, tc := range cases {
+		path := filepath.Join(dir, "disk."+tc.format)
+		if err := os.WriteFile(path, tc.content, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		size, err := (&diskImage{Path: path, Format: tc.format}).virtualSize()
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.format, err)
+		}
+		if size != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.format, tc.want, size)
+		}
+	}
+
+	path := filepath.Join(dir, "bad.qcow2")
+	if err := os.WriteFile(path, make([]byte, 512), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&diskImage{Path: path, Format: "qcow2"}).virtualSize(); err == nil {
+		t.Fatal("should error on a bad qcow2 header")
+	}
+}
@@ -0,0 +1,213 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+// Package oscimport contains a packersdk.PostProcessor implementation that
+// imports the disk image produced by another builder, such as QEMU, as an
+// Outscale OMI.
+package oscimport
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+)
+
+// The unique ID for this post-processor
+const BuilderId = "oapi.outscale.import"
+
+// Config is the configuration of the post-processor.
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	osccommon.AccessConfig `mapstructure:",squash"`
+	osccommon.OMIConfig    `mapstructure:",squash"`
+
+	S3Bucket       string `mapstructure:"s3_bucket_name"`
+	S3Key          string `mapstructure:"s3_key_name"`
+	SkipClean      bool   `mapstructure:"skip_clean"`
+	Format         string `mapstructure:"format"`
+	Architecture   string `mapstructure:"architecture"`
+	RootDeviceName string `mapstructure:"root_device_name"`
+
+	PollingConfig osccommon.PollingConfig `mapstructure:"osc_polling"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+	runner multistep.Runner
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	p.config.ctx.Funcs = osccommon.TemplateFuncs
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"omi_description",
+				"omi_retention",
+				"s3_key_name",
+				"snapshot_tags",
+				"tags",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	// -force deregisters the existing OMIs, unless another policy is set.
+	if p.config.PackerConfig.PackerForce && p.config.OMINameConflict == "" {
+		p.config.OMIForceDeregister = true
+	}
+
+	if p.config.S3Key == "" {
+		p.config.S3Key = "packer-import-{{timestamp}}"
+	}
+
+	if p.config.Architecture == "" {
+		p.config.Architecture = "x86_64"
+	}
+
+	if p.config.RootDeviceName == "" {
+		p.config.RootDeviceName = "/dev/sda1"
+	}
+
+	// Accumulate any errors
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, p.config.AccessConfig.Prepare(&p.config.ctx)...)
+	errs = packersdk.MultiErrorAppend(errs, p.config.PollingConfig.Prepare()...)
+	errs = packersdk.MultiErrorAppend(errs,
+		p.config.OMIConfig.Prepare(&p.config.AccessConfig, &p.config.ctx)...)
+
+	if p.config.S3Bucket == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("s3_bucket_name must be specified"))
+	}
+
+	if err := interpolate.Validate(p.config.S3Key, &p.config.ctx); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error parsing s3_key_name template: %s", err))
+	}
+
+	if p.config.Format != "" && !isSupportedFormat(p.config.Format) {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("format must be one of %v, got %q", supportedFormats, p.config.Format))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.AccessKey, p.config.SecretKey, p.config.Token)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	image, err := findDiskImage(artifact.Files(), p.config.Format)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	p.config.ctx.Data = &struct{ Format string }{Format: image.Format}
+	key, err := interpolate.Render(p.config.S3Key, &p.config.ctx)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error rendering s3_key_name template: %s", err)
+	}
+
+	oscConn, err := p.config.NewOSCClient()
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("osc", oscConn)
+	state.Put("accessConfig", &p.config.AccessConfig)
+	state.Put("ui", ui)
+	state.Put("omis", make(map[string]string))
+	state.Put("snapshots", make(map[string][]string))
+
+	steps := []multistep.Step{
+		&osccommon.StepDeregisterOMI{
+			AccessConfig:        &p.config.AccessConfig,
+			PollingConfig:       &p.config.PollingConfig,
+			NameConflict:        p.config.OMINameConflict,
+			ForceDeleteSnapshot: p.config.OMIForceDeleteSnapshot,
+			OMIName:             p.config.OMIName,
+			Regions:             p.config.OMIRegions,
+		},
+		&stepUploadDiskImage{
+			AccessConfig: &p.config.AccessConfig,
+			Bucket:       p.config.S3Bucket,
+			Key:          key,
+			Image:        image,
+			SkipClean:    p.config.SkipClean,
+		},
+		&stepImportSnapshot{
+			Description:   fmt.Sprintf("Imported by Packer from %s", image.Path),
+			PollingConfig: &p.config.PollingConfig,
+		},
+		&stepRegisterOMI{
+			RawRegion:      p.config.RawRegion,
+			Name:           p.config.OMIName,
+			Description:    p.config.OMIDescription,
+			Architecture:   p.config.Architecture,
+			RootDeviceName: p.config.RootDeviceName,
+			PollingConfig:  &p.config.PollingConfig,
+		},
+		&osccommon.StepCopyOMI{
+			AccessConfig:  &p.config.AccessConfig,
+			Regions:       p.config.OMIRegions,
+			Name:          p.config.OMIName,
+			PollingConfig: &p.config.PollingConfig,
+		},
+		&osccommon.StepUpdateOMIAttributes{
+			AccountIds:         p.config.OMIAccountIDs,
+			SnapshotAccountIds: p.config.SnapshotAccountIDs,
+			Groups:             p.config.OMIGroups,
+			SnapshotGroups:     p.config.SnapshotGroups,
+			ProductCodes:       p.config.OMIProductCodes,
+			RawRegion:          p.config.RawRegion,
+			GlobalPermission:   p.config.GlobalPermission,
+			Ctx:                p.config.ctx,
+		},
+		&osccommon.StepCreateTags{
+			Tags:         p.config.OMITags,
+			SnapshotTags: p.config.SnapshotTags,
+			Ctx:          p.config.ctx,
+		},
+		&osccommon.StepOMIRetention{
+			AccessConfig: &p.config.AccessConfig,
+			Retention:    p.config.OMIRetention,
+			Ctx:          p.config.ctx,
+		},
+	}
+
+	p.runner = commonsteps.NewRunner(steps, p.config.PackerConfig, ui)
+	p.runner.Run(ctx, state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, false, false, rawErr.(error)
+	}
+
+	snapshots, _ := state.Get("snapshots").(map[string][]string)
+	artifact = &osccommon.Artifact{
+		Omis:           state.Get("omis").(map[string]string),
+		Snapshots:      snapshots,
+		Endpoint:       p.config.CustomEndpointOAPI,
+		AccessConfig:   &p.config.AccessConfig,
+		BuilderIdValue: BuilderId,
+	}
+
+	return artifact, false, false, nil
+}
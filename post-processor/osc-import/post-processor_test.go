@@ -0,0 +1,74 @@
+package oscimport
+
+import (
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"access_key":     "foo",
+		"secret_key":     "bar",
+		"region":         "eu-west-2",
+		"omi_name":       "foo",
+		"s3_bucket_name": "packer-import",
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packersdk.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_Configure_Defaults(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if p.config.S3Key != "packer-import-{{timestamp}}" {
+		t.Errorf("bad s3_key_name: %s", p.config.S3Key)
+	}
+	if p.config.Architecture != "x86_64" {
+		t.Errorf("bad architecture: %s", p.config.Architecture)
+	}
+	if p.config.RootDeviceName != "/dev/sda1" {
+		t.Errorf("bad root_device_name: %s", p.config.RootDeviceName)
+	}
+}
+
+func TestPostProcessor_Configure_NoBucket(t *testing.T) {
+	var p PostProcessor
+	config := testConfig()
+	delete(config, "s3_bucket_name")
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should error without s3_bucket_name")
+	}
+}
+
+func TestPostProcessor_Configure_BadFormat(t *testing.T) {
+	var p PostProcessor
+	config := testConfig()
+	config["format"] = "ova"
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should error with an unsupported format")
+	}
+}
+
+func TestPostProcessor_Configure_OMIRetention(t *testing.T) {
+	var p PostProcessor
+	config := testConfig()
+	config["omi_retention"] = map[string]interface{}{"name_prefix": "foo-", "keep_latest": 2}
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.OMIRetention == nil || p.config.OMIRetention.KeepLatest != 2 {
+		t.Fatalf("bad omi_retention: %#v", p.config.OMIRetention)
+	}
+
+	p = PostProcessor{}
+	config["omi_retention"] = map[string]interface{}{"name_prefix": "foo-"}
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should error without keep_latest or keep_younger_than")
+	}
+}
@@ -0,0 +1,80 @@
+package oscimport
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antihax/optional"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+)
+
+// stepImportSnapshot creates a snapshot from the uploaded disk image.
+//
+// Produces:
+//
+//	snapshot_id string - the ID of the imported snapshot
+type stepImportSnapshot struct {
+	Description   string
+	PollingConfig *osccommon.PollingConfig
+
+	snapshotId string
+}
+
+func (s *stepImportSnapshot) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	oscconn := state.Get("osc").(*osc.APIClient)
+	ui := state.Get("ui").(packersdk.Ui)
+	fileLocation := state.Get("file_location").(string)
+	size := state.Get("snapshot_size").(int64)
+
+	ui.Say("Importing the snapshot...")
+	resp, _, err := oscconn.SnapshotApi.CreateSnapshot(ctx, &osc.CreateSnapshotOpts{
+		CreateSnapshotRequest: optional.NewInterface(osc.CreateSnapshotRequest{
+			Description:  s.Description,
+			FileLocation: fileLocation,
+			SnapshotSize: size,
+		}),
+	})
+	if err != nil {
+		err := fmt.Errorf("Error importing the snapshot: %s", osccommon.DecodeError(err))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.snapshotId = resp.Snapshot.SnapshotId
+
+	ui.Message(fmt.Sprintf("Waiting for the snapshot to be imported: %s", s.snapshotId))
+	if err := s.PollingConfig.WaitUntilOscSnapshotDone(ctx, oscconn, s.snapshotId); err != nil {
+		err := fmt.Errorf("Error waiting for the snapshot import: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("snapshot_id", s.snapshotId)
+	return multistep.ActionContinue
+}
+
+func (s *stepImportSnapshot) Cleanup(state multistep.StateBag) {
+	if s.snapshotId == "" {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	oscconn := state.Get("osc").(*osc.APIClient)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say("Deleting the imported snapshot because of cancellation or error...")
+	if _, _, err := oscconn.SnapshotApi.DeleteSnapshot(context.Background(), &osc.DeleteSnapshotOpts{
+		DeleteSnapshotRequest: optional.NewInterface(osc.DeleteSnapshotRequest{SnapshotId: s.snapshotId}),
+	}); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting snapshot (%s), may still be around: %s", s.snapshotId, osccommon.DecodeError(err)))
+	}
+}
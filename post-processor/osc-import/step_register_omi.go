@@ -0,0 +1,96 @@
+package oscimport
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antihax/optional"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+)
+
+// stepRegisterOMI registers an OMI booting from the imported snapshot.
+type stepRegisterOMI struct {
+	RawRegion      string
+	Name           string
+	Description    string
+	Architecture   string
+	RootDeviceName string
+	PollingConfig  *osccommon.PollingConfig
+
+	imageId string
+}
+
+func (s *stepRegisterOMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	oscconn := state.Get("osc").(*osc.APIClient)
+	ui := state.Get("ui").(packersdk.Ui)
+	snapshotId := state.Get("snapshot_id").(string)
+
+	ui.Say("Registering the OMI...")
+	resp, _, err := oscconn.ImageApi.CreateImage(ctx, &osc.CreateImageOpts{
+		CreateImageRequest: optional.NewInterface(osc.CreateImageRequest{
+			ImageName:      osccommon.OMIName(state, s.Name),
+			Description:    s.Description,
+			Architecture:   s.Architecture,
+			RootDeviceName: s.RootDeviceName,
+			BlockDeviceMappings: []osc.BlockDeviceMappingImage{
+				{
+					DeviceName: s.RootDeviceName,
+					Bsu: osc.BsuToCreate{
+						SnapshotId:         snapshotId,
+						DeleteOnVmDeletion: true,
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		err := fmt.Errorf("Error registering OMI: %s", osccommon.DecodeError(err))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.imageId = resp.Image.ImageId
+
+	ui.Message(fmt.Sprintf("OMI: %s", s.imageId))
+	if err := s.PollingConfig.WaitUntilOscImageAvailable(ctx, oscconn, s.imageId); err != nil {
+		err := fmt.Errorf("Error waiting for OMI: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	omis := state.Get("omis").(map[string]string)
+	omis[s.RawRegion] = s.imageId
+	state.Put("omis", omis)
+
+	snapshots := state.Get("snapshots").(map[string][]string)
+	snapshots[s.RawRegion] = []string{snapshotId}
+	state.Put("snapshots", snapshots)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepRegisterOMI) Cleanup(state multistep.StateBag) {
+	if s.imageId == "" {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	oscconn := state.Get("osc").(*osc.APIClient)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say("Deregistering the OMI because of cancellation or error...")
+	if _, _, err := oscconn.ImageApi.DeleteImage(context.Background(), &osc.DeleteImageOpts{
+		DeleteImageRequest: optional.NewInterface(osc.DeleteImageRequest{ImageId: s.imageId}),
+	}); err != nil {
+		ui.Error(fmt.Sprintf("Error deregistering OMI (%s), may still be around: %s", s.imageId, osccommon.DecodeError(err)))
+	}
+}
@@ -0,0 +1,81 @@
+package oscimport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/outscale/osc-sdk-go/osc"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+	"github.com/outscale/packer-plugin-outscale/builder/osc/common/oapitest"
+)
+
+// testRegisterOMIState returns the state of stepRegisterOMI run against the
+// fake OAPI server, the snapshot being imported.
+func testRegisterOMIState(t *testing.T, server *oapitest.Server, snapshot string) multistep.StateBag {
+	config := &osccommon.AccessConfig{AccessKey: "AK", SecretKey: "SK", RawRegion: "eu-west-2", CustomEndpointOAPI: server.URL}
+	state := new(multistep.BasicStateBag)
+	state.Put("osc", config.NewOSCClientByRegion("eu-west-2"))
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("snapshot_id", snapshot)
+	state.Put("omis", make(map[string]string))
+	state.Put("snapshots", make(map[string][]string))
+	return state
+}
+
+func TestStepRegisterOMI_pickedName(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	server.Transitions = 0
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	state := testRegisterOMIState(t, server, source.BlockDeviceMappings[0].Bsu.SnapshotId)
+	state.Put("omi_name", "imported-2")
+
+	step := &stepRegisterOMI{
+		RawRegion:      "eu-west-2",
+		Name:           "imported",
+		Architecture:   "x86_64",
+		RootDeviceName: "/dev/sda1",
+		PollingConfig:  new(osccommon.PollingConfig),
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("should continue, got %v: %v", action, state.Get("error"))
+	}
+
+	id := state.Get("omis").(map[string]string)["eu-west-2"]
+	if id == "" {
+		t.Fatal("the OMI should be registered")
+	}
+	for _, image := range server.Images() {
+		if image.ImageId == id && image.ImageName != "imported-2" {
+			t.Fatalf("the OMI should be registered under the picked name, got %s", image.ImageName)
+		}
+	}
+}
+
+func TestStepRegisterOMI_cancelled(t *testing.T) {
+	server := oapitest.NewServer()
+	defer server.Close()
+	source := server.AddImage("eu-west-2", osc.Image{ImageName: "source"})
+
+	state := testRegisterOMIState(t, server, source.BlockDeviceMappings[0].Bsu.SnapshotId)
+	state.Put("omi_name", "imported")
+
+	step := &stepRegisterOMI{
+		RawRegion:      "eu-west-2",
+		Name:           "imported",
+		Architecture:   "x86_64",
+		RootDeviceName: "/dev/sda1",
+		PollingConfig:  new(osccommon.PollingConfig),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if action := step.Run(ctx, state); action != multistep.ActionHalt {
+		t.Fatalf("should halt, got %v", action)
+	}
+	if calls := server.Calls("CreateImage"); calls != 0 {
+		t.Fatalf("the OMI should not be registered once cancelled, got %d calls", calls)
+	}
+}
@@ -0,0 +1,109 @@
+package oscimport
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	osccommon "github.com/outscale/packer-plugin-outscale/builder/osc/common"
+)
+
+// presignDuration is how long the URL given to the snapshot import is valid.
+const presignDuration = 6 * time.Hour
+
+// stepUploadDiskImage uploads the disk image to an OOS bucket and pre-signs
+// its URL for the snapshot import.
+//
+// Produces:
+//
+//	file_location string - the pre-signed URL of the uploaded image
+//	snapshot_size int64 - the virtual size of the image, in bytes
+type stepUploadDiskImage struct {
+	AccessConfig *osccommon.AccessConfig
+	Bucket       string
+	Key          string
+	Image        *diskImage
+	SkipClean    bool
+
+	s3conn *s3.S3
+}
+
+func (s *stepUploadDiskImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+
+	size, err := s.Image.virtualSize()
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	sess, err := s.AccessConfig.NewOOSSessionByRegion(s.AccessConfig.GetRegion())
+	if err != nil {
+		err := fmt.Errorf("Error creating the OOS session: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	file, err := os.Open(s.Image.Path)
+	if err != nil {
+		err := fmt.Errorf("Error opening %s: %s", s.Image.Path, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	defer file.Close()
+
+	ui.Say(fmt.Sprintf("Uploading %s to s3://%s/%s...", s.Image.Path, s.Bucket, s.Key))
+	_, err = s3manager.NewUploader(sess).UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+		Body:   file,
+	})
+	if err != nil {
+		err := fmt.Errorf("Error uploading %s: %s", s.Image.Path, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.s3conn = s3.New(sess)
+
+	req, _ := s.s3conn.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+	})
+	url, err := req.Presign(presignDuration)
+	if err != nil {
+		err := fmt.Errorf("Error pre-signing the URL of s3://%s/%s: %s", s.Bucket, s.Key, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("file_location", url)
+	state.Put("snapshot_size", size)
+	return multistep.ActionContinue
+}
+
+func (s *stepUploadDiskImage) Cleanup(state multistep.StateBag) {
+	if s.s3conn == nil || s.SkipClean {
+		return
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say(fmt.Sprintf("Deleting s3://%s/%s...", s.Bucket, s.Key))
+	if _, err := s.s3conn.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+	}); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting s3://%s/%s, may still be around: %s", s.Bucket, s.Key, err))
+	}
+}